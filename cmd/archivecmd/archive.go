@@ -18,30 +18,47 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gohugoio/hugoreleaser-plugins-api/archiveplugin"
 	"github.com/gohugoio/hugoreleaser/cmd/corecmd"
 	"github.com/gohugoio/hugoreleaser/internal/archives"
+	"github.com/gohugoio/hugoreleaser/internal/common/ioh"
+	"github.com/gohugoio/hugoreleaser/internal/common/templ"
 	"github.com/gohugoio/hugoreleaser/internal/config"
 	"github.com/gohugoio/hugoreleaser/internal/plugins"
+	"github.com/gohugoio/hugoreleaser/internal/releases"
+	"github.com/gohugoio/hugoreleaser/staticfiles"
 
+	"github.com/bep/helpers/envhelpers"
 	"github.com/bep/helpers/filehelpers"
 	"github.com/bep/logg"
+	"github.com/bep/workers"
 	"github.com/gohugoio/hugoreleaser-plugins-api/model"
 	"github.com/peterbourgon/ff/v3/ffcli"
 )
 
 const commandName = "archive"
 
+// The name of the file added to the archives with install_file.
+const installFilename = "INSTALL.txt"
+
 // New returns a usable ffcli.Command for the archive subcommand.
 func New(core *corecmd.Core) *ffcli.Command {
 	fs := flag.NewFlagSet(corecmd.CommandName+" "+commandName, flag.ExitOnError)
 
-	archivist := NewArchivist(core)
+	archivist := NewArchivist(core, fs)
 
 	core.RegisterFlags(fs)
 
@@ -57,18 +74,62 @@ func New(core *corecmd.Core) *ffcli.Command {
 type Archivist struct {
 	infoLog logg.LevelLogger
 	core    *corecmd.Core
+
+	// Shared between all archives in a run.
+	files    *archives.FileCache
+	modTimes *archives.GitModTimes
+
+	// Flags
+	force           bool
+	archiveParallel int
+}
+
+// Options configures BuildArchives.
+type Options struct {
+	// Rebuild all archives, also those with unchanged files and settings.
+	Force bool
+
+	// Max number of archives to build in parallel.
+	// 0 means the number of workers in the core.
+	ArchiveParallel int
+}
+
+// BuildArchives builds the archives for the builds matching the -paths filter in core,
+// which must be initialized, the same way as the archive command,
+// and returns the sorted filenames of all archives, including the aliases and any up to date archives.
+// With core.Try set, nothing gets written, but the filenames are still returned.
+func BuildArchives(ctx context.Context, core *corecmd.Core, opts Options) ([]string, error) {
+	a := newArchivist(core)
+	a.force = opts.Force
+	a.archiveParallel = opts.ArchiveParallel
+	return a.buildArchives(ctx)
 }
 
 // NewArchivist returns a new Archivist.
-func NewArchivist(core *corecmd.Core) *Archivist {
+func NewArchivist(core *corecmd.Core, fs *flag.FlagSet) *Archivist {
+	a := newArchivist(core)
+
+	fs.BoolVar(&a.force, "force", false, "Rebuild all archives, also those with unchanged files and settings.")
+	fs.IntVar(&a.archiveParallel, "archive-parallel", 0, "Max number of archives to build in parallel, e.g. to limit the disk I/O. 0 means the number of -workers.")
+
+	return a
+}
+
+func newArchivist(core *corecmd.Core) *Archivist {
 	return &Archivist{
 		core: core,
+		// Large enough for any README or LICENSE file.
+		files: archives.NewFileCache(1 << 20),
 	}
-
 }
 
 func (b *Archivist) Init() error {
+	if b.archiveParallel < 0 {
+		return fmt.Errorf("%s: flag -archive-parallel must not be negative", commandName)
+	}
+
 	b.infoLog = b.core.InfoLog.WithField("cmd", commandName)
+	b.modTimes = archives.NewGitModTimes(b.core.ProjectDir)
 	c := b.core
 
 	startAndRegister := func(p config.Plugin) error {
@@ -111,11 +172,25 @@ func (b *Archivist) Init() error {
 }
 
 func (b *Archivist) Exec(ctx context.Context, args []string) error {
+	_, err := b.buildArchives(ctx)
+	return err
+}
+
+// buildArchives builds the archives and returns their sorted filenames, see BuildArchives.
+func (b *Archivist) buildArchives(ctx context.Context) ([]string, error) {
 	if err := b.Init(); err != nil {
-		return err
+		return nil, err
+	}
+
+	if err := b.generate(ctx); err != nil {
+		return nil, err
 	}
 
-	r, _ := b.core.Workforce.Start(ctx)
+	archivers := b.core.Workforce
+	if b.archiveParallel > 0 {
+		archivers = workers.New(b.archiveParallel)
+	}
+	r, _ := archivers.Start(ctx)
 
 	archiveDistDir := filepath.Join(
 		b.core.DistDir,
@@ -125,15 +200,33 @@ func (b *Archivist) Exec(ctx context.Context, args []string) error {
 	)
 	filter := b.core.PathsBuildsCompiled
 
+	// Number of archives created and their total size in bytes, logged at the end.
+	var numCreated, sizeCreated atomic.Int64
+
+	// All errors are reported at the end, so they can be fixed in one go.
+	var (
+		numArchives int
+		mu          sync.Mutex
+		errs        []string
+		filenames   []string
+	)
+
+	addFilename := func(filename string) {
+		mu.Lock()
+		filenames = append(filenames, filename)
+		mu.Unlock()
+	}
+
 	for _, archive := range b.core.Config.Archives {
 		archive := archive
 		for _, archPath := range archive.ArchsCompiled {
-			if !filter.Match(archPath.Path) {
+			if !archPath.MatchedBy(filter) {
 				continue
 			}
 			archPath := archPath
 			archiveSettings := archive.ArchiveSettings
 			arch := archPath.Arch
+			archiveSettings.Type = archiveSettings.TypeFor(arch.Os.Goos, arch.Goarch)
 			buildInfo := model.BuildInfo{
 				Project: b.core.Config.Project,
 				Tag:     b.core.Tag,
@@ -141,7 +234,8 @@ func (b *Archivist) Exec(ctx context.Context, args []string) error {
 				Goarch:  arch.Goarch,
 			}
 
-			r.Run(func() (err error) {
+			numArchives++
+			buildArchive := func() (err error) {
 
 				outDir := filepath.Join(archiveDistDir, filepath.FromSlash(archPath.Path))
 
@@ -153,6 +247,10 @@ func (b *Archivist) Exec(ctx context.Context, args []string) error {
 				b.infoLog.WithField("file", outFilename).Log(logg.String("Archive"))
 
 				if b.core.Try {
+					addFilename(outFilename)
+					for _, alias := range archPath.Aliases {
+						addFilename(filepath.Join(outDir, alias))
+					}
 					return nil
 				}
 
@@ -185,25 +283,159 @@ func (b *Archivist) Exec(ctx context.Context, args []string) error {
 					Mode:          binFi.Mode(),
 				})
 
-				for _, extraFile := range archiveSettings.ExtraFiles {
+				for _, extraBinary := range archiveSettings.ExtraBinaries {
+					extraArch, found := b.core.Config.FindArch(extraBinary.Build, arch.Os.Goos, arch.Goarch)
+					if !found {
+						return fmt.Errorf("%s: extra_binaries: no build %q for %s/%s", commandName, extraBinary.Build, arch.Os.Goos, arch.Goarch)
+					}
+					extraBinaryFilename := filepath.Join(
+						b.core.DistDir,
+						b.core.Config.Project,
+						b.core.Tag,
+						b.core.DistRootBuilds,
+						extraArch.BinaryPath(),
+					)
+					fi, err := os.Stat(extraBinaryFilename)
+					if err != nil {
+						return fmt.Errorf("%s: binary file not found: %q", commandName, extraBinaryFilename)
+					}
+					binaryDir := extraBinary.BinaryDir
+					if binaryDir == "" {
+						binaryDir = archiveSettings.BinaryDir
+					}
 					buildRequest.Files = append(buildRequest.Files, archiveplugin.ArchiveFile{
-						SourcePathAbs: filepath.Join(b.core.ProjectDir, extraFile.SourcePath),
-						TargetPath:    extraFile.TargetPath,
+						SourcePathAbs: extraBinaryFilename,
+						TargetPath:    path.Join(binaryDir, extraArch.BuildSettings.Binary),
+						Mode:          fi.Mode(),
+					})
+				}
+
+				extraFiles, err := expandExtraFiles(b.core.ProjectDir, archiveSettings.ExtraFiles, b.core.NewTemplateContext(arch.Os.Goos, arch.Goarch))
+				if err != nil {
+					return err
+				}
+
+				for _, extraFile := range extraFiles {
+					sourcePathAbs := filepath.Join(b.core.ProjectDir, extraFile.SourcePath)
+					targetPath := archives.NormalizeTargetPath(extraFile.TargetPath)
+					linkname := extraFile.SymlinkTarget
+					if linkname == "" && archiveSettings.PreserveSymlinks {
+						if fi, err := os.Lstat(sourcePathAbs); err == nil && fi.Mode()&fs.ModeSymlink != 0 {
+							if linkname, err = os.Readlink(sourcePathAbs); err != nil {
+								return err
+							}
+						}
+					}
+					if linkname != "" {
+						// Keep the link in memory, keyed by a path unique to this archive.
+						sourcePathAbs = filepath.Join(outFilename+".symlinks", filepath.FromSlash(targetPath))
+						b.files.AddSymlink(sourcePathAbs, filepath.ToSlash(linkname))
+						buildRequest.Files = append(buildRequest.Files, archiveplugin.ArchiveFile{
+							SourcePathAbs: sourcePathAbs,
+							TargetPath:    targetPath,
+						})
+						continue
+					}
+					buildRequest.Files = append(buildRequest.Files, archiveplugin.ArchiveFile{
+						SourcePathAbs: sourcePathAbs,
+						TargetPath:    targetPath,
 						Mode:          extraFile.Mode,
 					})
 				}
 
-				err = archives.Build(
-					b.core,
-					b.infoLog,
-					archiveSettings,
-					buildRequest,
-				)
+				for _, templateFile := range archiveSettings.TemplateFiles {
+					targetPath := archives.NormalizeTargetPath(templateFile.TargetPath)
+					content, err := b.renderTemplateFile(templateFile.SourcePath, b.core.NewTemplateContext(arch.Os.Goos, arch.Goarch))
+					if err != nil {
+						return err
+					}
+					mode := templateFile.Mode
+					if mode == 0 {
+						mode = 0o644
+					}
+					// Keep the rendered file in memory, keyed by a path unique to this archive.
+					sourcePathAbs := filepath.Join(outFilename+".templates", filepath.FromSlash(targetPath))
+					b.files.Add(sourcePathAbs, content, mode)
+					buildRequest.Files = append(buildRequest.Files, archiveplugin.ArchiveFile{
+						SourcePathAbs: sourcePathAbs,
+						TargetPath:    targetPath,
+					})
+				}
+
+				if archiveSettings.InstallFile {
+					content, err := b.renderInstallFile(archiveSettings.InstallTemplate, b.core.NewTemplateContext(arch.Os.Goos, arch.Goarch))
+					if err != nil {
+						return err
+					}
+					sourcePathAbs := filepath.Join(outFilename+".templates", installFilename)
+					b.files.Add(sourcePathAbs, content, 0o644)
+					buildRequest.Files = append(buildRequest.Files, archiveplugin.ArchiveFile{
+						SourcePathAbs: sourcePathAbs,
+						TargetPath:    installFilename,
+					})
+				}
 
+				if archiveSettings.WrapInDirectory {
+					dir := strings.TrimSuffix(archPath.Name, archiveSettings.Type.Extension)
+					if archiveSettings.WrapInDirectoryTemplate != "" {
+						dir, err = templ.Sprintt(archiveSettings.WrapInDirectoryTemplate, b.core.NewTemplateContext(arch.Os.Goos, arch.Goarch))
+						if err != nil {
+							return fmt.Errorf("%s: failed to render wrap_in_directory_template: %w", commandName, err)
+						}
+						if err := ioh.ValidateFilename(dir); err != nil {
+							return fmt.Errorf("%s: wrap_in_directory_template: %v", commandName, err)
+						}
+					}
+					for i, f := range buildRequest.Files {
+						buildRequest.Files[i].TargetPath = path.Join(dir, f.TargetPath)
+					}
+				}
+
+				// Skip archives with unchanged files and settings since the last run.
+				digest, err := archives.InputsDigest(archiveSettings, buildRequest, b.files)
 				if err != nil {
 					return err
 				}
 
+				if !b.force && archives.IsUpToDate(outFilename, digest) {
+					b.infoLog.WithField("file", outFilename).Log(logg.String("Archive is up to date"))
+				} else {
+					if err := archives.RemoveState(outFilename); err != nil {
+						return err
+					}
+
+					var modTimes *archives.GitModTimes
+					if archiveSettings.GitTimestamps {
+						modTimes = b.modTimes
+					}
+
+					err = archives.Build(
+						ctx,
+						b.core,
+						b.infoLog,
+						archiveSettings,
+						buildRequest,
+						b.files,
+						modTimes,
+					)
+
+					if err != nil {
+						return err
+					}
+
+					if err := archives.WriteState(outFilename, digest); err != nil {
+						return err
+					}
+
+					fi, err := os.Stat(outFilename)
+					if err != nil {
+						return err
+					}
+					numCreated.Add(1)
+					sizeCreated.Add(fi.Size())
+				}
+				addFilename(outFilename)
+
 				for _, alias := range archPath.Aliases {
 					aliasFilename := filepath.Join(
 						outDir,
@@ -213,15 +445,153 @@ func (b *Archivist) Exec(ctx context.Context, args []string) error {
 					if err := filehelpers.CopyFile(outFilename, aliasFilename); err != nil {
 						return err
 					}
+					if checksum, found := releases.ReadChecksumFile(outFilename); found {
+						if err := releases.WriteChecksumFile(aliasFilename, checksum); err != nil {
+							return err
+						}
+					}
+					addFilename(aliasFilename)
 				}
 
 				return nil
+			}
 
+			r.Run(func() error {
+				if err := buildArchive(); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Sprintf("%q: %v", path.Join(b.core.DistRootBuilds, archPath.Path), err))
+					mu.Unlock()
+				}
+				// Let the other archives complete.
+				return nil
 			})
 
 		}
 	}
 
-	return r.Wait()
+	if err := r.Wait(); err != nil {
+		return nil, err
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, fmt.Errorf("%s: %d of %d archives failed:\n%s", commandName, len(errs), numArchives, strings.Join(errs, "\n"))
+	}
+
+	if !b.core.Try {
+		b.infoLog.WithField("count", strconv.FormatInt(numCreated.Load(), 10)).WithField("size", strconv.FormatInt(sizeCreated.Load(), 10)).Log(logg.String("Created archives"))
+	}
+
+	sort.Strings(filenames)
+
+	return filenames, nil
+}
+
+// renderTemplateFile renders the project relative Go template in filename with the given context.
+func (b *Archivist) renderTemplateFile(filename string, tctx corecmd.TemplateContext) ([]byte, error) {
+	f, err := b.files.Open(filepath.Join(b.core.ProjectDir, filename))
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to open template file: %w", commandName, err)
+	}
+	defer f.Close()
+	tmpl, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	s, err := templ.Sprintt(string(tmpl), tctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to render template file %q: %w", commandName, filename, err)
+	}
+	return []byte(s), nil
+}
+
+// renderInstallFile renders the INSTALL.txt file from the project relative Go template in filename,
+// or from the built-in template if filename is empty.
+func (b *Archivist) renderInstallFile(filename string, tctx corecmd.TemplateContext) ([]byte, error) {
+	if filename != "" {
+		return b.renderTemplateFile(filename, tctx)
+	}
+	s, err := templ.Sprintt(staticfiles.InstallTemplate, tctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to render the install file: %w", commandName, err)
+	}
+	return []byte(s), nil
+}
+
+// generate runs the configured generate commands, e.g. to create shell completions.
+func (b *Archivist) generate(ctx context.Context) error {
+	for _, g := range b.core.Config.Generate {
+		b.infoLog.WithField("command", g.Command).WithField("args", g.Args).Log(logg.String("Generate"))
+		if b.core.Try {
+			continue
+		}
+		environ := os.Environ()
+		envhelpers.SetEnvVars(&environ,
+			"HUGORELEASER_PROJECT", b.core.Config.Project,
+			"HUGORELEASER_TAG", b.core.Tag,
+			"HUGORELEASER_DIST", b.core.DistDir,
+		)
+		cmd := exec.CommandContext(ctx, g.Command, g.Args...)
+		cmd.Dir = b.core.ProjectDir
+		cmd.Env = environ
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: generate command %q failed: %w", commandName, g.Command, err)
+		}
+	}
+	return nil
+}
+
+// expandExtraFiles renders the source and target paths of files as Go templates with tctx
+// and expands the glob patterns in the source paths relative to projectDir.
+// The matched files are added below the target path of the pattern using their base names.
+func expandExtraFiles(projectDir string, files []config.ArchiveFileInfo, tctx any) ([]config.ArchiveFileInfo, error) {
+	var expanded []config.ArchiveFileInfo
+	for _, f := range files {
+		sourcePath, err := templ.Sprintt(f.SourcePath, tctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: extra_files: failed to render source_path %q: %v", commandName, f.SourcePath, err)
+		}
+		targetPath, err := templ.Sprintt(f.TargetPath, tctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: extra_files: failed to render target_path %q (source_path %q): %v", commandName, f.TargetPath, f.SourcePath, err)
+		}
+		f.SourcePath, f.TargetPath = sourcePath, targetPath
+		if !isGlob(f.SourcePath) {
+			expanded = append(expanded, f)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(projectDir, f.SourcePath))
+		if err != nil {
+			return nil, fmt.Errorf("%s: extra_files: invalid pattern %q: %v", commandName, f.SourcePath, err)
+		}
+		var n int
+		for _, match := range matches {
+			fi, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if fi.IsDir() {
+				continue
+			}
+			rel, err := filepath.Rel(projectDir, match)
+			if err != nil {
+				return nil, err
+			}
+			ff := f
+			ff.SourcePath = rel
+			ff.TargetPath = path.Join(filepath.ToSlash(f.TargetPath), filepath.Base(match))
+			expanded = append(expanded, ff)
+			n++
+		}
+		if n == 0 && !f.AllowEmpty {
+			return nil, fmt.Errorf("%s: extra_files: pattern %q matches no files, set allow_empty to allow this", commandName, f.SourcePath)
+		}
+	}
+	return expanded, nil
+}
 
+func isGlob(s string) bool {
+	return strings.ContainsAny(s, "*?[")
 }
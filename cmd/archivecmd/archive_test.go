@@ -0,0 +1,92 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archivecmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser/cmd/corecmd"
+)
+
+const testConfig = `
+project = "hugo"
+[build_settings]
+binary = "hugo"
+[[builds]]
+[[builds.os]]
+goos = "linux"
+[[builds.os.archs]]
+goarch = "amd64"
+[[builds.os]]
+goos = "windows"
+[[builds.os.archs]]
+goarch = "amd64"
+[[archives]]
+paths = ["builds/**"]
+[archive_settings]
+name_template = "{{ .Project }}_{{ .Tag | trimPrefix ` + "`v`" + ` }}_{{ .Goos }}-{{ .Goarch }}"
+[archive_settings.type]
+format = "tar.gz"
+`
+
+func newTestCore(c *qt.C, try bool) *corecmd.Core {
+	dir := c.TempDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, "hugoreleaser.toml"), []byte(testConfig), 0o644), qt.IsNil)
+	for _, goos := range []string{"linux", "windows"} {
+		binDir := filepath.Join(dir, "dist", "hugo", "v1.2.0", "builds", goos, "amd64")
+		c.Assert(os.MkdirAll(binDir, 0o755), qt.IsNil)
+		binary := "hugo"
+		if goos == "windows" {
+			binary += ".exe"
+		}
+		c.Assert(os.WriteFile(filepath.Join(binDir, binary), []byte(goos), 0o755), qt.IsNil)
+	}
+
+	_, core := corecmd.New()
+	core.ProjectDir = dir
+	core.Tag = "v1.2.0"
+	core.Quiet = true
+	core.Try = try
+	c.Assert(core.Init(), qt.IsNil)
+	c.Cleanup(func() { core.Close() })
+
+	return core
+}
+
+func TestBuildArchives(t *testing.T) {
+	c := qt.New(t)
+
+	for _, try := range []bool{false, true} {
+		core := newTestCore(c, try)
+		archiveDir := filepath.Join(core.DistDir, "hugo", "v1.2.0", "archives")
+		expect := []string{
+			filepath.Join(archiveDir, "linux", "amd64", "hugo_1.2.0_linux-amd64.tar.gz"),
+			filepath.Join(archiveDir, "windows", "amd64", "hugo_1.2.0_windows-amd64.tar.gz"),
+		}
+
+		filenames, err := BuildArchives(context.Background(), core, Options{})
+		c.Assert(err, qt.IsNil)
+		c.Assert(filenames, qt.DeepEquals, expect)
+
+		for _, filename := range filenames {
+			_, err := os.Stat(filename)
+			c.Assert(err == nil, qt.Equals, !try, qt.Commentf(filename))
+		}
+	}
+}
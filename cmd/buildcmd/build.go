@@ -22,6 +22,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/bep/helpers/envhelpers"
 	"github.com/bep/helpers/slicehelpers"
@@ -29,6 +30,7 @@ import (
 	"github.com/bep/logg"
 	"github.com/gohugoio/hugoreleaser/cmd/corecmd"
 	"github.com/gohugoio/hugoreleaser/internal/builds"
+	"github.com/gohugoio/hugoreleaser/internal/common/templ"
 	"github.com/gohugoio/hugoreleaser/internal/config"
 	"github.com/peterbourgon/ff/v3/ffcli"
 )
@@ -67,8 +69,9 @@ func NewBuilder(core *corecmd.Core, fs *flag.FlagSet) *Builder {
 }
 
 type Builder struct {
-	core    *corecmd.Core
-	infoLog logg.LevelLogger
+	core     *corecmd.Core
+	infoLog  logg.LevelLogger
+	debugLog logg.LevelLogger
 
 	chunks     int
 	chunkIndex int
@@ -76,6 +79,7 @@ type Builder struct {
 
 func (b *Builder) Init() error {
 	b.infoLog = b.core.InfoLog.WithField("cmd", commandName)
+	b.debugLog = b.core.DebugLog.WithField("cmd", commandName)
 
 	if b.chunks > 0 && b.chunkIndex >= b.chunks {
 		return fmt.Errorf("chunk-index (%d) must be less than chunks (%d)", b.chunkIndex, b.chunks)
@@ -97,7 +101,7 @@ func (b *Builder) Exec(ctx context.Context, args []string) error {
 		if _, err := os.Stat(filepath.Join(b.core.ProjectDir, "go.mod")); err == nil {
 			b.infoLog.Log(logg.String("Running 'go mod download'."))
 			var buff bytes.Buffer
-			if err := b.core.RunGo(ctx, nil, []string{"mod", "download"}, &buff); err != nil {
+			if err := b.core.RunGo(ctx, b.core.Config.BuildSettings.GoSettings, nil, []string{"mod", "download"}, &buff); err != nil {
 				b.core.ErrorLog.Log(logg.String(buff.String()))
 				return err
 			}
@@ -157,13 +161,65 @@ func (b *Builder) buildArch(ctx context.Context, archPath config.BuildArchPath)
 		arch.BuildSettings.Binary,
 	)
 
-	b.infoLog.WithField("binary", outFilename).WithFields(b.core.Config.BuildSettings).Log(logg.String("Building"))
+	buildSettings := arch.BuildSettings
+	tctx := b.core.NewTemplateContext(arch.Os.Goos, arch.Goarch)
+
+	if len(buildSettings.Env) > 0 {
+		// The env slice may be shared with other builds.
+		env := make([]string, len(buildSettings.Env))
+		for i, e := range buildSettings.Env {
+			rendered, err := templ.Sprintt(e, tctx)
+			if err != nil {
+				return fmt.Errorf("%s: failed to render env %q for %q: %w", commandName, e, archPath.Path, err)
+			}
+			env[i] = rendered
+		}
+		buildSettings.Env = env
+	}
+
+	if len(buildSettings.Flags) > 0 || b.core.Trimpath {
+		var flags []string
+		for _, f := range buildSettings.Flags {
+			rendered, err := templ.Sprintt(f, tctx)
+			if err != nil {
+				return fmt.Errorf("%s: failed to render flag %q for %q: %w", commandName, f, archPath.Path, err)
+			}
+			if rendered = strings.TrimSpace(rendered); rendered != "" {
+				flags = append(flags, rendered)
+			}
+		}
+		if b.core.Trimpath && !containsString(flags, "-trimpath") {
+			flags = append(flags, "-trimpath")
+		}
+		buildSettings.Flags = flags
+	}
+
+	if buildSettings.Ldflags != "" {
+		ldflags, err := templ.Sprintt(buildSettings.Ldflags, tctx)
+		if err != nil {
+			return fmt.Errorf("%s: failed to render ldflags for %q: %w", commandName, archPath.Path, err)
+		}
+		buildSettings.Ldflags = ldflags
+	}
+
+	if arch.ExtraLdflags != "" {
+		extraLdflags, err := templ.Sprintt(arch.ExtraLdflags, tctx)
+		if err != nil {
+			return fmt.Errorf("%s: failed to render extra_ldflags for %q: %w", commandName, archPath.Path, err)
+		}
+		b.infoLog.WithField("ldflags", buildSettings.Ldflags).WithField("extra_ldflags", extraLdflags).Log(logg.String("Appending extra ldflags"))
+		buildSettings.Ldflags = strings.TrimSpace(buildSettings.Ldflags + " " + extraLdflags)
+	}
+
+	b.infoLog.WithField("binary", outFilename).WithFields(buildSettings).Log(logg.String("Building"))
 
 	if b.core.Try {
 		return nil
 	}
 
-	buildSettings := arch.BuildSettings
+	if _, err := b.core.ResolveGoExe(buildSettings.GoSettings); err != nil {
+		return fmt.Errorf("%s: build %q: %w", commandName, archPath.Path, err)
+	}
 
 	buildBinary := func(filename, goarch string) error {
 		var keyVals []string
@@ -188,8 +244,17 @@ func (b *Builder) buildArch(ctx context.Context, archPath config.BuildArchPath)
 		if buildSettings.Flags != nil {
 			args = append(args, buildSettings.Flags...)
 		}
+		if buildSettings.Main != "" {
+			args = append(args, buildSettings.Main)
+		}
 
-		return b.core.RunGo(ctx, keyVals, args, os.Stderr)
+		var env []string
+		for i := 0; i < len(keyVals); i += 2 {
+			env = append(env, keyVals[i]+"="+keyVals[i+1])
+		}
+		b.debugLog.WithField("binary", filename).WithField("env", strings.Join(env, " ")).Log(logg.String("Build environment"))
+
+		return b.core.RunGo(ctx, buildSettings.GoSettings, keyVals, args, os.Stderr)
 	}
 
 	if arch.Goarch == builds.UniversalGoarch {
@@ -223,3 +288,12 @@ func (b *Builder) buildArch(ctx context.Context, archPath config.BuildArchPath)
 
 	return nil
 }
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cleancmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bep/logg"
+	"github.com/gohugoio/hugoreleaser/cmd/corecmd"
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+const commandName = "clean"
+
+// New returns a usable ffcli.Command for the clean subcommand.
+func New(core *corecmd.Core) *ffcli.Command {
+	fs := flag.NewFlagSet(corecmd.CommandName+" "+commandName, flag.ExitOnError)
+
+	c := &cleaner{
+		core: core,
+	}
+
+	fs.BoolVar(&c.force, "force", false, "Required to actually remove the files.")
+
+	core.RegisterFlags(fs)
+
+	return &ffcli.Command{
+		Name:       commandName,
+		ShortUsage: corecmd.CommandName + " " + commandName + " -tag <tag> -force [flags]",
+		ShortHelp:  "Remove all builds, archives and releases for the given tag from the dist directory.",
+		FlagSet:    fs,
+		Exec:       c.Exec,
+	}
+}
+
+type cleaner struct {
+	infoLog logg.LevelLogger
+	core    *corecmd.Core
+
+	force bool
+}
+
+func (c *cleaner) Exec(ctx context.Context, args []string) error {
+	c.infoLog = c.core.InfoLog.WithField("cmd", commandName)
+
+	dir, err := tagDir(c.core.DistDir, c.core.Config.Project, c.core.Tag)
+	if err != nil {
+		return fmt.Errorf("%s: %w", commandName, err)
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			c.infoLog.WithField("dir", dir).Log(logg.String("Nothing to clean"))
+			return nil
+		}
+		return fmt.Errorf("%s: %w", commandName, err)
+	}
+
+	if c.core.Try {
+		c.infoLog.WithField("dir", dir).Log(logg.String("Would remove"))
+		return nil
+	}
+
+	if !c.force {
+		return fmt.Errorf("%s: flag -force is required to remove %q", commandName, dir)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("%s: failed to remove %q: %w", commandName, dir, err)
+	}
+
+	c.infoLog.WithField("dir", dir).Log(logg.String("Removed"))
+
+	return nil
+}
+
+// tagDir returns the directory holding all artifacts for project and tag,
+// failing if that directory isn't strictly below distDir.
+func tagDir(distDir, project, tag string) (string, error) {
+	if project == "" {
+		return "", fmt.Errorf("project is not set")
+	}
+	if tag == "" {
+		return "", fmt.Errorf("flag -tag is required")
+	}
+
+	distDir = filepath.Clean(distDir)
+	dir := filepath.Join(distDir, project, tag)
+
+	rel, err := filepath.Rel(distDir, dir)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", fmt.Errorf("refusing to remove %q: not below the dist directory %q", dir, distDir)
+	}
+	if len(strings.Split(rel, string(filepath.Separator))) != 2 {
+		return "", fmt.Errorf("refusing to remove %q: project %q and tag %q must be single path elements", dir, project, tag)
+	}
+
+	return dir, nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cleancmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestTagDir(t *testing.T) {
+	c := qt.New(t)
+
+	distDir := filepath.FromSlash("/work/dist")
+
+	dir, err := tagDir(distDir, "hugo", "v1.2.0")
+	c.Assert(err, qt.IsNil)
+	c.Assert(dir, qt.Equals, filepath.Join(distDir, "hugo", "v1.2.0"))
+
+	for _, test := range []struct {
+		project, tag string
+	}{
+		{"", "v1.2.0"},
+		{"hugo", ""},
+		{"hugo", ".."},
+		{"..", ".."},
+		{"hugo", "../../etc"},
+		{"hugo", "v1/v2"},
+		{".", "."},
+	} {
+		_, err := tagDir(distDir, test.project, test.tag)
+		c.Assert(err, qt.Not(qt.IsNil), qt.Commentf("%q %q", test.project, test.tag))
+	}
+}
@@ -16,13 +16,16 @@ package corecmd
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,11 +35,15 @@ import (
 	"github.com/bep/logg/handlers/multi"
 	"github.com/bep/workers"
 	"github.com/gohugoio/hugoreleaser-plugins-api/archiveplugin"
+	"github.com/gohugoio/hugoreleaser/internal/builds"
 	"github.com/gohugoio/hugoreleaser/internal/common/errorsh"
+	"github.com/gohugoio/hugoreleaser/internal/common/filelock"
+	"github.com/gohugoio/hugoreleaser/internal/common/ioh"
 	"github.com/gohugoio/hugoreleaser/internal/common/logging"
 	"github.com/gohugoio/hugoreleaser/internal/common/matchers"
 	"github.com/gohugoio/hugoreleaser/internal/common/templ"
 	"github.com/gohugoio/hugoreleaser/internal/config"
+	"github.com/gohugoio/hugoreleaser/internal/releases"
 	"github.com/gohugoio/hugoreleaser/plugins/model"
 	"github.com/pelletier/go-toml/v2"
 	"github.com/peterbourgon/ff/v3/ffcli"
@@ -81,6 +88,9 @@ type Core struct {
 	// The parsed config.
 	Config config.Config
 
+	// The common Debug logger, only enabled with -debug.
+	DebugLog logg.LevelLogger
+
 	// The common Info logger.
 	InfoLog logg.LevelLogger
 
@@ -93,9 +103,22 @@ type Core struct {
 	// No output to stdout.
 	Quiet bool
 
+	// Enable debug logging.
+	Debug bool
+
+	// Build with -trimpath.
+	Trimpath bool
+
 	// Trial run, no builds or releases.
 	Try bool
 
+	// Snapshot run, the tag is generated from the current commit
+	// and nothing gets published.
+	Snapshot bool
+
+	// Don't lock the dist dir.
+	NoLock bool
+
 	// The Git tag to use for the release.
 	// This tag will eventually be created at release time if it does not exist.
 	Tag string
@@ -106,6 +129,10 @@ type Core struct {
 	PathsArchivesCompiled matchers.Matcher
 	PathsReleasesCompiled matchers.Matcher
 
+	// GOOS and GOARCH values to build and archive, combined with the builds/ -paths.
+	Goos   stringFlags
+	Goarch stringFlags
+
 	// Abolute path to the project root.
 	ProjectDir string
 
@@ -130,11 +157,62 @@ type Core struct {
 	// Global timeout for all commands.
 	Timeout time.Duration
 
+	// Git ref to compare with to only build, archive and release the
+	// builds with changes in the packages their main package depends on.
+	DiffBase string
+
+	// Retries of temporary errors, e.g. when creating releases and uploading assets.
+	MaxRetries        int
+	RetryInitialDelay time.Duration
+	RetryMaxDelay     time.Duration
+
 	// The global workforce.
 	Workforce *workers.Workforce
 
 	// Archive plugins started and ready to use.
 	PluginsRegistryArchive map[string]*execrpc.Client[archiveplugin.Request, archiveplugin.Response]
+
+	// The environment variables listed in template_env.
+	templateEnv map[string]string
+
+	// Lock on the dist dir, held until Close.
+	distLock *filelock.Lock
+}
+
+// TemplateContext is the data available in the Go templates in the config,
+// e.g. name_template, label_template, extra_ldflags and template_files.
+type TemplateContext struct {
+	model.BuildInfo
+
+	// The environment variables listed in template_env.
+	Env map[string]string
+
+	// Whether this is a -snapshot run.
+	IsSnapshot bool
+
+	// Whether the tag is a pre-release version (e.g. v1.2.0-beta1).
+	IsPrerelease bool
+}
+
+// NewTemplateContext creates a new TemplateContext for the given GOOS/GOARCH, which may be empty.
+func (c *Core) NewTemplateContext(goos, goarch string) TemplateContext {
+	return TemplateContext{
+		BuildInfo: model.BuildInfo{
+			Project: c.Config.Project,
+			Tag:     c.Tag,
+			Goos:    goos,
+			Goarch:  goarch,
+		},
+		Env:          c.templateEnv,
+		IsSnapshot:   c.Snapshot,
+		IsPrerelease: c.Snapshot || isPrereleaseTag(c.Tag),
+	}
+}
+
+// isPrereleaseTag reports whether tag has a semver pre-release part, e.g. v1.2.0-beta1.
+func isPrereleaseTag(tag string) bool {
+	version, _, _ := strings.Cut(strings.TrimPrefix(tag, "v"), "+")
+	return strings.Contains(version, "-")
 }
 
 // Exec function for this command.
@@ -155,12 +233,22 @@ func (c *Core) RegisterFlags(fs *flag.FlagSet) {
 	}
 	fs.StringVar(&c.Tag, "tag", "", "The name of the release tag (e.g. v1.2.0). Does not need to exist.")
 	fs.Var(&c.Paths, "paths", "Paths to include in the command.")
+	fs.Var(&c.Goos, "goos", "GOOS to build and archive, e.g. linux. Can be repeated.")
+	fs.Var(&c.Goarch, "goarch", "GOARCH (or one of its aliases) to build and archive, e.g. amd64. Can be repeated.")
 	fs.StringVar(&c.DistDir, "dist", "dist", "Directory to store the built artifacts in.")
 	fs.StringVar(&c.ConfigFile, "config", "hugoreleaser.toml", "The config file to use.")
 	fs.IntVar(&c.NumWorkers, "workers", numWorkers, "Number of parallel builds.")
 	fs.DurationVar(&c.Timeout, "timeout", 55*time.Minute, "Global timeout.")
+	fs.StringVar(&c.DiffBase, "diff-base", "", "Git ref to compare with, only builds with changes in the packages their main package depends on since this ref are included.")
+	fs.IntVar(&c.MaxRetries, "max-retries", releases.DefaultRetrySettings.MaxRetries, "Max number of retries on temporary errors, e.g. when uploading release assets.")
+	fs.DurationVar(&c.RetryInitialDelay, "retry-initial-delay", releases.DefaultRetrySettings.InitialDelay, "Delay before the first retry, growing randomly for each retry.")
+	fs.DurationVar(&c.RetryMaxDelay, "retry-max-delay", releases.DefaultRetrySettings.MaxDelay, "Max delay between two retries, 0 means no limit.")
 	fs.BoolVar(&c.Quiet, "quiet", false, "Don't output anything to stdout.")
+	fs.BoolVar(&c.Trimpath, "trimpath", false, "Build with -trimpath, removing the file system paths from the binaries, e.g. for reproducible builds.")
+	fs.BoolVar(&c.Debug, "debug", false, "Enable debug logging, e.g. the environment used for each build.")
 	fs.BoolVar(&c.Try, "try", false, "Trial run, no builds, archives or releases.")
+	fs.BoolVar(&c.NoLock, "no-lock", false, "Don't lock the dist directory, allowing concurrent runs to write to it.")
+	fs.BoolVar(&c.Snapshot, "snapshot", false, "Snapshot run, generates a tag (e.g. v0.0.0-snapshot-20240101-abcdef1) from the HEAD commit and skips publishing releases.")
 
 }
 
@@ -295,10 +383,34 @@ func (c *Core) Init() error {
 
 	if !filepath.IsAbs(c.DistDir) {
 		c.DistDir = filepath.Join(c.ProjectDir, c.DistDir)
+	}
+
+	if err := os.MkdirAll(c.DistDir, 0o755); err != nil {
+		return fmt.Errorf("error creating dist directory: %w", err)
+	}
 
-		if err := os.MkdirAll(c.DistDir, 0o755); err != nil {
-			return fmt.Errorf("error creating dist directory: %w", err)
+	if !c.NoLock && !c.Try {
+		// Prevent concurrent runs from removing each other's files.
+		lockFilename := filepath.Join(c.DistDir, ".hugoreleaser.lock")
+		var err error
+		c.distLock, err = filelock.TryLock(lockFilename)
+		if err != nil {
+			if errors.Is(err, filelock.ErrLocked) {
+				return fmt.Errorf("dist directory %q is in use by another %s process; use -no-lock to disable this check", c.DistDir, CommandName)
+			}
+			return fmt.Errorf("error locking dist directory: %w", err)
+		}
+	}
+
+	if c.Snapshot {
+		if c.Tag != "" {
+			return fmt.Errorf("flags -tag and -snapshot cannot be used together")
+		}
+		tag, err := snapshotTag(c.ProjectDir)
+		if err != nil {
+			return fmt.Errorf("error creating snapshot tag: %w", err)
 		}
+		c.Tag = tag
 	}
 
 	fields := logg.Fields{
@@ -312,13 +424,19 @@ func (c *Core) Init() error {
 		logging.Replacer(strings.NewReplacer(c.DistDir, "$DIST")), logHandler,
 	)
 
+	level := logg.LevelInfo
+	if c.Debug {
+		level = logg.LevelDebug
+	}
+
 	l = logg.New(
 		logg.Options{
-			Level:   logg.LevelInfo,
+			Level:   level,
 			Handler: logHandler,
 		},
 	)
 
+	c.DebugLog = l.WithLevel(logg.LevelDebug).WithField("cmd", "core")
 	c.InfoLog = l.WithLevel(logg.LevelInfo).WithField("cmd", "core")
 	c.WarnLog = l.WithLevel(logg.LevelWarn).WithField("cmd", "core")
 	c.ErrorLog = l.WithLevel(logg.LevelError).WithField("cmd", "core")
@@ -330,6 +448,9 @@ func (c *Core) Init() error {
 	if err := c.compilePaths(); err != nil {
 		return fmt.Errorf("error compiling -paths: %w", err)
 	}
+	if len(c.Goos) > 0 || len(c.Goarch) > 0 {
+		c.PathsBuildsCompiled = matchers.And(c.PathsBuildsCompiled, goosGoarchMatcher{goos: c.Goos, goarch: c.Goarch})
+	}
 
 	c.Workforce = workers.New(c.NumWorkers)
 
@@ -342,49 +463,67 @@ func (c *Core) Init() error {
 		c.NumWorkers = runtime.NumCPU()
 	}
 
-	if !filepath.IsAbs(c.ConfigFile) {
-		c.ConfigFile = filepath.Join(c.ProjectDir, c.ConfigFile)
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("-max-retries must be >= 0, got %d", c.MaxRetries)
+	}
+	if c.RetryInitialDelay < 0 || c.RetryMaxDelay < 0 {
+		return fmt.Errorf("-retry-initial-delay and -retry-max-delay must be >= 0")
 	}
 
-	f, err := os.Open(c.ConfigFile)
+	var err error
+	c.Config, err = c.LoadConfig()
 	if err != nil {
-		return fmt.Errorf("error opening config file %q: %w", c.ConfigFile, err)
+		return err
 	}
-	defer f.Close()
 
-	c.Config, err = config.DecodeAndApplyDefaults(f)
+	c.normalizeBinaryNames()
 
-	if err != nil {
-		msg := "error decoding config file"
-		switch v := err.(type) {
-		case *toml.DecodeError:
-			line, col := v.Position()
-			return fmt.Errorf("%s %q:%d:%d %w:\n%s", msg, c.ConfigFile, line, col, err, v.String())
-		case *toml.StrictMissingError:
-			return fmt.Errorf("%s %q: %w:\n%s", msg, c.ConfigFile, err, v.String())
+	if len(c.Goos) > 0 || len(c.Goarch) > 0 {
+		if len(c.Config.FindArchs(c.PathsBuildsCompiled)) == 0 {
+			return fmt.Errorf("no builds found matching -goos %v -goarch %v -paths %v", []string(c.Goos), []string(c.Goarch), []string(c.Paths))
+		}
+	}
+
+	if c.DiffBase != "" {
+		if err := c.skipUnchangedBuilds(); err != nil {
+			return err
 		}
-		return fmt.Errorf("%s %q: %w", msg, c.ConfigFile, err)
 	}
 
+	c.initTemplateEnv()
+
 	// Precompile the common navigation for all archives.
+	// Collect any invalid archive names to report them all at once.
+	var invalidNames []string
+	checkName := func(archPath config.BuildArchPath, name string) {
+		if err := ioh.ValidateFilename(name); err != nil {
+			invalidNames = append(invalidNames, fmt.Sprintf("%q (%s): %s", name, path.Join(c.DistRootBuilds, archPath.Path), err))
+		}
+	}
 	for i, archive := range c.Config.Archives {
 		archiveSettings := archive.ArchiveSettings
 		archs := c.Config.FindArchs(archive.PathsCompiled)
 		for _, archPath := range archs {
 			arch := archPath.Arch
-			buildInfo := model.BuildInfo{
-				Project: c.Config.Project,
-				Tag:     c.Tag,
-				Goos:    arch.Os.Goos,
-				Goarch:  arch.Goarch,
-			}
-			name, err := templ.Sprintt(archive.ArchiveSettings.NameTemplate, buildInfo)
+			tctx := c.NewTemplateContext(arch.Os.Goos, arch.Goarch)
+			name, err := templ.Sprintt(archive.ArchiveSettings.NameTemplate, tctx)
 			if err != nil {
 				return fmt.Errorf("error compiling archive name template: %w", err)
 			}
-			name = archiveSettings.ReplacementsCompiled.Replace(name) + archiveSettings.Type.Extension
+			name, err = c.AssetName(archiveSettings.ReplaceName(name), tctx)
+			if err != nil {
+				return err
+			}
+			name += archiveSettings.TypeFor(arch.Os.Goos, arch.Goarch).ExtensionFor(arch.Os.Goos)
 			archPath.Name = name
 
+			if archiveSettings.LabelTemplate != "" {
+				archPath.Label, err = templ.Sprintt(archiveSettings.LabelTemplate, tctx)
+				if err != nil {
+					return fmt.Errorf("error compiling archive label template: %w", err)
+				}
+			}
+
 			if c.Config.ArchiveAliasReplacements != nil {
 				for k, v := range c.Config.ArchiveAliasReplacements {
 					if strings.Contains(name, k) {
@@ -393,26 +532,51 @@ func (c *Core) Init() error {
 				}
 			}
 
+			checkName(archPath, archPath.Name)
+			for _, alias := range archPath.Aliases {
+				checkName(archPath, alias)
+			}
+
 			c.Config.Archives[i].ArchsCompiled = append(c.Config.Archives[i].ArchsCompiled, archPath)
 		}
 	}
 
+	if len(invalidNames) > 0 {
+		return fmt.Errorf("invalid archive names, check name_template, asset_name_prefix, asset_name_suffix and archive_alias_replacements:\n%s", strings.Join(invalidNames, "\n"))
+	}
+
 	for i, release := range c.Config.Releases {
 		// Precompile the build/archive selection for the release step.
 		// Filter out the archive/paths that belong to this release.
-		// Check that there are no duplicate archive names.
+		// Check that there are no duplicate archive names, including aliases.
+		// The release assets are flat, so the archive names must be unique across all paths.
 		seen := make(map[string]config.BuildArchPath)
+		var duplicates []string
 		for _, archive := range c.Config.Archives {
 			for _, archPath := range archive.ArchsCompiled {
-				if release.PathsCompiled.Match(archPath.Path) {
-					if _, found := seen[archPath.Name]; found {
-						return fmt.Errorf("path %q and %q end up with the same archive name %q within the same release", seen[archPath.Name].Path, archPath.Path, archPath.Name)
+				if archPath.MatchedBy(release.PathsCompiled) {
+					for _, name := range append([]string{archPath.Name}, archPath.Aliases...) {
+						if other, found := seen[name]; found {
+							duplicates = append(duplicates, fmt.Sprintf("path %q and %q end up with the same archive name %q", other.Path, archPath.Path, name))
+							continue
+						}
+						seen[name] = archPath
 					}
-					seen[archPath.Name] = archPath
 					c.Config.Releases[i].ArchsCompiled = append(c.Config.Releases[i].ArchsCompiled, archPath)
 				}
 			}
 		}
+		if len(duplicates) > 0 {
+			return fmt.Errorf("release %q: duplicate archive names within the same release:\n%s", release.Path, strings.Join(duplicates, "\n"))
+		}
+		if len(c.Config.Releases[i].ArchsCompiled) == 0 && c.DiffBase == "" {
+			// Most likely a path filter that's not updated after a rename.
+			var paths []string
+			for _, p := range release.Paths {
+				paths = append(paths, path.Join(c.DistRootArchives, p))
+			}
+			c.WarnLog.Logf("Release %q: paths %v match no archives", release.Path, paths)
+		}
 	}
 
 	// Registry for archive plugins.
@@ -421,6 +585,81 @@ func (c *Core) Init() error {
 	return nil
 }
 
+// InitConfig is a lighter version of Init for commands that don't build or release anything:
+// It loads the config and prepares the template context, but does not touch the dist directory.
+func (c *Core) InitConfig() error {
+	var err error
+	c.Config, err = c.LoadConfig()
+	if err != nil {
+		return err
+	}
+	c.initTemplateEnv()
+	return nil
+}
+
+func (c *Core) initTemplateEnv() {
+	c.templateEnv = make(map[string]string)
+	for _, k := range c.Config.TemplateEnv {
+		c.templateEnv[k] = os.Getenv(k)
+	}
+}
+
+// normalizeBinaryNames makes sure that only Windows binaries have the .exe suffix.
+// A common mistake is to set binary = "app.exe" for all platforms.
+func (c *Core) normalizeBinaryNames() {
+	for i := range c.Config.Builds {
+		for j := range c.Config.Builds[i].Os {
+			goos := c.Config.Builds[i].Os[j].Goos
+			for k := range c.Config.Builds[i].Os[j].Archs {
+				arch := &c.Config.Builds[i].Os[j].Archs[k]
+				binary := arch.BuildSettings.Binary
+				if binary == "" {
+					continue
+				}
+				isExe := strings.HasSuffix(binary, ".exe")
+				switch {
+				case goos == "windows" && !isExe:
+					arch.BuildSettings.Binary = binary + ".exe"
+				case goos != "windows" && isExe:
+					arch.BuildSettings.Binary = strings.TrimSuffix(binary, ".exe")
+				default:
+					continue
+				}
+				c.WarnLog.WithField("goos", goos).WithField("goarch", arch.Goarch).Logf("Binary %q renamed to %q", binary, arch.BuildSettings.Binary)
+			}
+		}
+	}
+}
+
+// LoadConfig resolves c.ConfigFile relative to the project dir,
+// decodes it and applies default values.
+func (c *Core) LoadConfig() (config.Config, error) {
+	if !filepath.IsAbs(c.ConfigFile) {
+		c.ConfigFile = filepath.Join(c.ProjectDir, c.ConfigFile)
+	}
+
+	f, err := os.Open(c.ConfigFile)
+	if err != nil {
+		return config.Config{}, fmt.Errorf("error opening config file %q: %w", c.ConfigFile, err)
+	}
+	defer f.Close()
+
+	cfg, err := config.DecodeAndApplyDefaults(f, c.ProjectDir)
+	if err != nil {
+		msg := "error decoding config file"
+		switch v := err.(type) {
+		case *toml.DecodeError:
+			line, col := v.Position()
+			return cfg, fmt.Errorf("%s %q:%d:%d %w:\n%s", msg, c.ConfigFile, line, col, err, v.String())
+		case *toml.StrictMissingError:
+			return cfg, fmt.Errorf("%s %q: %w:\n%s", msg, c.ConfigFile, err, v.String())
+		}
+		return cfg, fmt.Errorf("%s %q: %w", msg, c.ConfigFile, err)
+	}
+
+	return cfg, nil
+}
+
 func (c *Core) Close() error {
 	for k, v := range c.PluginsRegistryArchive {
 		if err := v.Close(); err != nil {
@@ -429,13 +668,41 @@ func (c *Core) Close() error {
 			}
 		}
 	}
+	if c.distLock != nil {
+		if err := c.distLock.Unlock(); err != nil {
+			return fmt.Errorf("error unlocking dist directory: %w", err)
+		}
+		c.distLock = nil
+	}
 	return nil
 }
 
-func (c *Core) RunGo(ctx context.Context, envKeyVals, args []string, stderr io.Writer) error {
-	buildSettings := c.Config.BuildSettings
-	goexe := buildSettings.GoSettings.GoExe
-	envKeyVals = append(envKeyVals, "GOPROXY", buildSettings.GoSettings.GoProxy)
+// ResolveGoExe returns the path to the Go executable in goSettings.
+// A bare name (e.g. "go") is looked up in PATH, a relative path is resolved relative to ProjectDir.
+func (c *Core) ResolveGoExe(goSettings config.GoSettings) (string, error) {
+	goexe := goSettings.GoExe
+	if goexe == "" {
+		goexe = "go"
+	}
+	if strings.ContainsRune(goexe, '/') || strings.ContainsRune(goexe, filepath.Separator) {
+		if !filepath.IsAbs(goexe) {
+			goexe = filepath.Join(c.ProjectDir, goexe)
+		}
+	}
+	resolved, err := exec.LookPath(goexe)
+	if err != nil {
+		return "", fmt.Errorf("go_exe %q: %w", goSettings.GoExe, err)
+	}
+	return resolved, nil
+}
+
+// RunGo runs the Go executable in goSettings with args.
+func (c *Core) RunGo(ctx context.Context, goSettings config.GoSettings, envKeyVals, args []string, stderr io.Writer) error {
+	goexe, err := c.ResolveGoExe(goSettings)
+	if err != nil {
+		return err
+	}
+	envKeyVals = append(envKeyVals, "GOPROXY", goSettings.GoProxy)
 	environ := os.Environ()
 	envhelpers.SetEnvVars(&environ, envKeyVals...)
 	cmd := exec.CommandContext(ctx, goexe, args...)
@@ -445,6 +712,191 @@ func (c *Core) RunGo(ctx context.Context, envKeyVals, args []string, stderr io.W
 	return cmd.Run()
 }
 
+// skipUnchangedBuilds removes the build archs with no changes since c.DiffBase
+// in the packages of the main module that their main package depends on, including embedded files.
+// Changes to go.mod or go.sum are considered to affect all builds.
+func (c *Core) skipUnchangedBuilds() error {
+	cmd := exec.Command("git", "diff", "-z", "--name-only", "--relative", c.DiffBase)
+	cmd.Dir = c.ProjectDir
+	out, err := cmd.Output()
+	if err != nil {
+		var stderr []byte
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr = exitErr.Stderr
+		}
+		return fmt.Errorf("-diff-base: git diff failed: %w: %s", err, stderr)
+	}
+	var changed []string
+	for _, filename := range strings.Split(string(out), "\x00") {
+		if filename != "" {
+			changed = append(changed, filename)
+		}
+	}
+
+	// The dependencies may vary with the build settings, e.g. build tags.
+	depsCache := make(map[string]goDeps)
+	isChanged := func(settings config.BuildSettings, goos, goarch string) (bool, error) {
+		goarchs := []string{goarch}
+		if goarch == builds.UniversalGoarch {
+			goarchs = []string{"arm64", "amd64"}
+		}
+		for _, goarch := range goarchs {
+			key := strings.Join(append([]string{settings.Main, goos, goarch, strings.Join(settings.Env, " ")}, settings.Flags...), "|")
+			deps, found := depsCache[key]
+			if !found {
+				var err error
+				deps, err = c.goListDeps(settings, goos, goarch)
+				if err != nil {
+					return false, err
+				}
+				depsCache[key] = deps
+			}
+			for _, filename := range changed {
+				if filename == "go.mod" || filename == "go.sum" || deps.dirs[path.Dir(filename)] || deps.files[filename] {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+
+	for i, build := range c.Config.Builds {
+		for j, os := range build.Os {
+			var archs []config.BuildArch
+			for _, arch := range os.Archs {
+				changed, err := isChanged(arch.BuildSettings, os.Goos, arch.Goarch)
+				if err != nil {
+					return err
+				}
+				if changed {
+					archs = append(archs, arch)
+					continue
+				}
+				main := arch.BuildSettings.Main
+				if main == "" {
+					main = "."
+				}
+				c.InfoLog.WithField("main", main).Logf("Skipping build %s: no changes since %s", path.Join(build.Path, os.Goos, arch.Goarch), c.DiffBase)
+			}
+			c.Config.Builds[i].Os[j].Archs = archs
+		}
+	}
+
+	return nil
+}
+
+// goDeps holds the packages in the main module a main package depends on.
+type goDeps struct {
+	// The package directories relative to the project dir, using forward slashes.
+	dirs map[string]bool
+	// The files embedded in the packages, relative to the project dir, using forward slashes.
+	files map[string]bool
+}
+
+// goListDeps lists the packages in the main module the main package in settings depends on for goos/goarch.
+func (c *Core) goListDeps(settings config.BuildSettings, goos, goarch string) (goDeps, error) {
+	deps := goDeps{dirs: make(map[string]bool), files: make(map[string]bool)}
+
+	goexe, err := c.ResolveGoExe(settings.GoSettings)
+	if err != nil {
+		return deps, err
+	}
+
+	const format = `{{ if and .Module .Module.Main }}d {{ .Dir }}{{ "\n" }}{{ range .EmbedFiles }}f {{ $.Dir }}/{{ . }}{{ "\n" }}{{ end }}{{ end }}`
+	args := append([]string{"list", "-deps", "-f", format}, settings.Flags...)
+	main := settings.Main
+	if main == "" {
+		main = "."
+	}
+	args = append(args, main)
+
+	keyVals := []string{"GOOS", goos, "GOARCH", goarch, "GOPROXY", settings.GoSettings.GoProxy}
+	for _, env := range settings.Env {
+		key, val := envhelpers.SplitEnvVar(env)
+		keyVals = append(keyVals, key, val)
+	}
+	environ := os.Environ()
+	envhelpers.SetEnvVars(&environ, keyVals...)
+
+	cmd := exec.Command(goexe, args...)
+	cmd.Dir = c.ProjectDir
+	cmd.Env = environ
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return deps, fmt.Errorf("-diff-base: go list failed for main %q: %w: %s", main, err, stderr.String())
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		typ, filename, found := strings.Cut(line, " ")
+		if !found {
+			continue
+		}
+		rel, err := filepath.Rel(c.ProjectDir, filepath.FromSlash(filename))
+		if err != nil {
+			return deps, err
+		}
+		rel = filepath.ToSlash(rel)
+		if typ == "d" {
+			deps.dirs[rel] = true
+		} else {
+			deps.files[rel] = true
+		}
+	}
+
+	return deps, nil
+}
+
+// snapshotTag creates a tag on the form v0.0.0-snapshot-20240101-abcdef1
+// from the commit date (UTC) and short hash of HEAD in dir.
+// Using the commit date makes the tag stable between the build, archive and release steps.
+func snapshotTag(dir string) (string, error) {
+	cmd := exec.Command("git", "log", "-1", "--format=%ct %h")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git log failed: %w: %s", err, out)
+	}
+	ts, hash, found := strings.Cut(strings.TrimSpace(string(out)), " ")
+	if !found {
+		return "", fmt.Errorf("unexpected git log output: %q", out)
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("unexpected git log output: %q: %w", out, err)
+	}
+	return fmt.Sprintf("v0.0.0-snapshot-%s-%s", time.Unix(unix, 0).UTC().Format("20060102"), hash), nil
+}
+
+// goosGoarchMatcher matches build paths on the form <build>/<goos>/<goarch>
+// by the last two path elements. An empty list matches any value.
+type goosGoarchMatcher struct {
+	goos   []string
+	goarch []string
+}
+
+func (m goosGoarchMatcher) Match(s string) bool {
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 {
+		return false
+	}
+	goos, goarch := parts[len(parts)-2], parts[len(parts)-1]
+	return matchesAny(m.goos, goos) && matchesAny(m.goarch, goarch)
+}
+
+func matchesAny(values []string, s string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 type stringFlags []string
 
 func (s *stringFlags) String() string {
@@ -455,3 +907,30 @@ func (s *stringFlags) Set(value string) error {
 	*s = append(*s, value)
 	return nil
 }
+
+// RetrySettings returns the retry settings from the -max-retries, -retry-initial-delay and -retry-max-delay flags.
+// Each retry is logged to log with the attempt number and the error.
+func (c *Core) RetrySettings(log logg.LevelLogger) releases.RetrySettings {
+	return releases.RetrySettings{
+		Set:          true,
+		MaxRetries:   c.MaxRetries,
+		InitialDelay: c.RetryInitialDelay,
+		MaxDelay:     c.RetryMaxDelay,
+		OnRetry: func(retry, maxRetries int, delay time.Duration, err error) {
+			log.WithField("attempt", fmt.Sprintf("%d/%d", retry+1, maxRetries+1)).Logf("Retrying in %s after temporary error: %v", delay, err)
+		},
+	}
+}
+
+// AssetName returns name (without extension) with the asset_name_prefix and asset_name_suffix templates applied.
+func (c *Core) AssetName(name string, tctx TemplateContext) (string, error) {
+	prefix, err := templ.Sprintt(c.Config.AssetNamePrefix, tctx)
+	if err != nil {
+		return "", fmt.Errorf("error compiling asset_name_prefix template: %w", err)
+	}
+	suffix, err := templ.Sprintt(c.Config.AssetNameSuffix, tctx)
+	if err != nil {
+		return "", fmt.Errorf("error compiling asset_name_suffix template: %w", err)
+	}
+	return prefix + name + suffix, nil
+}
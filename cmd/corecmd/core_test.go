@@ -1,9 +1,14 @@
 package corecmd
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/bep/logg"
 	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser/internal/common/logging"
 	"github.com/gohugoio/hugoreleaser/internal/common/matchers"
 )
 
@@ -90,3 +95,45 @@ func TestCompilePaths(t *testing.T) {
 	c.Assert((&Core{Paths: []string{"/**"}}).compilePaths(), qt.Not(qt.IsNil))
 
 }
+
+func TestGoosGoarchMatcher(t *testing.T) {
+	c := qt.New(t)
+
+	m := goosGoarchMatcher{goos: []string{"linux"}}
+	c.Assert(m.Match("main/linux/amd64"), qt.IsTrue)
+	c.Assert(m.Match("/linux/arm64"), qt.IsTrue)
+	c.Assert(m.Match("main/darwin/amd64"), qt.IsFalse)
+	c.Assert(m.Match("linux"), qt.IsFalse)
+
+	m = goosGoarchMatcher{goos: []string{"linux", "darwin"}, goarch: []string{"amd64"}}
+	c.Assert(m.Match("main/linux/amd64"), qt.IsTrue)
+	c.Assert(m.Match("main/darwin/amd64"), qt.IsTrue)
+	c.Assert(m.Match("main/linux/arm64"), qt.IsFalse)
+	c.Assert(m.Match("main/windows/amd64"), qt.IsFalse)
+}
+
+func TestIsPrereleaseTag(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(isPrereleaseTag("v1.2.0"), qt.IsFalse)
+	c.Assert(isPrereleaseTag("1.2.0"), qt.IsFalse)
+	c.Assert(isPrereleaseTag("v1.2.0+build-1"), qt.IsFalse)
+	c.Assert(isPrereleaseTag("v1.2.0-beta1"), qt.IsTrue)
+	c.Assert(isPrereleaseTag("v1.2.0-rc.1+build-1"), qt.IsTrue)
+	c.Assert(isPrereleaseTag("v0.0.0-snapshot-20240101-abcdef1"), qt.IsTrue)
+}
+
+func TestRetrySettingsLog(t *testing.T) {
+	c := qt.New(t)
+
+	var buf strings.Builder
+	l := logg.New(logg.Options{Level: logg.LevelInfo, Handler: logging.NewNoColoursHandler(&buf, &buf)})
+
+	core := &Core{MaxRetries: 3, RetryInitialDelay: time.Second}
+	settings := core.RetrySettings(l.WithLevel(logg.LevelInfo))
+	c.Assert(settings.MaxRetries, qt.Equals, 3)
+	settings.OnRetry(1, 3, time.Second, errors.New("rate limited"))
+
+	c.Assert(buf.String(), qt.Contains, "Retrying in 1s after temporary error: rate limited")
+	c.Assert(buf.String(), qt.Contains, `attempt "2/4"`)
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plancmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/gohugoio/hugoreleaser/cmd/corecmd"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+const commandName = "plan"
+
+// New returns a usable ffcli.Command for the plan subcommand.
+func New(core *corecmd.Core) *ffcli.Command {
+	fs := flag.NewFlagSet(corecmd.CommandName+" "+commandName, flag.ExitOnError)
+
+	p := &planner{
+		core: core,
+	}
+
+	core.RegisterFlags(fs)
+
+	return &ffcli.Command{
+		Name:       commandName,
+		ShortUsage: corecmd.CommandName + " " + commandName + " -tag <tag> [flags]",
+		ShortHelp:  "Print the builds matching -paths with their archives and releases as a tree, without building, archiving or releasing anything.",
+		FlagSet:    fs,
+		Exec:       p.Exec,
+	}
+}
+
+type planner struct {
+	core *corecmd.Core
+}
+
+func (p *planner) Exec(ctx context.Context, args []string) error {
+	return writePlan(os.Stdout, p.core)
+}
+
+// writePlan writes the build/archive/release matrix for the builds, archives and releases matching -paths to w:
+//
+//	builds/<build path> <binary>
+//	  archives/<archive path>/<archive name> <format>
+//	    releases/<release path>
+func writePlan(w io.Writer, c *corecmd.Core) error {
+	releases := c.Config.FindReleases(c.PathsReleasesCompiled)
+
+	for _, arch := range c.Config.FindArchs(c.PathsBuildsCompiled) {
+		if _, err := fmt.Fprintf(w, "%s %s\n", path.Join(c.DistRootBuilds, arch.Path), arch.Arch.BuildSettings.Binary); err != nil {
+			return err
+		}
+		for _, archive := range c.Config.Archives {
+			for _, archPath := range archive.ArchsCompiled {
+				if archPath.Path != arch.Path {
+					continue
+				}
+				typ := archive.ArchiveSettings.TypeFor(arch.Arch.Os.Goos, arch.Arch.Goarch)
+				if _, err := fmt.Fprintf(w, "  %s %s\n", path.Join(c.DistRootArchives, archPath.Path, archPath.Name), typ.Format); err != nil {
+					return err
+				}
+				for _, release := range releases {
+					if !containsArchive(release.ArchsCompiled, archPath) {
+						continue
+					}
+					if _, err := fmt.Fprintf(w, "    %s\n", path.Join(c.DistRootReleases, release.Path)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func containsArchive(archs []config.BuildArchPath, archPath config.BuildArchPath) bool {
+	for _, a := range archs {
+		if a.Path == archPath.Path && a.Name == archPath.Name {
+			return true
+		}
+	}
+	return false
+}
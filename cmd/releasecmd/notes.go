@@ -0,0 +1,145 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releasecmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gohugoio/hugoreleaser/cmd/corecmd"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+	"github.com/gohugoio/hugoreleaser/internal/releases"
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+const notesCommandName = "notes"
+
+// NewNotes returns a usable ffcli.Command for the release notes subcommand.
+func NewNotes(core *corecmd.Core) *ffcli.Command {
+	fs := flag.NewFlagSet(corecmd.CommandName+" "+commandName+" "+notesCommandName, flag.ExitOnError)
+
+	n := &notesPrinter{
+		core: core,
+	}
+
+	fs.StringVar(&n.commitish, "commitish", "", "The commitish value to collect the changes up to. Defaults to the tag.")
+	fs.StringVar(&n.changelogRange, "changelog-range", "", "An explicit Git commit range (fromRef..toRef) to collect the changes from, e.g. v1.0.0..v1.1.0 to re-generate the notes of an old release. Overrides changelog_range in the config.")
+	fs.BoolVar(&n.json, "json", false, "Print the grouped changes as JSON instead of the rendered release notes.")
+	fs.StringVar(&n.release, "release", "", "The release path to use the release notes settings from, e.g. releases/myrelease. Can be omitted if there's only one release.")
+
+	core.RegisterFlags(fs)
+
+	return &ffcli.Command{
+		Name:       notesCommandName,
+		ShortUsage: corecmd.CommandName + " " + commandName + " " + notesCommandName + " [flags]",
+		ShortHelp:  "Print the generated release notes to stdout without creating any files or releases.",
+		FlagSet:    fs,
+		Exec:       n.Exec,
+	}
+}
+
+type notesPrinter struct {
+	core *corecmd.Core
+
+	// Flags
+	commitish      string
+	release        string
+	changelogRange string
+	json           bool
+}
+
+func (n *notesPrinter) Exec(ctx context.Context, args []string) error {
+	what := commandName + " " + notesCommandName
+
+	if n.core.Tag == "" {
+		return fmt.Errorf("%s: flag -tag is required", what)
+	}
+
+	if err := n.core.InitConfig(); err != nil {
+		return err
+	}
+
+	release, err := n.findRelease()
+	if err != nil {
+		return fmt.Errorf("%s: %v", what, err)
+	}
+
+	info := releases.ReleaseInfo{
+		Project:   n.core.Config.Project,
+		Tag:       n.core.Tag,
+		Commitish: n.commitish,
+		Settings:  release.ReleaseSettings,
+	}
+	if n.changelogRange != "" {
+		info.Settings.ReleaseNotesSettings.ChangelogRange = n.changelogRange
+	}
+
+	// Use the client to resolve usernames if credentials are available,
+	// fall back to the commit authors if not.
+	var client releases.Client
+	if releases.Validate(release.ReleaseSettings.TypeParsed) == nil {
+		client, err = releases.NewClient(ctx, release.ReleaseSettings)
+		if err != nil {
+			return fmt.Errorf("%s: failed to create release client: %v", what, err)
+		}
+	}
+
+	b := &Releaser{
+		core:      n.core,
+		commitish: n.commitish,
+	}
+
+	rctx := releaseContext{
+		Ctx:    ctx,
+		Info:   info,
+		Client: client,
+	}
+
+	if n.json {
+		infosGrouped, err := b.collectChangeGroups(rctx)
+		if err != nil {
+			return err
+		}
+		return writeChangeGroupsJSON(os.Stdout, infosGrouped)
+	}
+
+	return b.writeReleaseNotes(rctx, os.Stdout)
+}
+
+// findRelease finds the release matching -release, or the only release if not set.
+func (n *notesPrinter) findRelease() (config.Release, error) {
+	releases := n.core.Config.Releases
+	if n.release == "" {
+		if len(releases) != 1 {
+			var paths []string
+			for _, r := range releases {
+				paths = append(paths, "releases/"+r.Path)
+			}
+			return config.Release{}, fmt.Errorf("flag -release is required when there are %d releases defined: %v", len(releases), paths)
+		}
+		return releases[0], nil
+	}
+
+	path := strings.TrimPrefix(n.release, "releases/")
+	for _, r := range releases {
+		if r.Path == path {
+			return r, nil
+		}
+	}
+	return config.Release{}, fmt.Errorf("release %q not found", n.release)
+}
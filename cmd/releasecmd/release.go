@@ -17,15 +17,23 @@ package releasecmd
 import (
 	"context"
 	_ "embed"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
-	"text/template"
+	"sync"
+	"time"
 
 	"github.com/bep/logg"
+	"github.com/bep/workers"
 	"github.com/gohugoio/hugoreleaser/cmd/corecmd"
+	"github.com/gohugoio/hugoreleaser/internal/common/ioh"
 	"github.com/gohugoio/hugoreleaser/internal/common/matchers"
 	"github.com/gohugoio/hugoreleaser/internal/common/templ"
 	"github.com/gohugoio/hugoreleaser/internal/config"
@@ -61,33 +69,99 @@ func NewReleaser(core *corecmd.Core, fs *flag.FlagSet) *Releaser {
 	}
 
 	fs.StringVar(&r.commitish, "commitish", "", "The commitish value that determines where the Git tag is created from.")
+	fs.BoolVar(&r.allowEmpty, "allow-empty", false, "Log a warning instead of failing when no releases or no release files are found.")
+	fs.BoolVar(&r.diff, "diff", false, "Print how the files differ from the existing release with the same tag (added, replaced or unchanged) without publishing anything.")
+	fs.BoolVar(&r.existing, "existing", false, "Upload the files to the existing release with the same tag instead of creating it, e.g. when the release is created by another job.")
+	fs.BoolVar(&r.draft, "draft", false, "Create the releases as drafts, overriding the draft setting in the config, e.g. to review them before they go public.")
+	fs.Int64Var(&r.releaseID, "release-id", 0, "Upload the files to the existing release with this ID instead of creating it. Implies -existing.")
+	fs.BoolVar(&r.failOversized, "fail-oversized", false, "Fail instead of logging a warning when a release file exceeds max_asset_size.")
+	fs.StringVar(&r.only, "only", "", "Glob matching the release paths to publish in this run, e.g. releases/myrelease. Applied after -paths.")
+	fs.BoolVar(&r.releaseNotesJSON, "release-notes-json", false, "Also write the grouped changes of the generated release notes to release-notes.json in the release dir.")
+	fs.IntVar(&r.parallelReleases, "parallel-releases", 0, "Max number of releases to publish in parallel, each with its own release client. All errors are reported at the end. 0 or 1 publishes the releases one by one, stopping at the first error.")
 
 	return r
 }
 
 type Releaser struct {
-	core    *corecmd.Core
-	infoLog logg.LevelLogger
+	core     *corecmd.Core
+	infoLog  logg.LevelLogger
+	warnLog  logg.LevelLogger
+	debugLog logg.LevelLogger
 
 	// Flags
-	commitish string
+	commitish  string
+	allowEmpty bool
+	diff       bool
+	existing   bool
+	draft      bool
+	releaseID  int64
+	only       string
+
+	failOversized    bool
+	releaseNotesJSON bool
+	parallelReleases int
+
+	onlyCompiled matchers.Matcher
+
+	// Guards the changelog file, which releases published in parallel may share.
+	changelogMu sync.Mutex
 }
 
 func (b *Releaser) Init() error {
-	if b.commitish == "" {
+	if b.releaseID != 0 {
+		b.existing = true
+	}
+
+	if b.parallelReleases < 0 {
+		return fmt.Errorf("%s: flag -parallel-releases must not be negative", commandName)
+	}
+
+	if b.commitish == "" && !b.core.Snapshot && !b.existing {
 		return fmt.Errorf("%s: flag -commitish is required", commandName)
 	}
 
+	if b.commitish == "" && b.core.Snapshot {
+		// The snapshot tag is never created, so collect the changes
+		// since the latest version tag up to the current commit.
+		b.commitish = "HEAD"
+	}
+
 	b.infoLog = b.core.InfoLog.WithField("cmd", commandName)
+	b.warnLog = b.core.WarnLog.WithField("cmd", commandName)
+	b.debugLog = b.core.DebugLog.WithField("cmd", commandName)
+
+	b.onlyCompiled = matchers.MatchEverything
+	if b.only != "" {
+		var err error
+		b.onlyCompiled, err = matchers.Glob(strings.TrimPrefix(b.only, "releases/"))
+		if err != nil {
+			return fmt.Errorf("%s: error compiling -only %q: %w", commandName, b.only, err)
+		}
+	}
 
-	releaseMatches := b.core.Config.FindReleases(b.core.PathsReleasesCompiled)
+	releaseMatches := b.findReleases()
 	if len(releaseMatches) == 0 {
-		return fmt.Errorf("%s: no releases found matching -paths %v", commandName, b.core.Paths)
+		if b.allowEmpty {
+			b.warnLog.Logf("No releases found matching -paths %v -only %q", b.core.Paths, b.only)
+			return nil
+		}
+		return fmt.Errorf("%s: no releases found matching -paths %v -only %q", commandName, b.core.Paths, b.only)
+	}
+	if b.releaseID != 0 && len(releaseMatches) > 1 {
+		return fmt.Errorf("%s: -release-id can only be used with one release, found %d matching -paths %v -only %q", commandName, len(releaseMatches), b.core.Paths, b.only)
 	}
 	for _, r := range releaseMatches {
 		if err := releases.Validate(r.ReleaseSettings.TypeParsed); err != nil {
 			return err
 		}
+		for _, mirror := range r.ReleaseSettings.Mirrors {
+			if err := releases.Validate(mirror.TypeParsed); err != nil {
+				return err
+			}
+		}
+		if b.releaseID != 0 && len(r.ReleaseSettings.Mirrors) > 0 {
+			return fmt.Errorf("%s: -release-id can not be used with mirrors", commandName)
+		}
 	}
 
 	return nil
@@ -114,11 +188,18 @@ func (b *Releaser) Exec(ctx context.Context, args []string) error {
 	if len(b.core.Paths) > 0 {
 		logFields = append(logFields, logg.Field{Name: "paths", Value: b.core.Paths})
 	}
+	if b.only != "" {
+		logFields = append(logFields, logg.Field{Name: "only", Value: b.only})
+	}
 
 	logCtx := b.infoLog.WithFields(logFields)
 
 	logCtx.Log(logg.String("Finding releases"))
-	releaseMatches := b.core.Config.FindReleases(b.core.PathsReleasesCompiled)
+	releaseMatches := b.findReleases()
+
+	if b.parallelReleases > 1 && len(releaseMatches) > 1 {
+		return b.handleReleasesParallel(ctx, logCtx, releaseMatches)
+	}
 
 	for _, release := range releaseMatches {
 		if err := b.handleRelease(ctx, logCtx, release); err != nil {
@@ -130,12 +211,71 @@ func (b *Releaser) Exec(ctx context.Context, args []string) error {
 	return nil
 }
 
+// handleReleasesParallel publishes up to b.parallelReleases releases at a time.
+// Each release gets its own client (see handleRelease) and a logger with a release field.
+// A failing release does not stop the others, all errors are returned at the end.
+func (b *Releaser) handleReleasesParallel(ctx context.Context, logCtx logg.LevelLogger, releaseMatches []config.Release) error {
+	logCtx.WithField("releases", strconv.Itoa(len(releaseMatches))).Logf("Publishing releases with -parallel-releases %d", b.parallelReleases)
+
+	var (
+		mu   sync.Mutex
+		errs []string
+	)
+
+	// Note that we don't use the global workforce here, as that is used
+	// to upload the files inside each release.
+	r, _ := workers.New(b.parallelReleases).Start(ctx)
+	for _, release := range releaseMatches {
+		release := release
+		r.Run(func() error {
+			if err := b.handleRelease(ctx, logCtx.WithField("release", release.Path), release); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Sprintf("%q: %v", release.Path, err))
+				mu.Unlock()
+			}
+			// Let the other releases complete.
+			return nil
+		})
+	}
+
+	if err := r.Wait(); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("%s: %d of %d releases failed:\n%s", commandName, len(errs), len(releaseMatches), strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
+
+// findReleases returns the releases matching both -paths and -only.
+func (b *Releaser) findReleases() []config.Release {
+	var releaseMatches []config.Release
+	for _, release := range b.core.Config.FindReleases(b.core.PathsReleasesCompiled) {
+		if b.onlyCompiled.Match(release.Path) {
+			releaseMatches = append(releaseMatches, release)
+		}
+	}
+	return releaseMatches
+}
+
 type releaseContext struct {
 	Ctx        context.Context
 	Log        logg.LevelLogger
 	ReleaseDir string
 	Client     releases.Client
 	Info       releases.ReleaseInfo
+
+	// Additional targets to publish the release to, see mirrors in the release settings.
+	Mirrors []releaseTarget
+}
+
+// releaseTarget is a release client with the release info to use with it.
+type releaseTarget struct {
+	Client releases.Client
+	Info   releases.ReleaseInfo
 }
 
 func (b *Releaser) handleRelease(ctx context.Context, logCtx logg.LevelLogger, release config.Release) error {
@@ -147,11 +287,28 @@ func (b *Releaser) handleRelease(ctx context.Context, logCtx logg.LevelLogger, r
 		filepath.FromSlash(release.Path),
 	)
 
+	if len(release.ArchsCompiled) == 0 && b.core.DiffBase != "" {
+		logCtx.Logf("Skipping release %q: no changes since %s", release.Path, b.core.DiffBase)
+		return nil
+	}
+
+	if len(release.ArchsCompiled) == 0 && len(release.ReleaseSettings.ExtraFiles) == 0 {
+		if b.allowEmpty {
+			b.warnLog.Logf("No files found for release %q", release.Path)
+			return nil
+		}
+		return fmt.Errorf("%s: no files found for release %q", commandName, release.Path)
+	}
+
 	info := releases.ReleaseInfo{
 		Project:   b.core.Config.Project,
 		Tag:       b.core.Tag,
 		Commitish: b.commitish,
 		Settings:  release.ReleaseSettings,
+		Retry:     b.core.RetrySettings(logCtx),
+	}
+	if b.draft {
+		info.Settings.Draft = true
 	}
 
 	var client releases.Client
@@ -159,7 +316,7 @@ func (b *Releaser) handleRelease(ctx context.Context, logCtx logg.LevelLogger, r
 		client = &releases.FakeClient{}
 	} else {
 		var err error
-		client, err = releases.NewClient(ctx, release.ReleaseSettings.TypeParsed)
+		client, err = releases.NewClient(ctx, release.ReleaseSettings)
 		if err != nil {
 			return fmt.Errorf("%s: failed to create release client: %v", commandName, err)
 		}
@@ -173,6 +330,21 @@ func (b *Releaser) handleRelease(ctx context.Context, logCtx logg.LevelLogger, r
 		Client:     client,
 	}
 
+	if !b.core.Try {
+		for _, mirror := range info.Settings.Mirrors {
+			mirrorInfo := info
+			mirrorInfo.Settings = mirror
+			if b.draft {
+				mirrorInfo.Settings.Draft = true
+			}
+			mirrorClient, err := releases.NewClient(ctx, mirror)
+			if err != nil {
+				return fmt.Errorf("%s: failed to create release client for mirror %q: %v", commandName, mirror.Type, err)
+			}
+			rctx.Mirrors = append(rctx.Mirrors, releaseTarget{Client: mirrorClient, Info: mirrorInfo})
+		}
+	}
+
 	if _, err := os.Stat(rctx.ReleaseDir); err == nil || os.IsNotExist(err) {
 		if !os.IsNotExist(err) {
 			// Start fresh.
@@ -188,38 +360,98 @@ func (b *Releaser) handleRelease(ctx context.Context, logCtx logg.LevelLogger, r
 
 	// First collect all files to be released.
 	var archiveFilenames []string
+	// Display labels keyed by filename.
+	labels := make(map[string]string)
 
 	for _, archPath := range release.ArchsCompiled {
-		archiveDir := filepath.Join(
-			b.core.DistDir,
-			b.core.Config.Project,
-			b.core.Tag,
-			b.core.DistRootArchives,
-			filepath.FromSlash(archPath.Path),
-		)
-		archiveFilenames = append(archiveFilenames, filepath.Join(archiveDir, archPath.Name))
+		archiveDir := filepath.Join(b.archivesDir(), filepath.FromSlash(archPath.Path))
+		archiveFilename := filepath.Join(archiveDir, archPath.Name)
+		archiveFilenames = append(archiveFilenames, archiveFilename)
+		if archPath.Label != "" {
+			labels[archiveFilename] = archPath.Label
+		}
 		for _, alias := range archPath.Aliases {
 			archiveFilenames = append(archiveFilenames, filepath.Join(archiveDir, alias))
 		}
 	}
 
+	for _, extraFile := range release.ReleaseSettings.ExtraFiles {
+		archiveFilenames = append(archiveFilenames, filepath.Join(b.core.ProjectDir, filepath.FromSlash(extraFile)))
+	}
+
+	signing := info.Settings.SigningSettings
+
 	if b.core.Try {
-		return nil
+		if signing.Enabled() && len(archiveFilenames) > 0 {
+			if signing.SignArchives {
+				for _, filename := range archiveFilenames {
+					logCtx.Logf("Would sign %q", filepath.Base(filename))
+				}
+			}
+			logCtx.Log(logg.String("Would sign the checksums file"))
+			if signing.PublicKeyFilename != "" {
+				logCtx.Logf("Would upload the public key as %q", signing.PublicKeyFilename)
+			}
+		}
+		return b.writeReleasePlan(rctx, release, archiveFilenames, labels)
 	}
 
-	if len(archiveFilenames) > 0 {
+	if err := checkFilesExist(release, archiveFilenames); err != nil {
+		return err
+	}
 
-		checksumFilename, err := b.generateChecksumTxt(rctx, archiveFilenames...)
+	var checksumFilename string
+	if len(archiveFilenames) > 0 {
+		var checksumFilenames []string
+		var err error
+		if info.Settings.ChecksumMode == config.ChecksumModePerFile {
+			checksumFilenames, err = b.generatePerFileChecksums(rctx, archiveFilenames...)
+		} else {
+			checksumFilenames, err = b.generateChecksumTxt(rctx, archiveFilenames...)
+			if err == nil {
+				checksumFilename = checksumFilenames[0]
+			}
+		}
 		if err != nil {
 			return err
 		}
 
-		archiveFilenames = append(archiveFilenames, checksumFilename)
+		var toSign []string
+		if signing.Enabled() {
+			if signing.SignArchives {
+				toSign = append(toSign, archiveFilenames...)
+			}
+			toSign = append(toSign, checksumFilenames...)
+		}
+
+		archiveFilenames = append(archiveFilenames, checksumFilenames...)
+
+		for _, filename := range toSign {
+			signatureFilename, err := releases.SignFile(ctx, signing, filename, rctx.ReleaseDir)
+			if err != nil {
+				return fmt.Errorf("%s: %v", commandName, err)
+			}
+			logCtx.WithField("filename", signatureFilename).Log(logg.String("Created signature file"))
+			archiveFilenames = append(archiveFilenames, signatureFilename)
+		}
+
+		if signing.Enabled() && signing.PublicKeyFilename != "" {
+			publicKeyFilename, err := releases.WritePublicKey(ctx, signing, rctx.ReleaseDir)
+			if err != nil {
+				return fmt.Errorf("%s: %v", commandName, err)
+			}
+			logCtx.WithField("filename", publicKeyFilename).Log(logg.String("Created public key file"))
+			archiveFilenames = append(archiveFilenames, publicKeyFilename)
+		}
 
 		logCtx.Logf("Prepared %d files to archive: %v", len(archiveFilenames), archiveFilenames)
 
 	}
 
+	if b.diff {
+		return b.diffRelease(rctx, checksumFilename, archiveFilenames)
+	}
+
 	// Generate release notes if needed.
 	// Write them to the release dir in dist to make testing easier.
 	if info.Settings.ReleaseNotesSettings.Generate {
@@ -233,29 +465,426 @@ func (b *Releaser) handleRelease(ctx context.Context, logCtx logg.LevelLogger, r
 		info.Settings.ReleaseNotesSettings.Filename = releaseNotesFilename
 	}
 
-	// Now create the release archive and upload files.
-	releaseID, err := client.Release(ctx, info)
+	// The release notes are used as the release body,
+	// and are never uploaded as an asset unless explicitly requested.
+	if releaseNotesFilename := info.Settings.ReleaseNotesSettings.Filename; releaseNotesFilename != "" && info.Settings.ReleaseNotesSettings.Upload {
+		if !filepath.IsAbs(releaseNotesFilename) {
+			releaseNotesFilename = filepath.Join(b.core.ProjectDir, releaseNotesFilename)
+		}
+		archiveFilenames = append(archiveFilenames, releaseNotesFilename)
+	}
+
+	if err := b.checkAssetSizes(rctx, archiveFilenames); err != nil {
+		return err
+	}
+
+	if b.core.Snapshot {
+		// The release artifacts are written to dist, but nothing gets published.
+		logCtx.Log(logg.String("Snapshot mode, skipping publish"))
+		return nil
+	}
+
+	// Now publish the release to all targets, reporting all errors at the end.
+	targets := append([]releaseTarget{{Client: client, Info: info}}, rctx.Mirrors...)
+	var errs []string
+	for i, target := range targets {
+		trctx := rctx
+		trctx.Client, trctx.Info = target.Client, target.Info
+		what := target.Info.Settings.Type
+		if i > 0 {
+			// Mirrors share the release notes.
+			trctx.Info.Settings.ReleaseNotesSettings = info.Settings.ReleaseNotesSettings
+			what = fmt.Sprintf("mirrors[%d] (%s)", i-1, what)
+			trctx.Log = logCtx.WithField("mirror", target.Info.Settings.Type)
+		}
+		if err := b.publish(trctx, archiveFilenames, labels); err != nil {
+			if len(targets) == 1 {
+				return err
+			}
+			errs = append(errs, fmt.Sprintf("%s: %v", what, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s: %d of %d release targets failed:\n%s", commandName, len(errs), len(targets), strings.Join(errs, "\n"))
+	}
+
+	// Only update the changelog file once the release is published,
+	// so a failed release does not leave it modified.
+	if info.Settings.ReleaseNotesSettings.ChangelogFilename != "" {
+		if err := b.updateChangelogFile(rctx, info.Settings.ReleaseNotesSettings); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// publish creates (or finds) the release using rctx.Client and uploads and verifies the files.
+func (b *Releaser) publish(rctx releaseContext, archiveFilenames []string, labels map[string]string) error {
+	ctx, client, info, logCtx := rctx.Ctx, rctx.Client, rctx.Info, rctx.Log
+
+	releaseID, err := b.createOrFindRelease(ctx, client, info)
 	if err != nil {
-		return fmt.Errorf("%s: failed to create release: %v", commandName, err)
+		return err
+	}
+	if info.Settings.OnExisting == config.OnExistingReplace && (b.existing || b.releaseID != 0) {
+		if err := b.deleteExistingAssets(rctx, releaseID, archiveFilenames); err != nil {
+			return err
+		}
 	}
-	r, ctx := b.core.Workforce.Start(ctx)
 
-	for _, archiveFilename := range archiveFilenames {
-		archiveFilename := archiveFilename
-		r.Run(func() error {
-			openFile := func() (*os.File, error) {
-				return os.Open(archiveFilename)
+	// Log the progress of large uploads with -debug.
+	if b.core.Debug {
+		info.OnUploadProgress = func(filename string, n, size int64) {
+			percent := int64(100)
+			if size > 0 {
+				percent = n * 100 / size
+			}
+			b.debugLog.WithField("file", filename).Logf("Uploaded %d of %d bytes (%d%%)", n, size, percent)
+		}
+	}
+
+	onUpload := func(archiveFilename, label string) {
+		uploadLog := logCtx
+		if label != "" {
+			uploadLog = uploadLog.WithField("label", label)
+		}
+		uploadLog.Logf("Uploading release file %s", archiveFilename)
+	}
+
+	if err := releases.UploadAssetsFiles(ctx, b.core.Workforce, client, info, releaseID, labels, onUpload, archiveFilenames...); err != nil {
+		return fmt.Errorf("%s: failed to upload files: %v", commandName, err)
+	}
+
+	logCtx.Log(logg.String("Verifying release assets"))
+	if err := releases.VerifyAssets(ctx, client, info, releaseID, b.existing, archiveFilenames...); err != nil {
+		return fmt.Errorf("%s: %v", commandName, err)
+	}
+
+	if p, ok := client.(releases.ReleaseURLProvider); ok {
+		if u := p.ReleaseURL(releaseID); u != "" {
+			msg := "Published release"
+			if info.Settings.Draft {
+				msg = "Draft release ready for review"
+			}
+			logCtx.WithField("url", u).Log(logg.String(msg))
+		}
+	}
+
+	return nil
+}
+
+// releasePlan describes what a release would publish, written to release-plan.json in the release dir on -try.
+type releasePlan struct {
+	Release   string            `json:"release"`
+	Type      string            `json:"type"`
+	Tag       string            `json:"tag"`
+	Commitish string            `json:"commitish"`
+	Draft     bool              `json:"draft"`
+	Files     []releasePlanFile `json:"files"`
+
+	// The names of the checksums files that would be created, not set for checksum_mode "per-file".
+	ChecksumFiles []string `json:"checksum_files,omitempty"`
+}
+
+type releasePlanFile struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Label string `json:"label,omitempty"`
+	Size  int64  `json:"size"`
+
+	// Set if the file does not exist (yet), e.g. when the archives were also created with -try.
+	Missing bool `json:"missing,omitempty"`
+}
+
+// writeReleasePlan logs the files that would be uploaded and writes the plan as JSON to the release dir.
+// The checksums files are only included by name and signature files are not included, as they are not created on -try.
+func (b *Releaser) writeReleasePlan(rctx releaseContext, release config.Release, filenames []string, labels map[string]string) error {
+	plan := releasePlan{
+		Release:   release.Path,
+		Type:      rctx.Info.Settings.Type,
+		Tag:       rctx.Info.Tag,
+		Commitish: rctx.Info.Commitish,
+		Draft:     rctx.Info.Settings.Draft,
+		Files:     make([]releasePlanFile, 0, len(filenames)),
+	}
+
+	if len(filenames) > 0 && rctx.Info.Settings.ChecksumMode != config.ChecksumModePerFile {
+		names, err := b.checksumTxtNames(rctx)
+		if err != nil {
+			return err
+		}
+		if !rctx.Info.Settings.SeparateChecksumFiles {
+			names = names[:1]
+		}
+		plan.ChecksumFiles = names
+	}
+
+	for _, filename := range filenames {
+		f := releasePlanFile{
+			Name:  filepath.Base(filename),
+			Path:  filename,
+			Label: labels[filename],
+		}
+		size := "missing"
+		if fi, err := os.Stat(filename); err == nil {
+			f.Size = fi.Size()
+			size = strconv.FormatInt(f.Size, 10)
+		} else {
+			f.Missing = true
+		}
+		plan.Files = append(plan.Files, f)
+		rctx.Log.WithField("size", size).Logf("Would upload %s", f.Name)
+	}
+	for _, name := range plan.ChecksumFiles {
+		rctx.Log.Logf("Would upload checksums file %s", name)
+	}
+
+	planFilename := filepath.Join(rctx.ReleaseDir, "release-plan.json")
+	err := func() error {
+		f, err := os.Create(planFilename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}()
+	if err != nil {
+		return fmt.Errorf("%s: failed to create release plan file %q: %s", commandName, planFilename, err)
+	}
+
+	rctx.Log.WithField("filename", planFilename).Log(logg.String("Created release plan"))
+
+	return nil
+}
+
+// deleteExistingAssets deletes the assets in the existing release with the same name as any of filenames,
+// so they can be uploaded again.
+func (b *Releaser) deleteExistingAssets(rctx releaseContext, releaseID int64, filenames []string) error {
+	deleter, ok := rctx.Client.(releases.AssetDeleter)
+	if !ok {
+		return fmt.Errorf("%s: on_existing=%q is not supported by the %q release client", commandName, config.OnExistingReplace, rctx.Info.Settings.Type)
+	}
+
+	assets, err := rctx.Client.ListAssets(rctx.Ctx, rctx.Info, releaseID)
+	if err != nil {
+		return fmt.Errorf("%s: failed to list release assets: %v", commandName, err)
+	}
+	existing := make(map[string]releases.Asset)
+	for _, asset := range assets {
+		existing[asset.Name] = asset
+	}
+
+	for _, filename := range filenames {
+		name := filepath.Base(filename)
+		asset, found := existing[name]
+		if !found {
+			continue
+		}
+		rctx.Log.Logf("Deleting existing release file %s", name)
+		if err := releases.DeleteAssetWithRetries(rctx.Ctx, deleter, rctx.Info, releaseID, asset); err != nil {
+			return fmt.Errorf("%s: failed to delete release file %q: %v", commandName, name, err)
+		}
+	}
+
+	return nil
+}
+
+// updateChangelogFile inserts the release notes into the project's changelog file.
+func (b *Releaser) updateChangelogFile(rctx releaseContext, settings config.ReleaseNotesSettings) error {
+	releaseNotesFilename, changelogFilename := settings.Filename, settings.ChangelogFilename
+	if releaseNotesFilename == "" {
+		return fmt.Errorf("%s: changelog_filename is set, but there are no release notes, set either generate or filename in release_notes_settings", commandName)
+	}
+	if !filepath.IsAbs(releaseNotesFilename) {
+		releaseNotesFilename = filepath.Join(b.core.ProjectDir, releaseNotesFilename)
+	}
+	if !filepath.IsAbs(changelogFilename) {
+		changelogFilename = filepath.Join(b.core.ProjectDir, changelogFilename)
+	}
+
+	notes, err := os.ReadFile(releaseNotesFilename)
+	if err != nil {
+		return fmt.Errorf("%s: failed to read release notes: %v", commandName, err)
+	}
+	b.changelogMu.Lock()
+	updated, err := releases.UpdateChangelogFile(changelogFilename, rctx.Info.Tag, notes)
+	b.changelogMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("%s: failed to update changelog: %v", commandName, err)
+	}
+
+	logCtx := rctx.Log.WithField("filename", changelogFilename)
+	if updated {
+		logCtx.Log(logg.String("Updated changelog"))
+	} else {
+		logCtx.Log(logg.String("Changelog already has this release, skipping"))
+	}
+
+	return nil
+}
+
+// checkAssetSizes logs the size of each file and warns (or fails with -fail-oversized)
+// if any of them exceeds the max asset size, so we fail before the release gets created.
+func (b *Releaser) checkAssetSizes(rctx releaseContext, filenames []string) error {
+	settings := rctx.Info.Settings
+	maxSize := settings.MaxAssetSize
+	if maxSize == 0 {
+		maxSize = releases.MaxAssetSize(settings.TypeParsed)
+	}
+
+	var oversized []string
+	for _, filename := range filenames {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			return fmt.Errorf("%s: %v", commandName, err)
+		}
+		rctx.Log.WithField("size", strconv.FormatInt(fi.Size(), 10)).Logf("Release file %s", filepath.Base(filename))
+		if maxSize > 0 && fi.Size() > maxSize {
+			oversized = append(oversized, fmt.Sprintf("%s (%d bytes)", filepath.Base(filename), fi.Size()))
+		}
+	}
+
+	if len(oversized) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("%d file(s) exceed the max asset size of %d bytes: %s", len(oversized), maxSize, strings.Join(oversized, ", "))
+	if b.failOversized {
+		return fmt.Errorf("%s: %s", commandName, msg)
+	}
+	b.warnLog.Log(logg.String(msg))
+
+	return nil
+}
+
+// checkFilesExist returns an error listing all of filenames that do not exist,
+// so a partially built matrix fails before anything gets created or uploaded.
+func checkFilesExist(release config.Release, filenames []string) error {
+	var missing []string
+	for _, filename := range filenames {
+		if _, err := os.Stat(filename); err != nil {
+			if !os.IsNotExist(err) {
+				return fmt.Errorf("%s: %v", commandName, err)
+			}
+			missing = append(missing, filename)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s: %d file(s) not found for release %q, make sure the archive command has completed:\n%s", commandName, len(missing), release.Path, strings.Join(missing, "\n"))
+}
+
+// createOrFindRelease creates the release or, with -existing or -release-id, looks up the existing one.
+func (b *Releaser) createOrFindRelease(ctx context.Context, client releases.Client, info releases.ReleaseInfo) (int64, error) {
+	if b.releaseID != 0 {
+		return b.releaseID, nil
+	}
+
+	if !b.existing {
+		releaseID, err := releases.ReleaseWithRetries(ctx, client, info)
+		if err != nil {
+			return 0, fmt.Errorf("%s: failed to create release: %v", commandName, err)
+		}
+		return releaseID, nil
+	}
+
+	finder, ok := client.(releases.ReleaseFinder)
+	if !ok {
+		return 0, fmt.Errorf("%s: -existing is not supported by the %q release client", commandName, info.Settings.Type)
+	}
+	releaseID, err := finder.FindRelease(ctx, info)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to find release %q: %v", commandName, info.Tag, err)
+	}
+	if releaseID == 0 {
+		return 0, fmt.Errorf("%s: release %q not found", commandName, info.Tag)
+	}
+	return releaseID, nil
+}
+
+// diffRelease prints how the files in archiveFilenames differ from the existing release.
+func (b *Releaser) diffRelease(rctx releaseContext, checksumFilename string, archiveFilenames []string) error {
+	finder, ok := rctx.Client.(releases.ReleaseFinder)
+	if !ok {
+		return fmt.Errorf("%s: -diff is not supported by the %q release client", commandName, rctx.Info.Settings.Type)
+	}
+
+	if rctx.Info.Settings.ChecksumLineTemplate != "" {
+		// We can only parse the default checksum line format, so compare by name and size only.
+		checksumFilename = ""
+	}
+
+	readChecksums := func(filename string) (map[string]string, error) {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return releases.ParseChecksumLines(f)
+	}
+
+	var local []releases.Asset
+	localChecksums := make(map[string]string)
+	if checksumFilename != "" {
+		var err error
+		if localChecksums, err = readChecksums(checksumFilename); err != nil {
+			return err
+		}
+	}
+	for _, filename := range archiveFilenames {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		name := filepath.Base(filename)
+		local = append(local, releases.Asset{Name: name, Size: fi.Size(), Checksum: localChecksums[name]})
+	}
+
+	var remote []releases.Asset
+	releaseID, err := finder.FindRelease(rctx.Ctx, rctx.Info)
+	if err != nil {
+		return fmt.Errorf("%s: failed to find release %q: %v", commandName, rctx.Info.Tag, err)
+	}
+	if releaseID != 0 {
+		remote, err = rctx.Client.ListAssets(rctx.Ctx, rctx.Info, releaseID)
+		if err != nil {
+			return fmt.Errorf("%s: failed to list release assets: %v", commandName, err)
+		}
+
+		// Use the checksums file in the release, if any, to compare the content.
+		downloader, ok := rctx.Client.(releases.AssetDownloader)
+		checksumName := filepath.Base(checksumFilename)
+		for _, a := range remote {
+			if !ok || checksumFilename == "" || a.Name != checksumName {
+				continue
 			}
-			logCtx.Logf("Uploading release file %s", archiveFilename)
-			if err := releases.UploadAssetsFileWithRetries(ctx, client, info, releaseID, openFile); err != nil {
+			filename, err := releases.DownloadAssetsFileWithRetries(rctx.Ctx, downloader, rctx.Info, filepath.Join(rctx.ReleaseDir, "remote"), checksumName)
+			if err != nil {
 				return err
 			}
-			return nil
-		})
+			remoteChecksums, err := readChecksums(filename)
+			if err != nil {
+				return err
+			}
+			for i := range remote {
+				remote[i].Checksum = remoteChecksums[remote[i].Name]
+			}
+			break
+		}
+	} else {
+		rctx.Log.Logf("Release %q not found", rctx.Info.Tag)
 	}
 
-	if err := r.Wait(); err != nil {
-		return fmt.Errorf("%s: failed to upload files: %v", commandName, err)
+	for _, change := range releases.DiffAssets(local, remote) {
+		rctx.Log.Logf("Diff: %s %s", change.Status, change.Name)
 	}
 
 	return nil
@@ -266,23 +895,123 @@ func (b *Releaser) generateReleaseNotes(rctx releaseContext) (string, error) {
 		return "", fmt.Errorf("%s: both GenerateReleaseNotes and ReleaseNotesFilename are set for release type %q", commandName, rctx.Info.Settings.Type)
 	}
 
+	infosGrouped, err := b.collectChangeGroups(rctx)
+	if err != nil {
+		return "", err
+	}
+
+	releaseNotesFilename := filepath.Join(rctx.ReleaseDir, "release-notes.md")
+	rctx.Info.Settings.ReleaseNotesSettings.Filename = releaseNotesFilename
+	err = func() error {
+		f, err := os.Create(releaseNotesFilename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		return b.renderReleaseNotes(rctx, f, infosGrouped)
+	}()
+
+	if err != nil {
+		return "", fmt.Errorf("%s: failed to create release notes file %q: %s", commandName, releaseNotesFilename, err)
+	}
+
+	rctx.Log.WithField("filename", releaseNotesFilename).Log(logg.String("Created release notes"))
+
+	if b.releaseNotesJSON {
+		jsonFilename := filepath.Join(rctx.ReleaseDir, "release-notes.json")
+		err := func() error {
+			f, err := os.Create(jsonFilename)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			return writeChangeGroupsJSON(f, infosGrouped)
+		}()
+		if err != nil {
+			return "", fmt.Errorf("%s: failed to create release notes JSON file %q: %s", commandName, jsonFilename, err)
+		}
+
+		rctx.Log.WithField("filename", jsonFilename).Log(logg.String("Created release notes JSON"))
+	}
+
+	return releaseNotesFilename, nil
+}
+
+// writeChangeGroupsJSON writes the grouped changes as indented JSON to w.
+func writeChangeGroupsJSON(w io.Writer, groups []changelog.TitleChanges) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(groups)
+}
+
+// writeReleaseNotes collects the changes from Git up to b.commitish,
+// groups them and renders the release notes template to w.
+func (b *Releaser) writeReleaseNotes(rctx releaseContext, w io.Writer) error {
+	infosGrouped, err := b.collectChangeGroups(rctx)
+	if err != nil {
+		return err
+	}
+	return b.renderReleaseNotes(rctx, w, infosGrouped)
+}
+
+// usernameResolver returns the first of the release targets in rctx that can resolve usernames,
+// with the release info to use with it.
+func usernameResolver(rctx releaseContext) (releases.UsernameResolver, releases.ReleaseInfo, bool) {
+	targets := append([]releaseTarget{{Client: rctx.Client, Info: rctx.Info}}, rctx.Mirrors...)
+	for _, target := range targets {
+		if unc, ok := target.Client.(releases.UsernameResolver); ok {
+			return unc, target.Info, true
+		}
+	}
+	return nil, releases.ReleaseInfo{}, false
+}
+
+// collectChangeGroups collects the changes from Git up to b.commitish
+// and groups them according to the release notes settings.
+func (b *Releaser) collectChangeGroups(rctx releaseContext) ([]changelog.TitleChanges, error) {
 	var resolveUsername func(commit, author string) (string, error)
-	if unc, ok := rctx.Client.(releases.UsernameResolver); ok {
+	if unc, info, ok := usernameResolver(rctx); ok {
 		resolveUsername = func(commit, author string) (string, error) {
-			return unc.ResolveUsername(rctx.Ctx, commit, author, rctx.Info)
+			username, err := unc.ResolveUsername(rctx.Ctx, commit, author, info)
+			if err != nil {
+				// Don't fail the release because of a missing username.
+				b.core.WarnLog.WithField("cmd", commandName).Logf("Failed to resolve username for %q in commit %s: %v", author, commit, err)
+				return "", nil
+			}
+			return username, nil
 		}
 	}
 
-	infos, err := changelog.CollectChanges(
-		changelog.Options{
-			Tag:             b.core.Tag,
-			Commitish:       b.commitish,
-			RepoPath:        os.Getenv("HUGORELEASER_CHANGELOG_GITREPO"), // Set in tests.
-			ResolveUserName: resolveUsername,
-		},
-	)
+	opts := changelog.Options{
+		Tag:             b.core.Tag,
+		Commitish:       b.commitish,
+		RepoPath:        os.Getenv("HUGORELEASER_CHANGELOG_GITREPO"), // Set in tests.
+		ResolveUserName: resolveUsername,
+		RepositoryURL:   rctx.Info.Settings.ReleaseNotesSettings.RepositoryURL,
+		IncludePaths:    rctx.Info.Settings.ReleaseNotesSettings.IncludePaths,
+	}
+
+	for _, g := range rctx.Info.Settings.ReleaseNotesSettings.Groups {
+		opts.Types = append(opts.Types, g.Types...)
+	}
+
+	if changelogRange := rctx.Info.Settings.ReleaseNotesSettings.ChangelogRange; changelogRange != "" {
+		r, err := templ.Sprintt(changelogRange, b.core.NewTemplateContext("", ""))
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to render changelog_range: %v", commandName, err)
+		}
+		from, to, found := strings.Cut(r, "..")
+		if !found || from == "" || to == "" {
+			return nil, fmt.Errorf("%s: changelog_range must be on the form fromRef..toRef, got %q", commandName, r)
+		}
+		opts.From, opts.To = strings.TrimSpace(from), strings.TrimSpace(to)
+	}
+
+	infos, err := changelog.CollectChanges(opts)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("%s: failed to collect changes: %v", commandName, err)
 	}
 
 	changeGroups := rctx.Info.Settings.ReleaseNotesSettings.Groups
@@ -305,7 +1034,7 @@ func (b *Releaser) generateReleaseNotes(rctx releaseContext) (string, error) {
 
 	infosGrouped, err := changelog.GroupByTitleFunc(infos, func(change changelog.Change) (string, int, bool) {
 		for i, g := range changeGroups {
-			if g.RegexpCompiled.Match(change.Subject) {
+			if g.Match(change.Subject, change.Type, change.Breaking) {
 				if g.Ignore {
 					return "", 0, false
 				}
@@ -320,93 +1049,320 @@ func (b *Releaser) generateReleaseNotes(rctx releaseContext) (string, error) {
 	})
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	if rctx.Info.Settings.ReleaseNotesSettings.FailIfEmpty {
+		var numChanges int
+		for _, g := range infosGrouped {
+			numChanges += len(g.Changes)
+		}
+		if numChanges == 0 {
+			return nil, fmt.Errorf("%s: no changes found for the release notes and fail_if_empty is set, check the commit range", commandName)
+		}
+	}
+
+	scopedTitles := make(map[string]bool)
+	for _, g := range changeGroups {
+		if g.GroupByScope {
+			scopedTitles[g.Title] = true
+		}
+	}
+	for i, g := range infosGrouped {
+		if scopedTitles[g.Title] {
+			infosGrouped[i].Scopes = changelog.GroupByScope(g.Changes)
+		}
+	}
+
+	return infosGrouped, nil
+}
+
+// renderReleaseNotes renders the release notes template with the grouped changes to w.
+func (b *Releaser) renderReleaseNotes(rctx releaseContext, w io.Writer, infosGrouped []changelog.TitleChanges) error {
 	type ReleaseNotesContext struct {
+		corecmd.TemplateContext
 		ChangeGroups []changelog.TitleChanges
+
+		// The commitish the changes are collected up to, the tag if not set.
+		Commitish string
+
+		// The short Git SHA of Commitish, empty if it does not exist (yet).
+		GitSHA string
+
+		// The time in SOURCE_DATE_EPOCH if set, else the current time, in UTC.
+		Date time.Time
+
+		// The sorted and deduplicated usernames of the authors of the changes.
+		// Only set if contributors is enabled in the release notes settings.
+		Contributors []string
+
+		// The name of the uploaded public key to verify the signatures with.
+		// Only set if signing_settings.public_key_filename is set.
+		PublicKeyFilename string
+	}
+
+	commitish := b.commitish
+	if commitish == "" {
+		commitish = b.core.Tag
+	}
+	gitSHA, err := changelog.ShortSHA(os.Getenv("HUGORELEASER_CHANGELOG_GITREPO"), commitish)
+	if err != nil {
+		return fmt.Errorf("%s: failed to resolve %q: %v", commandName, commitish, err)
+	}
+
+	date := time.Now().UTC()
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		sec, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: invalid SOURCE_DATE_EPOCH %q: %v", commandName, epoch, err)
+		}
+		date = time.Unix(sec, 0).UTC()
 	}
 
 	rnc := ReleaseNotesContext{
-		ChangeGroups: infosGrouped,
+		TemplateContext: b.core.NewTemplateContext("", ""),
+		ChangeGroups:    infosGrouped,
+		Commitish:       commitish,
+		GitSHA:          gitSHA,
+		Date:            date,
+	}
+	if rctx.Info.Settings.ReleaseNotesSettings.Contributors {
+		rnc.Contributors = changelog.Usernames(infosGrouped)
+	}
+	if rctx.Info.Settings.Prerelease {
+		rnc.IsPrerelease = true
+	}
+	if signing := rctx.Info.Settings.SigningSettings; signing.Enabled() {
+		rnc.PublicKeyFilename = signing.PublicKeyFilename
 	}
 
-	releaseNotesFilename := filepath.Join(rctx.ReleaseDir, "release-notes.md")
-	rctx.Info.Settings.ReleaseNotesSettings.Filename = releaseNotesFilename
-	err = func() error {
-		f, err := os.Create(releaseNotesFilename)
+	if sections := rctx.Info.Settings.ReleaseNotesSettings.Sections; len(sections) > 0 {
+		return b.writeReleaseNotesSections(w, sections, rnc)
+	}
+
+	t := staticfiles.ReleaseNotesTemplate
+
+	if customTemplateFilename := rctx.Info.Settings.ReleaseNotesSettings.TemplateFilename; customTemplateFilename != "" {
+		filename := customTemplateFilename
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(b.core.ProjectDir, filename)
+		}
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("%s: failed to read release notes template: %v", commandName, err)
+		}
+		// Clone the default template so the custom template can use its templates.
+		t, err = staticfiles.ReleaseNotesTemplate.Clone()
 		if err != nil {
 			return err
 		}
-		defer f.Close()
+		t, err = t.New(customTemplateFilename).Parse(string(b))
+		if err != nil {
+			return fmt.Errorf("%s: failed to parse release notes template %q: %v", commandName, customTemplateFilename, err)
+		}
+		if err := t.Execute(w, rnc); err != nil {
+			return fmt.Errorf("%s: failed to render release notes template %q: %v", commandName, customTemplateFilename, err)
+		}
+		return nil
+	}
 
-		var t *template.Template
+	return t.Execute(w, rnc)
+}
 
-		if customTemplateFilename := rctx.Info.Settings.ReleaseNotesSettings.TemplateFilename; customTemplateFilename != "" {
-			if !filepath.IsAbs(customTemplateFilename) {
-				customTemplateFilename = filepath.Join(b.core.ProjectDir, customTemplateFilename)
-			}
-			b, err := os.ReadFile(customTemplateFilename)
-			if err != nil {
-				return err
+// writeReleaseNotesSections renders the release notes sections in order and writes
+// the non-blank ones to w, separated by a blank line.
+func (b *Releaser) writeReleaseNotesSections(w io.Writer, sections []config.ReleaseNotesSection, data any) error {
+	var parts []string
+	for i, section := range sections {
+		text := section.Template
+		name := fmt.Sprintf("section %d", i+1)
+		if section.Filename != "" {
+			filename := section.Filename
+			if !filepath.IsAbs(filename) {
+				filename = filepath.Join(b.core.ProjectDir, filename)
 			}
-			t, err = templ.Parse(string(b))
+			b, err := os.ReadFile(filename)
 			if err != nil {
 				return err
 			}
-		} else {
-			t = staticfiles.ReleaseNotesTemplate
-
+			text = string(b)
+			name = section.Filename
 		}
 
-		if err := t.Execute(f, rnc); err != nil {
+		// Clone the default template so the sections can use its templates.
+		t, err := staticfiles.ReleaseNotesTemplate.Clone()
+		if err != nil {
 			return err
 		}
+		t, err = t.New(name).Parse(text)
+		if err != nil {
+			return fmt.Errorf("%s: failed to parse release notes %s: %v", commandName, name, err)
+		}
 
-		return nil
-	}()
+		var buf strings.Builder
+		if err := t.Execute(&buf, data); err != nil {
+			return fmt.Errorf("%s: failed to render release notes %s: %v", commandName, name, err)
+		}
+		if part := strings.TrimSpace(buf.String()); part != "" {
+			parts = append(parts, part)
+		}
+	}
 
+	_, err := io.WriteString(w, strings.Join(parts, "\n\n")+"\n")
+	return err
+}
+
+// archivesDir returns the dir the archive command writes the archives to.
+// Only checksum files below it are trusted when creating checksums.
+func (b *Releaser) archivesDir() string {
+	return filepath.Join(
+		b.core.DistDir,
+		b.core.Config.Project,
+		b.core.Tag,
+		b.core.DistRootArchives,
+	)
+}
+
+// generatePerFileChecksums writes a checksum file per algorithm next to each of archiveFilenames in the release dir,
+// e.g. hugo_1.2.0_linux-amd64.tar.gz.sha256, and returns the filenames.
+func (b *Releaser) generatePerFileChecksums(rctx releaseContext, archiveFilenames ...string) ([]string, error) {
+	settings := rctx.Info.Settings
+
+	checksums, err := releases.CreateChecksums(b.core.Workforce, b.archivesDir(), settings.ChecksumAlgorithmsParsed, archiveFilenames...)
 	if err != nil {
-		return "", fmt.Errorf("%s: failed to create release notes file %q: %s", commandName, releaseNotesFilename, err)
+		return nil, err
 	}
 
-	rctx.Log.WithField("filename", releaseNotesFilename).Log(logg.String("Created release notes"))
+	var checksumFilenames []string
+	for _, archiveFilename := range archiveFilenames {
+		name := filepath.Base(archiveFilename)
+		for i, checksum := range checksums[archiveFilename] {
+			algorithm := settings.ChecksumAlgorithmsParsed[i].String()
+			line, err := releases.FormatChecksumLine(settings.ChecksumLineTemplateCompiled, releases.ChecksumLine{Hash: checksum, Name: name, Algorithm: algorithm})
+			if err != nil {
+				return nil, err
+			}
+			checksumFilename := filepath.Join(rctx.ReleaseDir, name+"."+algorithm)
+			if err := os.WriteFile(checksumFilename, []byte(line+"\n"), 0o644); err != nil {
+				return nil, fmt.Errorf("%s: failed to create checksum file %q: %s", commandName, checksumFilename, err)
+			}
+			checksumFilenames = append(checksumFilenames, checksumFilename)
+		}
+	}
 
-	return releaseNotesFilename, nil
+	rctx.Log.WithField("count", strconv.Itoa(len(checksumFilenames))).Log(logg.String("Created checksum files"))
+
+	return checksumFilenames, nil
 }
 
-func (b *Releaser) generateChecksumTxt(rctx releaseContext, archiveFilenames ...string) (string, error) {
+// checksumTxtNames returns the names of the checksums files, the main checksums file first,
+// followed by one per additional algorithm, e.g. hugo_1.2.0_checksums-sha512.txt, used with separate_checksum_files.
+// The main name is checksum_filename if set, else <project>_<version>_checksums.txt.
+func (b *Releaser) checksumTxtNames(rctx releaseContext) ([]string, error) {
+	settings := rctx.Info.Settings
+	tctx := b.core.NewTemplateContext("", "")
+
+	// This is what Hugo got out of the box from Goreleaser.
+	name := fmt.Sprintf("%s_%s_checksums.txt", rctx.Info.Project, strings.TrimPrefix(rctx.Info.Tag, "v"))
+	// The asset name prefix and suffix are only applied to the default name,
+	// an explicit checksum_filename (e.g. SHA256SUMS) is used as is.
+	applyAssetName := settings.ChecksumFilename == ""
+	if settings.ChecksumFilename != "" {
+		var err error
+		name, err = templ.Sprintt(settings.ChecksumFilename, tctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to render checksum_filename: %w", commandName, err)
+		}
+		if err := ioh.ValidateFilename(name); err != nil {
+			return nil, fmt.Errorf("%s: checksum_filename %q: %w", commandName, name, err)
+		}
+	}
+	// Only split off a known extension, not e.g. the ".0" in "checksums-v1.2.0".
+	var ext string
+	if e := path.Ext(name); e == ".txt" {
+		ext = e
+	}
+	baseName := strings.TrimSuffix(name, ext)
+
+	var names []string
+	for i, algorithm := range settings.ChecksumAlgorithmsParsed {
+		n := baseName
+		if i > 0 {
+			n += "-" + algorithm.String()
+		}
+		if applyAssetName {
+			var err error
+			n, err = b.core.AssetName(n, tctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+		names = append(names, n+ext)
+	}
+
+	return names, nil
+}
+
+// generateChecksumTxt writes the checksums file(s) and returns the filenames, the main checksums file first.
+func (b *Releaser) generateChecksumTxt(rctx releaseContext, archiveFilenames ...string) ([]string, error) {
+	settings := rctx.Info.Settings
+
 	// Create a checksums.txt file.
-	checksumLines, err := releases.CreateChecksumLines(b.core.Workforce, archiveFilenames...)
+	checksumLines, err := releases.CreateChecksumLines(b.core.Workforce, settings.ChecksumLineTemplateCompiled, b.archivesDir(), settings.ChecksumAlgorithmsParsed, archiveFilenames...)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	names, err := b.checksumTxtNames(rctx)
+	if err != nil {
+		return nil, err
 	}
-	// This is what Hugo got out of the box from Goreleaser. No settings for now.
-	name := fmt.Sprintf("%s_%s_checksums.txt", rctx.Info.Project, strings.TrimPrefix(rctx.Info.Tag, "v"))
 
-	checksumFilename := filepath.Join(rctx.ReleaseDir, name)
-	err = func() error {
-		f, err := os.Create(checksumFilename)
-		if err != nil {
-			return err
+	type checksumFile struct {
+		name  string
+		lines []string
+	}
+	files := []checksumFile{{name: names[0]}}
+	for i, lines := range checksumLines {
+		if i > 0 {
+			if settings.SeparateChecksumFiles {
+				files = append(files, checksumFile{name: names[i]})
+			} else {
+				// Separate the algorithm groups with an empty line.
+				files[0].lines = append(files[0].lines, "")
+			}
 		}
-		defer f.Close()
+		last := &files[len(files)-1]
+		last.lines = append(last.lines, lines...)
+	}
 
-		for _, line := range checksumLines {
-			_, err := f.WriteString(line + "\n")
+	var checksumFilenames []string
+	for _, file := range files {
+		checksumFilename := filepath.Join(rctx.ReleaseDir, file.name)
+		err = func() error {
+			f, err := os.Create(checksumFilename)
 			if err != nil {
 				return err
 			}
+			defer f.Close()
+
+			for _, line := range file.lines {
+				_, err := f.WriteString(line + "\n")
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}()
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to create checksum file %q: %s", commandName, checksumFilename, err)
 		}
 
-		return nil
-	}()
+		rctx.Log.WithField("filename", checksumFilename).Log(logg.String("Created checksum file"))
 
-	if err != nil {
-		return "", fmt.Errorf("%s: failed to create checksum file %q: %s", commandName, checksumFilename, err)
+		checksumFilenames = append(checksumFilenames, checksumFilename)
 	}
 
-	rctx.Log.WithField("filename", checksumFilename).Log(logg.String("Created checksum file"))
-
-	return checksumFilename, nil
+	return checksumFilenames, nil
 }
@@ -0,0 +1,155 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package versioncmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gohugoio/hugoreleaser/cmd/corecmd"
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+const commandName = "version"
+
+// These can be set at build time, e.g.:
+//
+//	-ldflags "-X github.com/gohugoio/hugoreleaser/cmd/versioncmd.version=v0.1.0 -X github.com/gohugoio/hugoreleaser/cmd/versioncmd.commit=abc123"
+//
+// If not set, we fall back to the information embedded by the Go toolchain.
+var (
+	version string
+	commit  string
+)
+
+// New returns a usable ffcli.Command for the version subcommand.
+func New(core *corecmd.Core) *ffcli.Command {
+	fs := flag.NewFlagSet(corecmd.CommandName+" "+commandName, flag.ExitOnError)
+
+	v := &versioner{
+		core: core,
+	}
+
+	fs.StringVar(&v.format, "format", "text", "The output format, one of text or json.")
+
+	core.RegisterFlags(fs)
+
+	return &ffcli.Command{
+		Name:       commandName,
+		ShortUsage: corecmd.CommandName + " " + commandName + " [flags]",
+		ShortHelp:  "Print the Hugoreleaser version and the Go version of the configured toolchain.",
+		FlagSet:    fs,
+		Exec:       v.Exec,
+	}
+}
+
+// Info holds the version information printed by the version command.
+type Info struct {
+	// The Hugoreleaser version and VCS revision.
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+
+	// The Go version used to build Hugoreleaser.
+	BuildGoVersion string `json:"build_go_version"`
+
+	// The configured Go executable and its reported version.
+	GoExe     string `json:"go_exe"`
+	GoVersion string `json:"go_version"`
+}
+
+type versioner struct {
+	core *corecmd.Core
+
+	format string
+}
+
+func (v *versioner) Exec(ctx context.Context, args []string) error {
+	if v.format != "text" && v.format != "json" {
+		return fmt.Errorf("%s: invalid -format %q, must be one of text or json", commandName, v.format)
+	}
+
+	info := GetInfo()
+
+	// Use the Go toolchain configured in the config file, if any.
+	info.GoExe = "go"
+	if cfg, err := v.core.LoadConfig(); err == nil {
+		info.GoExe = cfg.GoSettings.GoExe
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, info.GoExe, "version")
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: failed to run %q: %w", commandName, info.GoExe+" version", err)
+	}
+	info.GoVersion = strings.TrimSpace(stdout.String())
+
+	if v.format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+
+	fmt.Printf("%s %s (%s) built with %s\n", corecmd.CommandName, info.Version, info.Commit, info.BuildGoVersion)
+	fmt.Printf("%s: %s\n", info.GoExe, info.GoVersion)
+
+	return nil
+}
+
+// GetInfo returns the version information for the running binary.
+// Note that the Go toolchain fields are not set.
+func GetInfo() Info {
+	info := Info{
+		Version:        version,
+		Commit:         commit,
+		BuildGoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" {
+			info.Version = bi.Main.Version
+		}
+		if info.Commit == "" {
+			for _, s := range bi.Settings {
+				if s.Key == "vcs.revision" {
+					info.Commit = s.Value
+					break
+				}
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "(devel)"
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+
+	return info
+}
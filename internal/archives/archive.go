@@ -15,32 +15,88 @@
 package archives
 
 import (
+	"compress/gzip"
 	"fmt"
 	"io"
 
 	"github.com/gohugoio/hugoreleaser/internal/archives/archiveformats"
 	"github.com/gohugoio/hugoreleaser/internal/archives/renamer"
 	"github.com/gohugoio/hugoreleaser/internal/archives/targz"
+	"github.com/gohugoio/hugoreleaser/internal/archives/tarh"
+	"github.com/gohugoio/hugoreleaser/internal/archives/tarxz"
+	"github.com/gohugoio/hugoreleaser/internal/archives/tarzst"
 	"github.com/gohugoio/hugoreleaser/internal/archives/zip"
 	"github.com/gohugoio/hugoreleaser/internal/common/ioh"
 	"github.com/gohugoio/hugoreleaser/internal/config"
 )
 
-func New(settings config.ArchiveSettings, out io.WriteCloser) (Archiver, error) {
+// defaultSmallArchiveThreshold is the default total uncompressed size below which
+// an archive is compressed with a faster gzip level.
+// For small archives, the setup cost of the best compression dominates,
+// see BenchmarkNewTarGzSmall.
+const defaultSmallArchiveThreshold = 1 << 20
+
+// New creates a new Archiver for the configured archive type.
+// size is the total uncompressed size of the files to be added, -1 if unknown.
+func New(settings config.ArchiveSettings, size int64, out io.WriteCloser) (Archiver, error) {
+	headerOpts := tarh.HeaderOptions{
+		Reproducible: settings.Reproducible,
+		Uname:        settings.Owner,
+		Gname:        settings.Group,
+		Umask:        settings.Umask,
+	}
+
 	switch settings.Type.FormatParsed {
 	case archiveformats.TarGz:
-		return targz.New(out), nil
+		return targz.NewWithOptions(out, targz.Options{
+			Level:         compressionLevel(settings, size),
+			HeaderOptions: headerOpts,
+		})
+	case archiveformats.TarXz:
+		return tarxz.New(out, tarxz.Options{
+			HeaderOptions: headerOpts,
+		})
+	case archiveformats.TarZst:
+		return tarzst.New(out, tarzst.Options{
+			Level:         zstdLevel(settings),
+			HeaderOptions: headerOpts,
+		})
 	case archiveformats.Zip:
 		return zip.New(out), nil
-	case archiveformats.Rename:
+	case archiveformats.Rename, archiveformats.Binary:
 		return renamer.New(out), nil
 	default:
 		return nil, fmt.Errorf("unsupported archive format %q", settings.Type.Format)
 	}
 }
 
+// compressionLevel returns the gzip compression level to use for an archive of the given size.
+func compressionLevel(settings config.ArchiveSettings, size int64) int {
+	if settings.CompressionLevel != nil {
+		// 0 is gzip.NoCompression.
+		return *settings.CompressionLevel
+	}
+	threshold := settings.SmallArchiveThreshold
+	if threshold == 0 {
+		threshold = defaultSmallArchiveThreshold
+	}
+	if size >= 0 && size < threshold {
+		return gzip.DefaultCompression
+	}
+	return gzip.BestCompression
+}
+
+// zstdLevel returns the zstd compression level (1-22) to use, 0 for the default level.
+func zstdLevel(settings config.ArchiveSettings) int {
+	if settings.CompressionLevel == nil {
+		return 0
+	}
+	return *settings.CompressionLevel
+}
+
 type Archiver interface {
 	// AddAndClose adds a file to the archive, then closes it.
+	// The target path is used as is, see NormalizeTargetPath.
 	AddAndClose(dir string, f ioh.File) error
 
 	// Finalize finalizes the archive and closes all writers in use.
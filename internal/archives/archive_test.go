@@ -14,8 +14,75 @@
 
 package archives
 
-import "testing"
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math/rand"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser/internal/archives/archiveformats"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+)
 
 func TestArvhiceTarGz(t *testing.T) {
 	// TODO
 }
+
+func TestCompressionLevel(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(compressionLevel(config.ArchiveSettings{}, 100), qt.Equals, gzip.DefaultCompression)
+	c.Assert(compressionLevel(config.ArchiveSettings{}, 10<<20), qt.Equals, gzip.BestCompression)
+	c.Assert(compressionLevel(config.ArchiveSettings{}, -1), qt.Equals, gzip.BestCompression)
+	c.Assert(compressionLevel(config.ArchiveSettings{SmallArchiveThreshold: -1}, 100), qt.Equals, gzip.BestCompression)
+	c.Assert(compressionLevel(config.ArchiveSettings{SmallArchiveThreshold: 10 << 20}, 5<<20), qt.Equals, gzip.DefaultCompression)
+	one, zero := 1, 0
+	c.Assert(compressionLevel(config.ArchiveSettings{CompressionLevel: &one}, 10<<20), qt.Equals, gzip.BestSpeed)
+	c.Assert(compressionLevel(config.ArchiveSettings{CompressionLevel: &zero}, 10<<20), qt.Equals, gzip.NoCompression)
+}
+
+// BenchmarkNewTarGzSmall simulates a build matrix with many small single-binary archives.
+func BenchmarkNewTarGzSmall(b *testing.B) {
+	content := make([]byte, 256<<10)
+	r := rand.New(rand.NewSource(32))
+	for i := range content {
+		// Something that compresses, like a binary.
+		content[i] = byte('a' + r.Intn(16))
+	}
+	fi := memFileInfo{name: "hugo", size: int64(len(content)), mode: 0o755}
+
+	for _, test := range []struct {
+		name     string
+		settings config.ArchiveSettings
+	}{
+		{"best", config.ArchiveSettings{SmallArchiveThreshold: -1}},
+		{"auto", config.ArchiveSettings{}},
+	} {
+		settings := test.settings
+		settings.Type = config.ArchiveType{Format: "tar.gz", Extension: ".tar.gz", FormatParsed: archiveformats.TarGz}
+		b.Run(test.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				archive, err := New(settings, fi.size, nopWriteCloser{io.Discard})
+				if err != nil {
+					b.Fatal(err)
+				}
+				f := &memFile{Reader: bytes.NewReader(content), name: "hugo", fi: fi}
+				if err := archive.AddAndClose("hugo", f); err != nil {
+					b.Fatal(err)
+				}
+				if err := archive.Finalize(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -22,23 +22,29 @@ import (
 )
 
 // Goreleaser supports `tar.gz`, `tar.xz`, `tar`, `gz`, `zip` and `binary`.
-// We currently limit ourselves to what Hugo uses: `tar.gz` and 'zip' (for Windows).
+// We support `tar.gz`, `tar.xz`, `tar.zst` and 'zip' (for Windows).
 const (
 	InvalidFormat Format = iota
 	Deb
 	TarGz
+	TarXz
+	TarZst
 	Zip
 	Rename
 	Plugin // Plugin is a special format that is used to indicate that the archive operation is handled by an external tool.
+	Binary // Binary publishes the built binary as is, without wrapping it in an archive.
 )
 
 var formatString = map[Format]string{
 	// The string values is what users can specify in the config.
 	Deb:    "deb",
 	TarGz:  "tar.gz",
+	TarXz:  "tar.xz",
+	TarZst: "tar.zst",
 	Zip:    "zip",
 	Rename: "rename",
 	Plugin: "_plugin",
+	Binary: "binary",
 }
 
 var stringFormat = map[string]Format{}
@@ -73,3 +79,14 @@ type Format int
 func (f Format) String() string {
 	return formatString[f]
 }
+
+// DefaultExtension returns the file extension to use when none is configured,
+// empty if the format has no default.
+func (f Format) DefaultExtension() string {
+	switch f {
+	case TarGz, TarXz, TarZst, Zip:
+		return "." + f.String()
+	default:
+		return ""
+	}
+}
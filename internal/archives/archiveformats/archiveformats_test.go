@@ -12,6 +12,12 @@ func TestFormat(t *testing.T) {
 	c.Assert(MustParse("tar.gz"), qt.Equals, TarGz)
 	c.Assert(MustParse("tar.gz").String(), qt.Equals, "tar.gz")
 	c.Assert(MustParse("ZiP").String(), qt.Equals, "zip")
+	c.Assert(MustParse("tar.xz"), qt.Equals, TarXz)
+	c.Assert(MustParse("tar.zst"), qt.Equals, TarZst)
+	c.Assert(TarZst.DefaultExtension(), qt.Equals, ".tar.zst")
+	c.Assert(Rename.DefaultExtension(), qt.Equals, "")
+	c.Assert(MustParse("binary"), qt.Equals, Binary)
+	c.Assert(Binary.DefaultExtension(), qt.Equals, "")
 
 	_, err := Parse("invalid")
 	c.Assert(err, qt.ErrorMatches, "invalid archive format \"invalid\", must be one of .*")
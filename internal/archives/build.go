@@ -15,27 +15,80 @@
 package archives
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/bep/logg"
 	"github.com/gohugoio/hugoreleaser-plugins-api/archiveplugin"
 	"github.com/gohugoio/hugoreleaser/cmd/corecmd"
 	"github.com/gohugoio/hugoreleaser/internal/archives/archiveformats"
+	"github.com/gohugoio/hugoreleaser/internal/common/ioh"
 	"github.com/gohugoio/hugoreleaser/internal/config"
+	"github.com/gohugoio/hugoreleaser/internal/releases"
 )
 
 // Build builds an archive from the given settings and writes it to req.OutFilename
-func Build(c *corecmd.Core, infoLogger logg.LevelLogger, settings config.ArchiveSettings, req archiveplugin.Request) (err error) {
+// Files will be opened using files, which may be nil.
+// If modTimes is set, it will be used to set the modification time of the archive entries.
+// Else, if settings.Reproducible is set, all entries get the time in SOURCE_DATE_EPOCH, or the Unix epoch if not set.
+// If settings.Reproducible is set, the entries are also sorted by target path, unless settings.PreserveOrder is set.
+// For the binary format, the binary is written as is.
+// If settings.ChecksumsFile is set, a SHA256SUMS file with the checksums of the regular files is added, see addChecksumsFile.
+// For the built-in formats, the SHA256 checksum is written to a file next to the archive, see releases.WriteChecksumFile.
+// Cancelling ctx stops the copying of the files, and a partially written archive is removed on any error.
+// The target paths are normalized with NormalizeTargetPath for all formats, including plugins.
+func Build(ctx context.Context, c *corecmd.Core, infoLogger logg.LevelLogger, settings config.ArchiveSettings, req archiveplugin.Request, files *FileCache, modTimes *GitModTimes) (err error) {
+	if !c.Try {
+		// Any precomputed checksum is stale from now on.
+		if err := os.Remove(releases.ChecksumFilename(req.OutFilename)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// Don't modify the caller's slice.
+	req.Files = append([]archiveplugin.ArchiveFile(nil), req.Files...)
+	for i, file := range req.Files {
+		req.Files[i].TargetPath = NormalizeTargetPath(file.TargetPath)
+	}
+
+	if settings.Type.FormatParsed == archiveformats.Binary && len(req.Files) != 1 {
+		// Extra files are rejected in the config.
+		return fmt.Errorf("the binary format expects 1 file, got %d", len(req.Files))
+	}
+
+	if settings.ChecksumsFile && !c.Try {
+		if files == nil {
+			// Only used for the in-memory SHA256SUMS file.
+			files = NewFileCache(0)
+		}
+		if req.Files, err = addChecksumsFile(settings, req, files); err != nil {
+			return err
+		}
+	}
+
+	if settings.Reproducible && !settings.PreserveOrder {
+		sort.SliceStable(req.Files, func(i, j int) bool {
+			return req.Files[i].TargetPath < req.Files[j].TargetPath
+		})
+	}
+
 	if settings.Type.FormatParsed == archiveformats.Plugin {
 		// Delegate to external tool.
 		return buildExternal(c, infoLogger, settings, req)
 	}
 
 	if c.Try {
-		archive, err := New(settings, struct {
+		archive, err := New(settings, -1, struct {
 			io.Writer
 			io.Closer
 		}{
@@ -48,30 +101,93 @@ func Build(c *corecmd.Core, infoLogger logg.LevelLogger, settings config.Archive
 		return archive.Finalize()
 	}
 
+	// The total size is used to select the compression setup.
+	var size int64
+	for _, file := range req.Files {
+		fi, err := files.Stat(file.SourcePathAbs)
+		if err != nil {
+			return err
+		}
+		size += fi.Size()
+	}
+
+	var pinnedModTime time.Time
+	if modTimes == nil && settings.Reproducible {
+		var found bool
+		pinnedModTime, found, err = sourceDateEpoch()
+		if err != nil {
+			return err
+		}
+		if !found {
+			pinnedModTime = time.Unix(0, 0)
+		}
+	}
+
 	outFile, err := os.Create(req.OutFilename)
 	if err != nil {
 		return err
 	}
+	if settings.Type.FormatParsed == archiveformats.Binary {
+		if err := outFile.Chmod(0o755); err != nil {
+			outFile.Close()
+			os.Remove(req.OutFilename)
+			return err
+		}
+	}
 
-	archiver, err := New(settings, outFile)
+	// Compute the checksum while writing the archive to save a read in the release step.
+	hasher := sha256.New()
+	archiver, err := New(settings, size, struct {
+		io.Writer
+		io.Closer
+	}{
+		io.MultiWriter(outFile, hasher),
+		outFile,
+	})
 	if err != nil {
+		outFile.Close()
+		os.Remove(req.OutFilename)
 		return err
 	}
 	defer func() {
-		err = archiver.Finalize()
+		if ferr := archiver.Finalize(); err == nil {
+			err = ferr
+		}
+		if err == nil {
+			err = releases.WriteChecksumFile(req.OutFilename, hex.EncodeToString(hasher.Sum(nil)))
+		}
+		if err != nil {
+			// Don't leave a corrupt archive behind.
+			os.Remove(req.OutFilename)
+		}
 	}()
 
 	for _, file := range req.Files {
-		if file.Mode != 0 {
-			if err := os.Chmod(file.SourcePathAbs, file.Mode); err != nil {
-				return err
-			}
+		if err := ctx.Err(); err != nil {
+			return err
 		}
 
-		f, err := os.Open(file.SourcePathAbs)
+		f, err := files.Open(file.SourcePathAbs)
 		if err != nil {
 			return err
 		}
+		f = withContext(ctx, f)
+
+		if file.Mode != 0 {
+			// Set the mode in the archive only, leaving the source file as is.
+			f = withMode(f, file.Mode)
+		}
+
+		if modTimes != nil {
+			modTime, err := modTimes.ModTime(file.SourcePathAbs)
+			if err != nil {
+				f.Close()
+				return err
+			}
+			f = withModTime(f, modTime)
+		} else if !pinnedModTime.IsZero() {
+			f = withModTime(f, pinnedModTime)
+		}
 
 		err = archiver.AddAndClose(file.TargetPath, f)
 		if err != nil {
@@ -82,6 +198,60 @@ func Build(c *corecmd.Core, infoLogger logg.LevelLogger, settings config.Archive
 	return
 }
 
+// NormalizeTargetPath returns the path of an entry in an archive with forward slashes
+// regardless of the host OS, as tar and zip require, cleaned and without any leading slash.
+func NormalizeTargetPath(targetPath string) string {
+	targetPath = strings.ReplaceAll(targetPath, "\\", "/")
+	return strings.TrimPrefix(path.Clean("/"+targetPath), "/")
+}
+
+// checksumsFilename is the name of the file added to archives with checksums_file.
+const checksumsFilename = "SHA256SUMS"
+
+// addChecksumsFile adds a SHA256SUMS file with the checksums of the regular files in req to files
+// and returns a copy of req.Files with it appended.
+// With wrap_in_directory, the file is put in the top level directory and the paths are relative to it,
+// so it can be verified with sha256sum -c from where it's extracted.
+func addChecksumsFile(settings config.ArchiveSettings, req archiveplugin.Request, files *FileCache) ([]archiveplugin.ArchiveFile, error) {
+	var dir string
+	if settings.WrapInDirectory && len(req.Files) > 0 {
+		dir, _, _ = strings.Cut(req.Files[0].TargetPath, "/")
+	}
+
+	var lines []string
+	for _, file := range req.Files {
+		fi, err := files.Stat(file.SourcePathAbs)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.Mode().IsRegular() {
+			// Skip symlinks.
+			continue
+		}
+		hash, err := hashFile(files, file.SourcePathAbs)
+		if err != nil {
+			return nil, err
+		}
+		targetPath := file.TargetPath
+		if dir != "" {
+			targetPath = strings.TrimPrefix(targetPath, dir+"/")
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s\n", hash, targetPath))
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i][sha256.Size*2:] < lines[j][sha256.Size*2:]
+	})
+
+	// Keep the file in memory, keyed by a path unique to this archive.
+	sourcePathAbs := filepath.Join(req.OutFilename+".checksums", checksumsFilename)
+	files.Add(sourcePathAbs, []byte(strings.Join(lines, "")), 0o644)
+
+	return append(req.Files[:len(req.Files):len(req.Files)], archiveplugin.ArchiveFile{
+		SourcePathAbs: sourcePathAbs,
+		TargetPath:    path.Join(dir, checksumsFilename),
+	}), nil
+}
+
 func buildExternal(c *corecmd.Core, infoLogger logg.LevelLogger, settings config.ArchiveSettings, req archiveplugin.Request) error {
 	infoLogger = infoLogger.WithField("plugin", settings.Plugin.ID)
 
@@ -111,3 +281,49 @@ func buildExternal(c *corecmd.Core, infoLogger logg.LevelLogger, settings config
 
 	return nil
 }
+
+// withContext returns f with reads failing with ctx.Err() once ctx is cancelled,
+// so a large file copied into the archive in chunks stops promptly on e.g. Ctrl-C.
+func withContext(ctx context.Context, f ioh.File) ioh.File {
+	return &contextFile{File: f, ctx: ctx}
+}
+
+type contextFile struct {
+	ioh.File
+	ctx context.Context
+}
+
+func (f *contextFile) Read(p []byte) (int, error) {
+	if err := f.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return f.File.Read(p)
+}
+
+// withMode returns f with the permission bits of its mode set to mode,
+// e.g. to make a script executable in the archive regardless of the source file's mode.
+func withMode(f ioh.File, mode fs.FileMode) ioh.File {
+	return &modeFile{File: f, mode: mode}
+}
+
+type modeFile struct {
+	ioh.File
+	mode fs.FileMode
+}
+
+func (f *modeFile) Stat() (fs.FileInfo, error) {
+	fi, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return modeFileInfo{FileInfo: fi, mode: fi.Mode()&^fs.ModePerm | f.mode.Perm()}, nil
+}
+
+type modeFileInfo struct {
+	fs.FileInfo
+	mode fs.FileMode
+}
+
+func (fi modeFileInfo) Mode() fs.FileMode {
+	return fi.mode
+}
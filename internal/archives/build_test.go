@@ -0,0 +1,308 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archives
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser-plugins-api/archiveplugin"
+	"github.com/gohugoio/hugoreleaser/cmd/corecmd"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+)
+
+func TestBuildReproducible(t *testing.T) {
+	c := qt.New(t)
+
+	tempDir := t.TempDir()
+	binary := filepath.Join(tempDir, "hugo")
+	readme := filepath.Join(tempDir, "README.md")
+	c.Assert(os.WriteFile(binary, []byte("binary"), 0o755), qt.IsNil)
+	c.Assert(os.WriteFile(readme, []byte("readme"), 0o644), qt.IsNil)
+
+	// build builds an archive with the given file modification time and returns its content.
+	build := func(c *qt.C, format string, reproducible bool, modTime time.Time) []byte {
+		c.Helper()
+		for _, filename := range []string{binary, readme} {
+			c.Assert(os.Chtimes(filename, modTime, modTime), qt.IsNil)
+		}
+		settings := config.ArchiveSettings{
+			Type:         config.ArchiveType{Format: format, Extension: "." + format},
+			Reproducible: reproducible,
+		}
+		c.Assert(settings.Init(), qt.IsNil)
+		req := archiveplugin.Request{
+			Files: []archiveplugin.ArchiveFile{
+				{SourcePathAbs: binary, TargetPath: "hugo"},
+				{SourcePathAbs: readme, TargetPath: "README.md"},
+			},
+			OutFilename: filepath.Join(c.TempDir(), "out."+format),
+		}
+		c.Assert(Build(context.Background(), &corecmd.Core{}, nil, settings, req, NewFileCache(0), nil), qt.IsNil)
+		b, err := os.ReadFile(req.OutFilename)
+		c.Assert(err, qt.IsNil)
+		return b
+	}
+
+	t1 := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(48 * time.Hour)
+
+	for _, format := range []string{"tar.gz", "zip"} {
+		format := format
+		c.Run(format, func(c *qt.C) {
+			c.Setenv("SOURCE_DATE_EPOCH", "")
+			unixEpoch := build(c, format, true, t1)
+			c.Assert(bytes.Equal(unixEpoch, build(c, format, true, t2)), qt.IsTrue)
+			c.Assert(bytes.Equal(build(c, format, false, t1), build(c, format, false, t2)), qt.IsFalse)
+
+			c.Setenv("SOURCE_DATE_EPOCH", "1640995200")
+			sourceDateEpoch := build(c, format, true, t1)
+			c.Assert(bytes.Equal(sourceDateEpoch, build(c, format, true, t2)), qt.IsTrue)
+			c.Assert(bytes.Equal(sourceDateEpoch, unixEpoch), qt.IsFalse)
+
+			c.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+			settings := config.ArchiveSettings{Type: config.ArchiveType{Format: format, Extension: "." + format}, Reproducible: true}
+			c.Assert(settings.Init(), qt.IsNil)
+			req := archiveplugin.Request{
+				Files:       []archiveplugin.ArchiveFile{{SourcePathAbs: binary, TargetPath: "hugo"}},
+				OutFilename: filepath.Join(c.TempDir(), "out."+format),
+			}
+			c.Assert(Build(context.Background(), &corecmd.Core{}, nil, settings, req, NewFileCache(0), nil), qt.ErrorMatches, `invalid SOURCE_DATE_EPOCH "yesterday".*`)
+		})
+	}
+}
+
+func TestBuildMode(t *testing.T) {
+	c := qt.New(t)
+
+	tempDir := t.TempDir()
+	script := filepath.Join(tempDir, "postinstall.sh")
+	c.Assert(os.WriteFile(script, []byte("#!/bin/sh"), 0o644), qt.IsNil)
+
+	for _, format := range []string{"tar.gz", "zip"} {
+		format := format
+		c.Run(format, func(c *qt.C) {
+			settings := config.ArchiveSettings{Type: config.ArchiveType{Format: format}}
+			c.Assert(settings.Init(), qt.IsNil)
+			req := archiveplugin.Request{
+				Files: []archiveplugin.ArchiveFile{
+					{SourcePathAbs: script, TargetPath: "postinstall.sh", Mode: 0o755},
+				},
+				OutFilename: filepath.Join(c.TempDir(), "out"+settings.Type.Extension),
+			}
+			c.Assert(Build(context.Background(), &corecmd.Core{}, nil, settings, req, NewFileCache(0), nil), qt.IsNil)
+
+			var mode fs.FileMode
+			if format == "zip" {
+				zr, err := zip.OpenReader(req.OutFilename)
+				c.Assert(err, qt.IsNil)
+				defer zr.Close()
+				c.Assert(zr.File, qt.HasLen, 1)
+				mode = zr.File[0].Mode()
+			} else {
+				f, err := os.Open(req.OutFilename)
+				c.Assert(err, qt.IsNil)
+				defer f.Close()
+				gr, err := gzip.NewReader(f)
+				c.Assert(err, qt.IsNil)
+				hdr, err := tar.NewReader(gr).Next()
+				c.Assert(err, qt.IsNil)
+				mode = hdr.FileInfo().Mode()
+			}
+			c.Assert(mode, qt.Equals, fs.FileMode(0o755))
+
+			// The source file is left as is.
+			fi, err := os.Stat(script)
+			c.Assert(err, qt.IsNil)
+			c.Assert(fi.Mode().Perm(), qt.Equals, fs.FileMode(0o644))
+		})
+	}
+}
+
+func TestNormalizeTargetPath(t *testing.T) {
+	c := qt.New(t)
+
+	for _, test := range []struct {
+		in, expect string
+	}{
+		{"hugo", "hugo"},
+		{"docs/README.md", "docs/README.md"},
+		{`docs\README.md`, "docs/README.md"},
+		{`bin\..\docs\README.md`, "docs/README.md"},
+		{"./docs//README.md", "docs/README.md"},
+		{"/docs/README.md", "docs/README.md"},
+		{"../README.md", "README.md"},
+	} {
+		c.Assert(NormalizeTargetPath(test.in), qt.Equals, test.expect, qt.Commentf(test.in))
+	}
+}
+
+func TestBuildForwardSlashes(t *testing.T) {
+	c := qt.New(t)
+
+	tempDir := t.TempDir()
+	readme := filepath.Join(tempDir, "README.md")
+	c.Assert(os.WriteFile(readme, []byte("readme"), 0o644), qt.IsNil)
+
+	for _, format := range []string{"tar.gz", "zip"} {
+		format := format
+		c.Run(format, func(c *qt.C) {
+			settings := config.ArchiveSettings{Type: config.ArchiveType{Format: format}}
+			c.Assert(settings.Init(), qt.IsNil)
+			req := archiveplugin.Request{
+				Files: []archiveplugin.ArchiveFile{
+					// As created from a Windows path.
+					{SourcePathAbs: readme, TargetPath: `docs\README.md`},
+				},
+				OutFilename: filepath.Join(c.TempDir(), "out"+settings.Type.Extension),
+			}
+			c.Assert(Build(context.Background(), &corecmd.Core{}, nil, settings, req, NewFileCache(0), nil), qt.IsNil)
+			// The request is left as is.
+			c.Assert(req.Files[0].TargetPath, qt.Equals, `docs\README.md`)
+
+			var name string
+			if format == "zip" {
+				zr, err := zip.OpenReader(req.OutFilename)
+				c.Assert(err, qt.IsNil)
+				defer zr.Close()
+				c.Assert(zr.File, qt.HasLen, 1)
+				name = zr.File[0].Name
+			} else {
+				f, err := os.Open(req.OutFilename)
+				c.Assert(err, qt.IsNil)
+				defer f.Close()
+				gr, err := gzip.NewReader(f)
+				c.Assert(err, qt.IsNil)
+				hdr, err := tar.NewReader(gr).Next()
+				c.Assert(err, qt.IsNil)
+				name = hdr.Name
+			}
+			c.Assert(name, qt.Equals, "docs/README.md")
+		})
+	}
+}
+
+func TestBuildCancelled(t *testing.T) {
+	c := qt.New(t)
+
+	tempDir := t.TempDir()
+	binary := filepath.Join(tempDir, "hugo")
+	c.Assert(os.WriteFile(binary, []byte("binary"), 0o755), qt.IsNil)
+
+	settings := config.ArchiveSettings{Type: config.ArchiveType{Format: "tar.gz"}}
+	c.Assert(settings.Init(), qt.IsNil)
+	req := archiveplugin.Request{
+		Files: []archiveplugin.ArchiveFile{
+			{SourcePathAbs: binary, TargetPath: "hugo"},
+		},
+		OutFilename: filepath.Join(tempDir, "out.tar.gz"),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Build(ctx, &corecmd.Core{}, nil, settings, req, NewFileCache(0), nil)
+	c.Assert(err, qt.ErrorIs, context.Canceled)
+
+	// The partially written archive is removed.
+	_, err = os.Stat(req.OutFilename)
+	c.Assert(os.IsNotExist(err), qt.IsTrue)
+
+	// A file is also cancelled while it's being copied.
+	ctx, cancel = context.WithCancel(context.Background())
+	f, err := os.Open(binary)
+	c.Assert(err, qt.IsNil)
+	defer f.Close()
+	cf := withContext(ctx, f)
+	_, err = cf.Read(make([]byte, 2))
+	c.Assert(err, qt.IsNil)
+	cancel()
+	_, err = cf.Read(make([]byte, 2))
+	c.Assert(err, qt.ErrorIs, context.Canceled)
+}
+
+func TestBuildSymlink(t *testing.T) {
+	c := qt.New(t)
+
+	tempDir := t.TempDir()
+	binary := filepath.Join(tempDir, "hugo-1.2.0")
+	c.Assert(os.WriteFile(binary, []byte("binary"), 0o755), qt.IsNil)
+	link := filepath.Join(tempDir, "out.symlinks", "hugo")
+
+	files := NewFileCache(1 << 20)
+	files.AddSymlink(link, "hugo-1.2.0")
+
+	for _, format := range []string{"tar.gz", "zip"} {
+		format := format
+		c.Run(format, func(c *qt.C) {
+			settings := config.ArchiveSettings{Type: config.ArchiveType{Format: format}}
+			c.Assert(settings.Init(), qt.IsNil)
+			req := archiveplugin.Request{
+				Files: []archiveplugin.ArchiveFile{
+					{SourcePathAbs: binary, TargetPath: "hugo-1.2.0"},
+					{SourcePathAbs: link, TargetPath: "hugo"},
+				},
+				OutFilename: filepath.Join(c.TempDir(), "out"+settings.Type.Extension),
+			}
+			c.Assert(Build(context.Background(), &corecmd.Core{}, nil, settings, req, files, nil), qt.IsNil)
+
+			links := make(map[string]string)
+			if format == "zip" {
+				zr, err := zip.OpenReader(req.OutFilename)
+				c.Assert(err, qt.IsNil)
+				defer zr.Close()
+				for _, f := range zr.File {
+					if f.Mode()&fs.ModeSymlink == 0 {
+						continue
+					}
+					r, err := f.Open()
+					c.Assert(err, qt.IsNil)
+					b, err := io.ReadAll(r)
+					c.Assert(err, qt.IsNil)
+					r.Close()
+					links[f.Name] = string(b)
+				}
+			} else {
+				f, err := os.Open(req.OutFilename)
+				c.Assert(err, qt.IsNil)
+				defer f.Close()
+				gr, err := gzip.NewReader(f)
+				c.Assert(err, qt.IsNil)
+				tr := tar.NewReader(gr)
+				for {
+					hdr, err := tr.Next()
+					if err == io.EOF {
+						break
+					}
+					c.Assert(err, qt.IsNil)
+					if hdr.Typeflag == tar.TypeSymlink {
+						c.Assert(hdr.Size, qt.Equals, int64(0))
+						links[hdr.Name] = hdr.Linkname
+					}
+				}
+			}
+			c.Assert(links, qt.DeepEquals, map[string]string{"hugo": "hugo-1.2.0"})
+		})
+	}
+}
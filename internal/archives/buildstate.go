@@ -0,0 +1,161 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archives
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/gohugoio/hugoreleaser-plugins-api/archiveplugin"
+	"github.com/gohugoio/hugoreleaser-plugins-api/model"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+)
+
+// StateFilename returns the filename of the state file for the archive in outFilename.
+func StateFilename(outFilename string) string {
+	return outFilename + ".state"
+}
+
+// IsUpToDate reports whether the archive in outFilename exists and
+// was built from inputs with the given digest, see InputsDigest.
+func IsUpToDate(outFilename, digest string) bool {
+	if _, err := os.Stat(outFilename); err != nil {
+		return false
+	}
+	b, err := os.ReadFile(StateFilename(outFilename))
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(b)) == digest
+}
+
+// WriteState writes digest to the state file for the archive in outFilename.
+func WriteState(outFilename, digest string) error {
+	return os.WriteFile(StateFilename(outFilename), []byte(digest+"\n"), 0o644)
+}
+
+// RemoveState removes the state file for the archive in outFilename, if any.
+func RemoveState(outFilename string) error {
+	err := os.Remove(StateFilename(outFilename))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+type inputsState struct {
+	BuildInfo model.BuildInfo
+	Settings  inputsSettings
+	Custom    map[string]any
+	Files     []inputsFile
+}
+
+// inputsSettings holds the archive settings that affect the archive content.
+type inputsSettings struct {
+	Type                  config.ArchiveType
+	Plugin                string
+	GitTimestamps         bool
+	SourceDateEpoch       string
+	Reproducible          bool
+	PreserveOrder         bool
+	ChecksumsFile         bool
+	Owner                 string
+	Group                 string
+	Umask                 fs.FileMode
+	PreserveSymlinks      bool
+	CompressionLevel      *int
+	SmallArchiveThreshold int64
+}
+
+type inputsFile struct {
+	TargetPath string
+	Mode       fs.FileMode
+	Size       int64
+	ModTime    int64
+	// Set for files without a modification time, e.g. rendered template files.
+	Hash string
+}
+
+// InputsDigest returns a digest of the inputs to the archive build in req:
+// the relevant settings and the target paths, sizes and modification times of the files.
+// Files will be opened using files, which may be nil.
+func InputsDigest(settings config.ArchiveSettings, req archiveplugin.Request, files *FileCache) (string, error) {
+	state := inputsState{
+		BuildInfo: req.BuildInfo,
+		Settings: inputsSettings{
+			Type:                  settings.Type,
+			Plugin:                settings.Plugin.ID + " " + settings.Plugin.Command,
+			GitTimestamps:         settings.GitTimestamps,
+			Reproducible:          settings.Reproducible,
+			PreserveOrder:         settings.PreserveOrder,
+			ChecksumsFile:         settings.ChecksumsFile,
+			Owner:                 settings.Owner,
+			Group:                 settings.Group,
+			Umask:                 settings.Umask,
+			PreserveSymlinks:      settings.PreserveSymlinks,
+			CompressionLevel:      settings.CompressionLevel,
+			SmallArchiveThreshold: settings.SmallArchiveThreshold,
+		},
+		Custom: settings.CustomSettings,
+	}
+	if settings.GitTimestamps || settings.Reproducible {
+		state.Settings.SourceDateEpoch = os.Getenv("SOURCE_DATE_EPOCH")
+	}
+
+	for _, file := range req.Files {
+		fi, err := files.Stat(file.SourcePathAbs)
+		if err != nil {
+			return "", err
+		}
+		f := inputsFile{
+			TargetPath: file.TargetPath,
+			Mode:       file.Mode,
+			Size:       fi.Size(),
+			ModTime:    fi.ModTime().UnixNano(),
+		}
+		if fi.ModTime().IsZero() {
+			f.Hash, err = hashFile(files, file.SourcePathAbs)
+			if err != nil {
+				return "", err
+			}
+		}
+		state.Files = append(state.Files, f)
+	}
+
+	b, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:]), nil
+}
+
+func hashFile(files *FileCache, filename string) (string, error) {
+	f, err := files.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archives
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser-plugins-api/archiveplugin"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+)
+
+func TestInputsDigest(t *testing.T) {
+	c := qt.New(t)
+
+	tempDir := t.TempDir()
+	readme := filepath.Join(tempDir, "README.md")
+	c.Assert(os.WriteFile(readme, []byte("readme"), 0o644), qt.IsNil)
+	templ := filepath.Join(tempDir, "out.tar.gz.templates", "meta.json")
+
+	files := NewFileCache(1 << 20)
+	files.Add(templ, []byte(`{"v": 1}`), 0o644)
+
+	req := archiveplugin.Request{
+		Files: []archiveplugin.ArchiveFile{
+			{SourcePathAbs: readme, TargetPath: "README.md"},
+			{SourcePathAbs: templ, TargetPath: "meta.json"},
+		},
+	}
+	settings := config.ArchiveSettings{Type: config.ArchiveType{Format: "tar.gz", Extension: ".tar.gz"}}
+
+	digest := func(settings config.ArchiveSettings, req archiveplugin.Request, files *FileCache) string {
+		c.Helper()
+		d, err := InputsDigest(settings, req, files)
+		c.Assert(err, qt.IsNil)
+		return d
+	}
+
+	d1 := digest(settings, req, files)
+	c.Assert(digest(settings, req, files), qt.Equals, d1)
+
+	c.Run("Settings changed", func(c *qt.C) {
+		settings := settings
+		level := 1
+		settings.CompressionLevel = &level
+		c.Assert(digest(settings, req, files), qt.Not(qt.Equals), d1)
+	})
+
+	c.Run("Target path changed", func(c *qt.C) {
+		req := req
+		req.Files = []archiveplugin.ArchiveFile{req.Files[0], {SourcePathAbs: templ, TargetPath: "meta2.json"}}
+		c.Assert(digest(settings, req, files), qt.Not(qt.Equals), d1)
+	})
+
+	c.Run("Template file changed", func(c *qt.C) {
+		files := NewFileCache(1 << 20)
+		files.Add(templ, []byte(`{"v": 2}`), 0o644)
+		c.Assert(digest(settings, req, files), qt.Not(qt.Equals), d1)
+	})
+
+	c.Run("File modified", func(c *qt.C) {
+		modTime := time.Now().Add(-time.Hour)
+		c.Assert(os.Chtimes(readme, modTime, modTime), qt.IsNil)
+		c.Assert(digest(settings, req, files), qt.Not(qt.Equals), d1)
+	})
+}
+
+func TestIsUpToDate(t *testing.T) {
+	c := qt.New(t)
+
+	outFilename := filepath.Join(t.TempDir(), "out.tar.gz")
+	c.Assert(IsUpToDate(outFilename, "abc"), qt.IsFalse)
+	c.Assert(WriteState(outFilename, "abc"), qt.IsNil)
+	// The archive is missing.
+	c.Assert(IsUpToDate(outFilename, "abc"), qt.IsFalse)
+	c.Assert(os.WriteFile(outFilename, []byte("archive"), 0o644), qt.IsNil)
+	c.Assert(IsUpToDate(outFilename, "abc"), qt.IsTrue)
+	c.Assert(IsUpToDate(outFilename, "abcd"), qt.IsFalse)
+	c.Assert(RemoveState(outFilename), qt.IsNil)
+	c.Assert(RemoveState(outFilename), qt.IsNil)
+	c.Assert(IsUpToDate(outFilename, "abc"), qt.IsFalse)
+}
@@ -0,0 +1,182 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archives
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gohugoio/hugoreleaser/internal/common/ioh"
+)
+
+// FileCache caches the content of small files that are added to many archives
+// (e.g. README and LICENSE files), so they're only read from disk once per run.
+// It is safe for concurrent use.
+type FileCache struct {
+	maxSize int64
+
+	mu    sync.Mutex
+	files map[string]*cachedFile
+}
+
+// NewFileCache creates a new FileCache that caches files up to maxSize bytes.
+// Larger files are opened directly.
+func NewFileCache(maxSize int64) *FileCache {
+	return &FileCache{
+		maxSize: maxSize,
+		files:   make(map[string]*cachedFile),
+	}
+}
+
+type cachedFile struct {
+	once    sync.Once
+	fi      fs.FileInfo
+	content []byte
+	err     error
+}
+
+// Open opens filename for reading, using the cached content if possible.
+// A nil FileCache will always open the file directly.
+func (c *FileCache) Open(filename string) (ioh.File, error) {
+	if c == nil {
+		return os.Open(filename)
+	}
+
+	c.mu.Lock()
+	cf, found := c.files[filename]
+	if !found {
+		cf = &cachedFile{}
+		c.files[filename] = cf
+	}
+	c.mu.Unlock()
+
+	cf.once.Do(func() {
+		cf.fi, cf.err = os.Stat(filename)
+		if cf.err != nil || cf.fi.Size() > c.maxSize {
+			return
+		}
+		cf.content, cf.err = os.ReadFile(filename)
+	})
+
+	if cf.err != nil {
+		return nil, cf.err
+	}
+
+	if cf.content == nil {
+		// Too big for the cache.
+		return os.Open(filename)
+	}
+
+	return &memFile{
+		Reader: bytes.NewReader(cf.content),
+		name:   filename,
+		fi:     cf.fi,
+	}, nil
+}
+
+// Stat returns the FileInfo for filename, using the cache if possible.
+// A nil FileCache will always stat the file directly.
+func (c *FileCache) Stat(filename string) (fs.FileInfo, error) {
+	if c == nil {
+		return os.Stat(filename)
+	}
+	c.mu.Lock()
+	_, found := c.files[filename]
+	c.mu.Unlock()
+	if found {
+		// Make sure it's loaded.
+		f, err := c.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return f.Stat()
+	}
+	return os.Stat(filename)
+}
+
+// Add adds content to the cache as filename, which does not need to exist on disk.
+// This can be used to add generated files to archives without writing them to disk.
+func (c *FileCache) Add(filename string, content []byte, mode fs.FileMode) {
+	if content == nil {
+		content = []byte{}
+	}
+	cf := &cachedFile{
+		content: content,
+		fi: memFileInfo{
+			name: filepath.Base(filename),
+			size: int64(len(content)),
+			mode: mode,
+		},
+	}
+	// Mark it as loaded.
+	cf.once.Do(func() {})
+
+	c.mu.Lock()
+	c.files[filename] = cf
+	c.mu.Unlock()
+}
+
+// AddSymlink adds a symbolic link to linkname to the cache as filename.
+// The content of the file is the link target, which is how the archivers
+// expect symbolic links, see fs.ModeSymlink.
+func (c *FileCache) AddSymlink(filename, linkname string) {
+	c.Add(filename, []byte(linkname), fs.ModeSymlink|0o777)
+}
+
+var _ ioh.File = (*memFile)(nil)
+
+// memFile is a read-only ioh.File backed by a byte slice.
+type memFile struct {
+	*bytes.Reader
+	name string
+	fi   fs.FileInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return f.fi, nil
+}
+
+func (f *memFile) Name() string {
+	return f.name
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	return 0, errors.New("memFile is read-only")
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+var _ fs.FileInfo = memFileInfo{}
+
+type memFileInfo struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+func (fi memFileInfo) Name() string       { return fi.name }
+func (fi memFileInfo) Size() int64        { return fi.size }
+func (fi memFileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi memFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi memFileInfo) IsDir() bool        { return false }
+func (fi memFileInfo) Sys() any           { return nil }
@@ -0,0 +1,104 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archives
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestFileCache(t *testing.T) {
+	c := qt.New(t)
+
+	tempDir := t.TempDir()
+	small := filepath.Join(tempDir, "small.txt")
+	large := filepath.Join(tempDir, "large.txt")
+	c.Assert(os.WriteFile(small, []byte("small"), 0o644), qt.IsNil)
+	c.Assert(os.WriteFile(large, []byte("large file"), 0o644), qt.IsNil)
+
+	cache := NewFileCache(5)
+
+	readAll := func(filename string) string {
+		c.Helper()
+		f, err := cache.Open(filename)
+		c.Assert(err, qt.IsNil)
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		c.Assert(err, qt.IsNil)
+		return string(b)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Check(readAll(small), qt.Equals, "small")
+		}()
+	}
+	wg.Wait()
+
+	f, err := cache.Open(small)
+	c.Assert(err, qt.IsNil)
+	fi, err := f.Stat()
+	c.Assert(err, qt.IsNil)
+	c.Assert(fi.Size(), qt.Equals, int64(5))
+	c.Assert(fi.Name(), qt.Equals, "small.txt")
+
+	// Small files are read once.
+	c.Assert(os.WriteFile(small, []byte("changed"), 0o644), qt.IsNil)
+	c.Assert(readAll(small), qt.Equals, "small")
+
+	// Large files are read from disk every time.
+	c.Assert(readAll(large), qt.Equals, "large file")
+	c.Assert(os.WriteFile(large, []byte("large changed"), 0o644), qt.IsNil)
+	c.Assert(readAll(large), qt.Equals, "large changed")
+
+	_, err = cache.Open(filepath.Join(tempDir, "doesnotexist.txt"))
+	c.Assert(err, qt.Not(qt.IsNil))
+
+	var nilCache *FileCache
+	nf, err := nilCache.Open(small)
+	c.Assert(err, qt.IsNil)
+	nf.Close()
+}
+
+func TestFileCacheAdd(t *testing.T) {
+	c := qt.New(t)
+
+	cache := NewFileCache(5)
+	filename := filepath.Join(t.TempDir(), "doesnotexist", "metadata.json")
+
+	// Not limited by maxSize.
+	cache.Add(filename, []byte("in memory"), 0o600)
+
+	f, err := cache.Open(filename)
+	c.Assert(err, qt.IsNil)
+	defer f.Close()
+	fi, err := f.Stat()
+	c.Assert(err, qt.IsNil)
+	c.Assert(fi.Name(), qt.Equals, "metadata.json")
+	c.Assert(fi.Size(), qt.Equals, int64(9))
+	c.Assert(fi.Mode(), qt.Equals, fs.FileMode(0o600))
+	b, err := io.ReadAll(f)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b), qt.Equals, "in memory")
+}
@@ -0,0 +1,144 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archives
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gohugoio/hugoreleaser/internal/common/ioh"
+)
+
+// GitModTimes looks up the last commit time of files in a Git repository,
+// to be used as the modification time of the archive entries.
+// It is safe for concurrent use.
+type GitModTimes struct {
+	dir string
+
+	initOnce sync.Once
+	fallback time.Time
+	initErr  error
+
+	mu    sync.Mutex
+	cache map[string]time.Time
+}
+
+// NewGitModTimes creates a new GitModTimes for the Git repository in dir.
+func NewGitModTimes(dir string) *GitModTimes {
+	return &GitModTimes{
+		dir:   dir,
+		cache: make(map[string]time.Time),
+	}
+}
+
+// ModTime returns the time of the last commit touching filename.
+// For files not in Git (e.g. the binaries), the time in the SOURCE_DATE_EPOCH
+// environment variable is used if set, else the time of the HEAD commit.
+func (g *GitModTimes) ModTime(filename string) (time.Time, error) {
+	g.initOnce.Do(func() {
+		var found bool
+		if g.fallback, found, g.initErr = sourceDateEpoch(); found || g.initErr != nil {
+			return
+		}
+		g.fallback, found, g.initErr = g.gitLog()
+		if g.initErr == nil && !found {
+			g.initErr = fmt.Errorf("no commits found in %q", g.dir)
+		}
+	})
+	if g.initErr != nil {
+		return time.Time{}, g.initErr
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if t, found := g.cache[filename]; found {
+		return t, nil
+	}
+
+	t, found, err := g.gitLog("--", filename)
+	if err != nil || !found {
+		// Not in Git.
+		t = g.fallback
+	}
+
+	g.cache[filename] = t
+	return t, nil
+}
+
+// sourceDateEpoch returns the time in the SOURCE_DATE_EPOCH environment variable, if set.
+func sourceDateEpoch() (time.Time, bool, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Time{}, false, nil
+	}
+	sec, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %w", epoch, err)
+	}
+	return time.Unix(sec, 0), true, nil
+}
+
+func (g *GitModTimes) gitLog(args ...string) (time.Time, bool, error) {
+	args = append([]string{"log", "-1", "--format=%ct"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = g.dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("git log failed: %w: %s", err, out)
+	}
+	s := strings.TrimSpace(string(out))
+	if s == "" {
+		return time.Time{}, false, nil
+	}
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("unexpected git log output: %q", s)
+	}
+	return time.Unix(sec, 0), true, nil
+}
+
+// withModTime returns f with its modification time set to modTime.
+func withModTime(f ioh.File, modTime time.Time) ioh.File {
+	return &modTimeFile{File: f, modTime: modTime}
+}
+
+type modTimeFile struct {
+	ioh.File
+	modTime time.Time
+}
+
+func (f *modTimeFile) Stat() (fs.FileInfo, error) {
+	fi, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return modTimeFileInfo{FileInfo: fi, modTime: f.modTime}, nil
+}
+
+type modTimeFileInfo struct {
+	fs.FileInfo
+	modTime time.Time
+}
+
+func (fi modTimeFileInfo) ModTime() time.Time {
+	return fi.modTime
+}
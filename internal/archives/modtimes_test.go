@@ -0,0 +1,107 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archives
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser/internal/archives/targz"
+)
+
+func TestGitModTimes(t *testing.T) {
+	c := qt.New(t)
+
+	if _, err := exec.LookPath("git"); err != nil {
+		c.Skip("git not found")
+	}
+
+	dir := t.TempDir()
+	git := func(date string, args ...string) {
+		c.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=hugoreleaser", "GIT_AUTHOR_EMAIL=hugoreleaser@example.com", "GIT_AUTHOR_DATE="+date,
+			"GIT_COMMITTER_NAME=hugoreleaser", "GIT_COMMITTER_EMAIL=hugoreleaser@example.com", "GIT_COMMITTER_DATE="+date,
+		)
+		out, err := cmd.CombinedOutput()
+		c.Assert(err, qt.IsNil, qt.Commentf("%s", out))
+	}
+	writeFile := func(name string) string {
+		filename := filepath.Join(dir, name)
+		c.Assert(os.WriteFile(filename, []byte(name), 0o644), qt.IsNil)
+		return filename
+	}
+
+	readme := writeFile("README.md")
+	git("2022-01-01T00:00:00Z", "init", "-q")
+	git("2022-01-01T00:00:00Z", "add", "README.md")
+	git("2022-01-01T00:00:00Z", "commit", "-q", "-m", "first")
+	license := writeFile("LICENSE")
+	git("2023-01-01T00:00:00Z", "add", "LICENSE")
+	git("2023-01-01T00:00:00Z", "commit", "-q", "-m", "second")
+	binary := writeFile("hugo")
+
+	first := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	modTime := func(g *GitModTimes, filename string) time.Time {
+		c.Helper()
+		t, err := g.ModTime(filename)
+		c.Assert(err, qt.IsNil)
+		return t.UTC()
+	}
+
+	g := NewGitModTimes(dir)
+	c.Assert(modTime(g, readme), qt.Equals, first)
+	c.Assert(modTime(g, license), qt.Equals, second)
+	// Not in Git, falls back to HEAD.
+	c.Assert(modTime(g, binary), qt.Equals, second)
+
+	c.Setenv("SOURCE_DATE_EPOCH", "1577836800")
+	g = NewGitModTimes(dir)
+	c.Assert(modTime(g, readme), qt.Equals, first)
+	c.Assert(modTime(g, binary), qt.Equals, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	c.Setenv("SOURCE_DATE_EPOCH", "")
+	_, err := NewGitModTimes(t.TempDir()).ModTime(readme)
+	c.Assert(err, qt.ErrorMatches, "(?s)git log failed.*")
+
+	// The modification time is used in the tar header.
+	var buf bytes.Buffer
+	archive := targz.New(struct {
+		io.Writer
+		io.Closer
+	}{&buf, io.NopCloser(nil)})
+	f, err := os.Open(binary)
+	c.Assert(err, qt.IsNil)
+	c.Assert(archive.AddAndClose("hugo", withModTime(f, first)), qt.IsNil)
+	c.Assert(archive.Finalize(), qt.IsNil)
+
+	gr, err := gzip.NewReader(&buf)
+	c.Assert(err, qt.IsNil)
+	hdr, err := tar.NewReader(gr).Next()
+	c.Assert(err, qt.IsNil)
+	c.Assert(hdr.ModTime.UTC(), qt.Equals, first)
+}
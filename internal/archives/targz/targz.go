@@ -15,58 +15,73 @@
 package targz
 
 import (
-	"archive/tar"
 	"compress/gzip"
+	"fmt"
 	"io"
+	"sync"
 
+	"github.com/gohugoio/hugoreleaser/internal/archives/tarh"
 	"github.com/gohugoio/hugoreleaser/internal/common/ioh"
 )
 
-func New(out io.WriteCloser) *Archive {
-	archive := &Archive{
-		out: out,
-	}
-
-	gw, _ := gzip.NewWriterLevel(out, gzip.BestCompression)
-	tw := tar.NewWriter(gw)
+// Options configures a tar.gz archive.
+type Options struct {
+	// The gzip compression level, see compress/gzip.
+	Level int
 
-	archive.gw = gw
-	archive.tw = tw
+	tarh.HeaderOptions
+}
 
+// New creates a new tar.gz archive using the best gzip compression.
+func New(out io.WriteCloser) *Archive {
+	archive, _ := NewWithOptions(out, Options{Level: gzip.BestCompression})
 	return archive
 }
 
-type Archive struct {
-	out io.WriteCloser
-	gw  *gzip.Writer
-	tw  *tar.Writer
-}
+// NewWithOptions creates a new tar.gz archive with the given options.
+// The gzip writers are reused between archives, which saves allocations
+// when creating many small archives.
+func NewWithOptions(out io.WriteCloser, opts Options) (*Archive, error) {
+	if opts.Level < gzip.HuffmanOnly || opts.Level > gzip.BestCompression {
+		return nil, fmt.Errorf("invalid gzip compression level: %d", opts.Level)
+	}
 
-func (a *Archive) AddAndClose(targetPath string, f ioh.File) error {
-	defer f.Close()
+	pool := gzipWriterPool(opts.Level)
+	gw := pool.Get().(*gzip.Writer)
+	gw.Reset(out)
 
-	info, err := f.Stat()
-	if err != nil {
-		return err
-	}
+	return &Archive{
+		out:  out,
+		gw:   gw,
+		pool: pool,
+		tw:   tarh.NewWriter(gw, opts.HeaderOptions),
+	}, nil
+}
 
-	header, err := tar.FileInfoHeader(info, "") // TODO(bep) symlink handling?
-	if err != nil {
-		return err
-	}
-	header.Name = targetPath
+var gzipWriterPools sync.Map // level => *sync.Pool
 
-	err = a.tw.WriteHeader(header)
-	if err != nil {
-		return err
+func gzipWriterPool(level int) *sync.Pool {
+	if p, found := gzipWriterPools.Load(level); found {
+		return p.(*sync.Pool)
 	}
+	p, _ := gzipWriterPools.LoadOrStore(level, &sync.Pool{
+		New: func() any {
+			gw, _ := gzip.NewWriterLevel(nil, level)
+			return gw
+		},
+	})
+	return p.(*sync.Pool)
+}
 
-	_, err = io.Copy(a.tw, f)
-	if err != nil {
-		return err
-	}
+type Archive struct {
+	out  io.WriteCloser
+	gw   *gzip.Writer
+	pool *sync.Pool
+	tw   *tarh.Writer
+}
 
-	return nil
+func (a *Archive) AddAndClose(targetPath string, f ioh.File) error {
+	return a.tw.AddAndClose(targetPath, f)
 }
 
 func (a *Archive) Finalize() error {
@@ -76,6 +91,7 @@ func (a *Archive) Finalize() error {
 	if err := a.gw.Close(); err != nil {
 		return err
 	}
+	a.pool.Put(a.gw)
 
 	return a.out.Close()
 }
@@ -0,0 +1,185 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package targz
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser/internal/archives/tarh"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := NewWithOptions(nil, Options{Level: 10})
+	c.Assert(err, qt.ErrorMatches, "invalid gzip compression level: 10")
+
+	tempDir := t.TempDir()
+	sourceFilename := filepath.Join(tempDir, "README.md")
+	c.Assert(os.WriteFile(sourceFilename, []byte("readme"), 0o644), qt.IsNil)
+
+	// The gzip writers are reused, make sure they're properly reset.
+	for i := 0; i < 3; i++ {
+		archiveFilename := filepath.Join(tempDir, "archive.tar.gz")
+		out, err := os.Create(archiveFilename)
+		c.Assert(err, qt.IsNil)
+		archive, err := NewWithOptions(out, Options{Level: gzip.BestSpeed})
+		c.Assert(err, qt.IsNil)
+		f, err := os.Open(sourceFilename)
+		c.Assert(err, qt.IsNil)
+		c.Assert(archive.AddAndClose("README.md", f), qt.IsNil)
+		c.Assert(archive.Finalize(), qt.IsNil)
+
+		f, err = os.Open(archiveFilename)
+		c.Assert(err, qt.IsNil)
+		gr, err := gzip.NewReader(f)
+		c.Assert(err, qt.IsNil)
+		tr := tar.NewReader(gr)
+		hdr, err := tr.Next()
+		c.Assert(err, qt.IsNil)
+		c.Assert(hdr.Name, qt.Equals, "README.md")
+		b, err := io.ReadAll(tr)
+		c.Assert(err, qt.IsNil)
+		c.Assert(string(b), qt.Equals, "readme")
+		f.Close()
+	}
+}
+
+func TestReproducibleHeaders(t *testing.T) {
+	c := qt.New(t)
+
+	tempDir := t.TempDir()
+	sourceFilename := filepath.Join(tempDir, "hugo")
+	c.Assert(os.WriteFile(sourceFilename, []byte("binary"), 0o755), qt.IsNil)
+	modTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	opts := Options{
+		Level: gzip.BestCompression,
+		HeaderOptions: tarh.HeaderOptions{
+			Reproducible: true,
+			Uname:        "root",
+			Gname:        "root",
+			Umask:        0o022,
+		},
+	}
+
+	headers := func(mode os.FileMode) *tar.Header {
+		// Simulate a build host with a different umask.
+		c.Assert(os.Chmod(sourceFilename, mode), qt.IsNil)
+		c.Assert(os.Chtimes(sourceFilename, modTime, modTime), qt.IsNil)
+
+		var buf bytes.Buffer
+		archive, err := NewWithOptions(nopWriteCloser{&buf}, opts)
+		c.Assert(err, qt.IsNil)
+		f, err := os.Open(sourceFilename)
+		c.Assert(err, qt.IsNil)
+		c.Assert(archive.AddAndClose("hugo", f), qt.IsNil)
+		c.Assert(archive.Finalize(), qt.IsNil)
+
+		gr, err := gzip.NewReader(&buf)
+		c.Assert(err, qt.IsNil)
+		hdr, err := tar.NewReader(gr).Next()
+		c.Assert(err, qt.IsNil)
+		return hdr
+	}
+
+	h1 := headers(0o755)
+	h2 := headers(0o775)
+
+	c.Assert(h1, qt.DeepEquals, h2)
+	c.Assert(h1.Uname, qt.Equals, "root")
+	c.Assert(h1.Gname, qt.Equals, "root")
+	c.Assert(h1.Uid, qt.Equals, 0)
+	c.Assert(h1.Mode, qt.Equals, int64(0o755))
+}
+
+func TestOwnerAndUmaskWithoutReproducible(t *testing.T) {
+	c := qt.New(t)
+
+	tempDir := t.TempDir()
+	sourceFilename := filepath.Join(tempDir, "hugo")
+	c.Assert(os.WriteFile(sourceFilename, []byte("binary"), 0o755), qt.IsNil)
+	c.Assert(os.Chmod(sourceFilename, 0o775), qt.IsNil)
+
+	var buf bytes.Buffer
+	archive, err := NewWithOptions(nopWriteCloser{&buf}, Options{
+		Level: gzip.BestCompression,
+		HeaderOptions: tarh.HeaderOptions{
+			Uname: "hugo",
+			Gname: "hugo",
+			Umask: 0o022,
+		},
+	})
+	c.Assert(err, qt.IsNil)
+	f, err := os.Open(sourceFilename)
+	c.Assert(err, qt.IsNil)
+	c.Assert(archive.AddAndClose("hugo", f), qt.IsNil)
+	c.Assert(archive.Finalize(), qt.IsNil)
+
+	gr, err := gzip.NewReader(&buf)
+	c.Assert(err, qt.IsNil)
+	hdr, err := tar.NewReader(gr).Next()
+	c.Assert(err, qt.IsNil)
+	c.Assert(hdr.Uname, qt.Equals, "hugo")
+	c.Assert(hdr.Gname, qt.Equals, "hugo")
+	c.Assert(hdr.Mode, qt.Equals, int64(0o755))
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestAddAndCloseLongTargetPath(t *testing.T) {
+	c := qt.New(t)
+
+	tempDir := t.TempDir()
+	sourceFilename := filepath.Join(tempDir, "README.md")
+	c.Assert(os.WriteFile(sourceFilename, []byte("readme"), 0o644), qt.IsNil)
+
+	// Longer than the 100 characters USTAR name limit, and the 155 characters prefix.
+	targetPath := strings.Repeat("deeply/nested/directory/", 10) + strings.Repeat("long", 30) + ".md"
+	c.Assert(len(targetPath) > 256, qt.IsTrue)
+
+	var buf bytes.Buffer
+	archive := New(nopWriteCloser{&buf})
+	f, err := os.Open(sourceFilename)
+	c.Assert(err, qt.IsNil)
+	c.Assert(archive.AddAndClose(targetPath, f), qt.IsNil)
+	c.Assert(archive.Finalize(), qt.IsNil)
+
+	gr, err := gzip.NewReader(&buf)
+	c.Assert(err, qt.IsNil)
+	tr := tar.NewReader(gr)
+	hdr, err := tr.Next()
+	c.Assert(err, qt.IsNil)
+	c.Assert(hdr.Name, qt.Equals, targetPath)
+	c.Assert(hdr.Format, qt.Equals, tar.FormatPAX)
+	b, err := io.ReadAll(tr)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b), qt.Equals, "readme")
+	_, err = tr.Next()
+	c.Assert(err, qt.Equals, io.EOF)
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tarh writes the tar entries shared by the tar based archive formats.
+package tarh
+
+import (
+	"archive/tar"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/gohugoio/hugoreleaser/internal/common/ioh"
+)
+
+// HeaderOptions configures the tar entry headers.
+type HeaderOptions struct {
+	// Reproducible sets the Uid/Gid of all entries to 0, so the headers don't depend on the build host.
+	Reproducible bool
+
+	// Uname/Gname, if set, are used as the owner names of all entries.
+	Uname string
+	Gname string
+
+	// Umask is cleared from the entry modes.
+	Umask fs.FileMode
+}
+
+// Writer writes files to a tar archive.
+type Writer struct {
+	tw   *tar.Writer
+	opts HeaderOptions
+}
+
+// NewWriter creates a new Writer writing to w, typically a compressor.
+func NewWriter(w io.Writer, opts HeaderOptions) *Writer {
+	return &Writer{tw: tar.NewWriter(w), opts: opts}
+}
+
+// AddAndClose adds f to the archive as targetPath, then closes it.
+func (w *Writer) AddAndClose(targetPath string, f ioh.File) error {
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// The content of a symbolic link is the link target, see FileCache.AddSymlink.
+	var link string
+	isSymlink := info.Mode()&fs.ModeSymlink != 0
+	if isSymlink {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		link = string(b)
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = targetPath
+
+	// Use PAX to support long names and large files.
+	// PAX records are only written when needed, so drop the
+	// sub-second and access/change times to keep the headers stable.
+	header.Format = tar.FormatPAX
+	header.ModTime = header.ModTime.Truncate(time.Second)
+	header.AccessTime = time.Time{}
+	header.ChangeTime = time.Time{}
+
+	if w.opts.Reproducible {
+		header.Uid, header.Gid = 0, 0
+	}
+	if w.opts.Uname != "" {
+		header.Uname = w.opts.Uname
+	}
+	if w.opts.Gname != "" {
+		header.Gname = w.opts.Gname
+	}
+	header.Mode &^= int64(w.opts.Umask.Perm())
+
+	err = w.tw.WriteHeader(header)
+	if err != nil || isSymlink {
+		return err
+	}
+
+	_, err = io.Copy(w.tw, f)
+
+	return err
+}
+
+// Close writes the tar footer. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	return w.tw.Close()
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tarxz
+
+import (
+	"io"
+
+	"github.com/gohugoio/hugoreleaser/internal/archives/tarh"
+	"github.com/gohugoio/hugoreleaser/internal/common/ioh"
+	"github.com/ulikunitz/xz"
+)
+
+// Options configures a tar.xz archive.
+type Options struct {
+	tarh.HeaderOptions
+}
+
+// New creates a new tar.xz archive with the given options.
+func New(out io.WriteCloser, opts Options) (*Archive, error) {
+	xw, err := xz.NewWriter(out)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Archive{
+		out: out,
+		xw:  xw,
+		tw:  tarh.NewWriter(xw, opts.HeaderOptions),
+	}, nil
+}
+
+type Archive struct {
+	out io.WriteCloser
+	xw  *xz.Writer
+	tw  *tarh.Writer
+}
+
+func (a *Archive) AddAndClose(targetPath string, f ioh.File) error {
+	return a.tw.AddAndClose(targetPath, f)
+}
+
+func (a *Archive) Finalize() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	if err := a.xw.Close(); err != nil {
+		return err
+	}
+
+	return a.out.Close()
+}
@@ -0,0 +1,61 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tarxz
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/ulikunitz/xz"
+)
+
+func TestAddAndClose(t *testing.T) {
+	c := qt.New(t)
+
+	tempDir := t.TempDir()
+	sourceFilename := filepath.Join(tempDir, "README.md")
+	c.Assert(os.WriteFile(sourceFilename, []byte("readme"), 0o644), qt.IsNil)
+
+	var buf bytes.Buffer
+	archive, err := New(nopWriteCloser{&buf}, Options{})
+	c.Assert(err, qt.IsNil)
+	f, err := os.Open(sourceFilename)
+	c.Assert(err, qt.IsNil)
+	c.Assert(archive.AddAndClose("docs/README.md", f), qt.IsNil)
+	c.Assert(archive.Finalize(), qt.IsNil)
+
+	r, err := xz.NewReader(&buf)
+	c.Assert(err, qt.IsNil)
+	tr := tar.NewReader(r)
+	hdr, err := tr.Next()
+	c.Assert(err, qt.IsNil)
+	c.Assert(hdr.Name, qt.Equals, "docs/README.md")
+	b, err := io.ReadAll(tr)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b), qt.Equals, "readme")
+	_, err = tr.Next()
+	c.Assert(err, qt.Equals, io.EOF)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,78 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tarzst
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gohugoio/hugoreleaser/internal/archives/tarh"
+	"github.com/gohugoio/hugoreleaser/internal/common/ioh"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Options configures a tar.zst archive.
+type Options struct {
+	// The zstd compression level (1-22), mapped to the closest level
+	// supported by the encoder, see zstd.EncoderLevelFromZstd.
+	// 0 means the default level.
+	Level int
+
+	tarh.HeaderOptions
+}
+
+// New creates a new tar.zst archive with the given options.
+func New(out io.WriteCloser, opts Options) (*Archive, error) {
+	if opts.Level < 0 || opts.Level > 22 {
+		return nil, fmt.Errorf("invalid zstd compression level: %d", opts.Level)
+	}
+	level := zstd.SpeedDefault
+	if opts.Level != 0 {
+		level = zstd.EncoderLevelFromZstd(opts.Level)
+	}
+
+	// The archives are built in parallel, so use one goroutine per encoder.
+	zw, err := zstd.NewWriter(out, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Archive{
+		out: out,
+		zw:  zw,
+		tw:  tarh.NewWriter(zw, opts.HeaderOptions),
+	}, nil
+}
+
+type Archive struct {
+	out io.WriteCloser
+	zw  *zstd.Encoder
+	tw  *tarh.Writer
+}
+
+func (a *Archive) AddAndClose(targetPath string, f ioh.File) error {
+	return a.tw.AddAndClose(targetPath, f)
+}
+
+func (a *Archive) Finalize() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	if err := a.zw.Close(); err != nil {
+		return err
+	}
+
+	return a.out.Close()
+}
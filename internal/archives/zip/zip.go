@@ -17,6 +17,7 @@ package zip
 import (
 	"archive/zip"
 	"io"
+	"time"
 
 	"github.com/gohugoio/hugoreleaser/internal/common/ioh"
 )
@@ -30,6 +31,8 @@ func New(out io.WriteCloser) *Archive {
 	return archive
 }
 
+var minDOSTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
 type Archive struct {
 	out  io.WriteCloser
 	zipw *zip.Writer
@@ -38,7 +41,28 @@ type Archive struct {
 func (a *Archive) AddAndClose(targetPath string, f ioh.File) error {
 	defer f.Close()
 
-	zw, err := a.zipw.Create(targetPath)
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// This stores the mode in the external attributes, so
+	// e.g. the executable bit of the binary survives extraction on Unix.
+	// Symbolic links are stored with their link target as content, which is what f contains.
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = targetPath
+	header.Method = zip.Deflate
+	header.Modified = header.Modified.Truncate(time.Second)
+	if header.Modified.Before(minDOSTime) {
+		// The MS-DOS date format used in zip starts in 1980,
+		// e.g. a reproducible archive pinned to the Unix epoch.
+		header.Modified = minDOSTime
+	}
+
+	zw, err := a.zipw.CreateHeader(header)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,85 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zip
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestAddAndClose(t *testing.T) {
+	c := qt.New(t)
+
+	tempDir := t.TempDir()
+	binaryFilename := filepath.Join(tempDir, "hugo")
+	readmeFilename := filepath.Join(tempDir, "README.md")
+	c.Assert(os.WriteFile(binaryFilename, []byte("binary"), 0o755), qt.IsNil)
+	c.Assert(os.Chmod(binaryFilename, 0o755), qt.IsNil)
+	c.Assert(os.WriteFile(readmeFilename, []byte("readme"), 0o644), qt.IsNil)
+	modTime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	c.Assert(os.Chtimes(readmeFilename, modTime, modTime), qt.IsNil)
+
+	archiveFilename := filepath.Join(tempDir, "archive.zip")
+	out, err := os.Create(archiveFilename)
+	c.Assert(err, qt.IsNil)
+
+	archive := New(out)
+	for targetPath, filename := range map[string]string{
+		"bin/hugo":       binaryFilename,
+		"docs/README.md": readmeFilename,
+	} {
+		f, err := os.Open(filename)
+		c.Assert(err, qt.IsNil)
+		c.Assert(archive.AddAndClose(targetPath, f), qt.IsNil)
+	}
+	c.Assert(archive.Finalize(), qt.IsNil)
+
+	zr, err := zip.OpenReader(archiveFilename)
+	c.Assert(err, qt.IsNil)
+	defer zr.Close()
+
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	c.Assert(files, qt.HasLen, 2)
+
+	binary := files["bin/hugo"]
+	c.Assert(binary, qt.Not(qt.IsNil))
+	c.Assert(binary.Method, qt.Equals, zip.Deflate)
+	if runtime.GOOS != "windows" {
+		c.Assert(binary.Mode().Perm(), qt.Equals, os.FileMode(0o755))
+	}
+
+	readme := files["docs/README.md"]
+	c.Assert(readme, qt.Not(qt.IsNil))
+	if runtime.GOOS != "windows" {
+		c.Assert(readme.Mode().Perm(), qt.Equals, os.FileMode(0o644))
+	}
+	c.Assert(readme.Modified.UTC(), qt.Equals, modTime)
+	rc, err := readme.Open()
+	c.Assert(err, qt.IsNil)
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b), qt.Equals, "readme")
+}
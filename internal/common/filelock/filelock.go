@@ -0,0 +1,52 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filelock provides advisory file locks, held until released or the process exits.
+package filelock
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrLocked is returned when the lock is held by someone else.
+var ErrLocked = errors.New("file is locked")
+
+// Lock is an acquired lock.
+type Lock struct {
+	f *os.File
+}
+
+// TryLock tries to acquire an exclusive lock on filename, creating it if needed.
+// It returns ErrLocked without waiting if the lock is held by someone else.
+func TryLock(filename string) (*Lock, error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := tryLock(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Lock{f: f}, nil
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock() error {
+	if err := unlock(l.f); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
@@ -0,0 +1,40 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filelock
+
+import (
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestTryLock(t *testing.T) {
+	c := qt.New(t)
+
+	filename := filepath.Join(t.TempDir(), ".lock")
+
+	l1, err := TryLock(filename)
+	c.Assert(err, qt.IsNil)
+
+	_, err = TryLock(filename)
+	c.Assert(err, qt.Equals, ErrLocked)
+
+	c.Assert(l1.Unlock(), qt.IsNil)
+
+	l2, err := TryLock(filename)
+	c.Assert(err, qt.IsNil)
+	c.Assert(l2.Unlock(), qt.IsNil)
+}
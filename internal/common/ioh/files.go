@@ -15,9 +15,12 @@
 package ioh
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"strings"
 )
 
 type File interface {
@@ -31,3 +34,39 @@ func RemoveAllMkdirAll(dirname string) error {
 	_ = os.RemoveAll(dirname)
 	return os.MkdirAll(dirname, 0o755)
 }
+
+// ValidateFilename checks that name is a single path segment that is safe to use
+// as a filename on all the common operating systems, including Windows.
+func ValidateFilename(name string) error {
+	if name == "" {
+		return errors.New("empty name")
+	}
+	if strings.HasPrefix(name, ".") {
+		return errors.New("must not start with a dot")
+	}
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		return errors.New("must not end with a dot or a space")
+	}
+	for _, r := range name {
+		if r < 32 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return fmt.Errorf("contains reserved character %q", r)
+		}
+	}
+	base, _, _ := strings.Cut(name, ".")
+	if isReservedWindowsName(strings.TrimSpace(base)) {
+		return fmt.Errorf("%q is a reserved name on Windows", base)
+	}
+	return nil
+}
+
+func isReservedWindowsName(s string) bool {
+	s = strings.ToUpper(s)
+	switch s {
+	case "CON", "PRN", "AUX", "NUL":
+		return true
+	}
+	if len(s) == 4 && (strings.HasPrefix(s, "COM") || strings.HasPrefix(s, "LPT")) {
+		return s[3] >= '1' && s[3] <= '9'
+	}
+	return false
+}
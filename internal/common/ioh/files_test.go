@@ -0,0 +1,51 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ioh
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestValidateFilename(t *testing.T) {
+	c := qt.New(t)
+
+	for _, name := range []string{
+		"hugo_1.2.0_linux-amd64.tar.gz",
+		"hugo_extended_1.2.0_Windows-ARM64.zip",
+		"console.zip",
+		"COM0.zip",
+	} {
+		c.Assert(ValidateFilename(name), qt.IsNil, qt.Commentf(name))
+	}
+
+	for _, test := range []struct {
+		name string
+		err  string
+	}{
+		{"", "empty name"},
+		{"linux/hugo.tar.gz", `contains reserved character '/'`},
+		{`windows\hugo.zip`, `contains reserved character '\\\\'`},
+		{"hugo:1.2.0.zip", `contains reserved character ':'`},
+		{"hugo\t.zip", `contains reserved character '\\t'`},
+		{".hugo.zip", "must not start with a dot"},
+		{"hugo.", "must not end with a dot or a space"},
+		{"con.zip", `"con" is a reserved name on Windows`},
+		{"LPT1.tar.gz", `"LPT1" is a reserved name on Windows`},
+	} {
+		c.Assert(ValidateFilename(test.name), qt.ErrorMatches, test.err, qt.Commentf(test.name))
+	}
+}
@@ -16,10 +16,13 @@ package config
 
 import (
 	"fmt"
+	"io/fs"
+	"regexp"
 	"strings"
 
 	"github.com/gohugoio/hugoreleaser/internal/archives/archiveformats"
 	"github.com/gohugoio/hugoreleaser/internal/common/matchers"
+	"github.com/gohugoio/hugoreleaser/internal/common/templ"
 	"github.com/gohugoio/hugoreleaser/plugins/model"
 )
 
@@ -71,6 +74,25 @@ type BuildArchPath struct {
 
 	// Any archive aliase names, with the extension.
 	Aliases []string `toml:"aliases"`
+
+	// The display label to use for the archive in the release, if any.
+	Label string `toml:"label"`
+
+	// Alternative paths for this arch, see BuildArch.Aliases.
+	PathAliases []string `toml:"path_aliases"`
+}
+
+// MatchedBy reports whether m matches the path or any of the alias paths.
+func (b BuildArchPath) MatchedBy(m matchers.Matcher) bool {
+	if m.Match(b.Path) {
+		return true
+	}
+	for _, p := range b.PathAliases {
+		if m.Match(p) {
+			return true
+		}
+	}
+	return false
 }
 
 type ArchiveSettings struct {
@@ -82,6 +104,83 @@ type ArchiveSettings struct {
 	Replacements map[string]string `toml:"replacements"`
 	Plugin       Plugin            `toml:"plugin"`
 
+	// Regular expression replacements applied in order to the archive name after replacements,
+	// e.g. to normalize x86_64 to amd64 or to strip a suffix.
+	RegexpReplacements []ArchiveRegexpReplacement `toml:"regexp_replacements"`
+
+	// Set the modification time of the archive entries to the last commit time of each file in Git.
+	// Files not in Git (e.g. the binary) will use SOURCE_DATE_EPOCH if set, else the time of the HEAD commit.
+	GitTimestamps bool `toml:"git_timestamps"`
+
+	// Put all archive entries below a top level directory named after the archive without the extension,
+	// e.g. hugo_1.2.0_linux-amd64/hugo.
+	WrapInDirectory bool `toml:"wrap_in_directory"`
+
+	// The name of the top level directory for wrap_in_directory, e.g. "{{ .Project }}-{{ .Tag }}".
+	// It's a Go template with the same context as name_template. Setting it enables wrap_in_directory.
+	WrapInDirectoryTemplate string `toml:"wrap_in_directory_template"`
+
+	// An optional display label for the archive in the release, e.g. "Linux (x86-64)".
+	// It's a Go template with the same context as name_template.
+	LabelTemplate string `toml:"label_template"`
+
+	// Project relative Go template files rendered per GOOS/GOARCH
+	// and added to the archive at target_path, e.g. a metadata.json file.
+	TemplateFiles []ArchiveFileInfo `toml:"template_files"`
+
+	// Add an INSTALL.txt file with install instructions to the archive, rendered from
+	// the project relative Go template install_template, or a built-in template if not set.
+	InstallFile     bool   `toml:"install_file"`
+	InstallTemplate string `toml:"install_template"`
+
+	// Make the archives independent of the build host:
+	// All tar entries get uid/gid 0 and owner/group default to root.
+	// Unless git_timestamps is set, all entries get the modification time in
+	// SOURCE_DATE_EPOCH, or the Unix epoch if not set.
+	// The entries are also sorted by target path, unless preserve_order is set.
+	//
+	// If set, owner/group are used as the owner names of all tar entries, and
+	// umask is applied to the entry modes, including any mode set in extra_files,
+	// also without reproducible.
+	Reproducible bool        `toml:"reproducible"`
+	Owner        string      `toml:"owner"`
+	Group        string      `toml:"group"`
+	Umask        fs.FileMode `toml:"umask"`
+
+	// Add a SHA256SUMS file with the SHA256 checksums of the regular files in the archive,
+	// in the format used by sha256sum, so the extracted files can be verified with sha256sum -c.
+	// This is independent of the checksums file in the release, which covers the archives themselves.
+	// Not supported for archive plugins and the rename and binary formats.
+	ChecksumsFile bool `toml:"checksums_file"`
+
+	// Keep the archive entries in config order (the binary first, then extra_files)
+	// when reproducible is set.
+	PreserveOrder bool `toml:"preserve_order"`
+
+	// The compression level for tar.gz and tar.zst archives, validated per format.
+	// For tar.gz, 0-9 where 0 means no compression. The default (not set) uses the best compression,
+	// except for archives smaller than small_archive_threshold, where the compression overhead dominates.
+	// For tar.zst, the zstd levels 1-22, mapped to the closest encoder level: fastest (1-2), default (3-5),
+	// better (6-9) or best (10-22). The default (not set) is the zstd default level (3).
+	// It's not used for the other formats.
+	CompressionLevel *int `toml:"compression_level"`
+
+	// Total uncompressed size in bytes below which an archive is considered small.
+	// Defaults to 1 MiB, set to -1 to always use the best compression.
+	SmallArchiveThreshold int64 `toml:"small_archive_threshold"`
+
+	// Add symbolic links in extra_files as links instead of copying the files they point to.
+	// Note that the link targets must also be in the archive for the links to work when extracted.
+	PreserveSymlinks bool `toml:"preserve_symlinks"`
+
+	// Binaries from other builds to add to the archive, e.g. helper binaries below libexec.
+	// The binary is taken from the build with the same GOOS/GOARCH as the archive.
+	ExtraBinaries []ArchiveBinary `toml:"extra_binaries"`
+
+	// Select a different archive type for some targets, e.g. zip for Windows.
+	// The first matching override wins, the default is Type.
+	FormatOverrides []ArchiveFormatOverride `toml:"format_overrides"`
+
 	// CustomSettings is archive type specific metadata.
 	// See in the documentation for the configured archive type.
 	CustomSettings map[string]any `toml:"custom_settings"`
@@ -96,12 +195,119 @@ func (a *ArchiveSettings) Init() error {
 		return fmt.Errorf("%s: %v", what, err)
 	}
 
+	if a.Reproducible {
+		if a.Owner == "" {
+			a.Owner = "root"
+		}
+		if a.Group == "" {
+			a.Group = "root"
+		}
+	}
+	if a.Umask&^fs.ModePerm != 0 {
+		return fmt.Errorf("%s: umask must only contain permission bits, got %o", what, a.Umask)
+	}
+
+	if a.WrapInDirectoryTemplate != "" {
+		if _, err := templ.Parse(a.WrapInDirectoryTemplate); err != nil {
+			return fmt.Errorf("%s: wrap_in_directory_template: %v", what, err)
+		}
+		a.WrapInDirectory = true
+	}
+
+	for i := range a.FormatOverrides {
+		if err := a.FormatOverrides[i].Init(); err != nil {
+			return fmt.Errorf("%s: format_overrides: %v", what, err)
+		}
+	}
+
+	for i := range a.RegexpReplacements {
+		if err := a.RegexpReplacements[i].Init(); err != nil {
+			return fmt.Errorf("%s: regexp_replacements: %v", what, err)
+		}
+	}
+
+	types := []ArchiveType{a.Type}
+	for _, o := range a.FormatOverrides {
+		types = append(types, o.Type)
+	}
+
+	if l := a.CompressionLevel; l != nil {
+		for _, t := range types {
+			var min, max int
+			switch t.FormatParsed {
+			case archiveformats.TarGz:
+				min, max = 0, 9
+			case archiveformats.TarZst:
+				min, max = 1, 22
+			default:
+				continue
+			}
+			if *l < min || *l > max {
+				return fmt.Errorf("%s: compression_level must be between %d and %d for the %s format, got %d", what, min, max, t.FormatParsed, *l)
+			}
+		}
+	}
+
+	if a.ChecksumsFile {
+		for _, t := range types {
+			switch t.FormatParsed {
+			case archiveformats.Plugin, archiveformats.Rename, archiveformats.Binary:
+				return fmt.Errorf("%s: checksums_file is not supported for the %s format", what, t.FormatParsed)
+			}
+		}
+	}
+
+	if len(a.ExtraFiles) > 0 || len(a.ExtraBinaries) > 0 || len(a.TemplateFiles) > 0 || a.InstallFile {
+		for _, t := range types {
+			if t.FormatParsed == archiveformats.Binary {
+				// Only the binary itself is published.
+				return fmt.Errorf("%s: extra_files, extra_binaries, template_files and install_file are not supported for the %s format", what, t.FormatParsed)
+			}
+		}
+	}
+
+	for _, b := range a.ExtraBinaries {
+		if b.Build == "" {
+			return fmt.Errorf("%s: extra_binaries: build must be set", what)
+		}
+	}
+
+	var hasSymlinks bool
+	for _, f := range a.ExtraFiles {
+		if f.SymlinkTarget == "" {
+			continue
+		}
+		hasSymlinks = true
+		if f.SourcePath != "" || f.TargetPath == "" {
+			return fmt.Errorf("%s: extra_files: symlink_target %q requires target_path and no source_path", what, f.SymlinkTarget)
+		}
+	}
+
+	for _, f := range a.TemplateFiles {
+		if f.SourcePath == "" || f.TargetPath == "" {
+			return fmt.Errorf("%s: template_files: both source_path and target_path must be set", what)
+		}
+		if f.SymlinkTarget != "" {
+			return fmt.Errorf("%s: template_files: symlink_target is not supported", what)
+		}
+	}
+
 	// Validate format setup.
 	switch a.Type.FormatParsed {
 	case archiveformats.Plugin:
 		if err := a.Plugin.Init(); err != nil {
 			return fmt.Errorf("%s: %v", what, err)
 		}
+		if len(a.TemplateFiles) > 0 || a.InstallFile {
+			// The rendered files are kept in memory.
+			return fmt.Errorf("%s: template_files and install_file are not supported for archive plugins", what)
+		}
+		if a.GitTimestamps {
+			return fmt.Errorf("%s: git_timestamps is not supported for archive plugins", what)
+		}
+		if hasSymlinks || a.PreserveSymlinks {
+			return fmt.Errorf("%s: symlinks are not supported for archive plugins", what)
+		}
 	default:
 		// Clear it to we don't need to start it.
 		a.Plugin.Clear()
@@ -118,6 +324,77 @@ func (a *ArchiveSettings) Init() error {
 	return nil
 }
 
+// ReplaceName applies the replacements and then the regexp_replacements to the archive name.
+func (a ArchiveSettings) ReplaceName(name string) string {
+	if a.ReplacementsCompiled != nil {
+		name = a.ReplacementsCompiled.Replace(name)
+	}
+	for _, r := range a.RegexpReplacements {
+		name = r.PatternCompiled.ReplaceAllString(name, r.Replacement)
+	}
+	return name
+}
+
+// TypeFor returns the archive type to use for the given target.
+func (a ArchiveSettings) TypeFor(goos, goarch string) ArchiveType {
+	for _, o := range a.FormatOverrides {
+		if o.Goos == goos && (o.Goarch == "" || o.Goarch == goarch) {
+			return o.Type
+		}
+	}
+	return a.Type
+}
+
+// ArchiveBinary is a binary from another build to add to an archive.
+type ArchiveBinary struct {
+	// The path of the build (builds.path) to take the binary from.
+	Build string `toml:"build"`
+
+	// The directory in the archive to put the binary in, defaults to the archive's binary_dir.
+	BinaryDir string `toml:"binary_dir"`
+}
+
+// ArchiveRegexpReplacement replaces all matches of Pattern in the archive name with Replacement,
+// which may refer to submatches, e.g. "$1".
+type ArchiveRegexpReplacement struct {
+	Pattern     string `toml:"pattern"`
+	Replacement string `toml:"replacement"`
+
+	PatternCompiled *regexp.Regexp `toml:"-"`
+}
+
+func (r *ArchiveRegexpReplacement) Init() error {
+	if r.Pattern == "" {
+		return fmt.Errorf("pattern must be set")
+	}
+	var err error
+	r.PatternCompiled, err = regexp.Compile(r.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %v", r.Pattern, err)
+	}
+	return nil
+}
+
+// ArchiveFormatOverride selects the archive type for the given GOOS and optional GOARCH.
+type ArchiveFormatOverride struct {
+	Goos   string      `toml:"goos"`
+	Goarch string      `toml:"goarch"`
+	Type   ArchiveType `toml:"type"`
+}
+
+func (o *ArchiveFormatOverride) Init() error {
+	if o.Goos == "" {
+		return fmt.Errorf("goos is required")
+	}
+	if err := o.Type.Init(); err != nil {
+		return fmt.Errorf("%s: %v", o.Goos, err)
+	}
+	if o.Type.FormatParsed == archiveformats.Plugin {
+		return fmt.Errorf("%s: plugin format is not supported", o.Goos)
+	}
+	return nil
+}
+
 type ArchiveType struct {
 	Format    string `toml:"format"`
 	Extension string `toml:"extension"`
@@ -130,17 +407,29 @@ func (a *ArchiveType) Init() error {
 	if a.Format == "" {
 		return fmt.Errorf("%s: has no format", what)
 	}
-	if a.Extension == "" {
-		return fmt.Errorf("%s: has no extension", what)
-	}
 	var err error
 	if a.FormatParsed, err = archiveformats.Parse(a.Format); err != nil {
 		return err
 	}
+	if a.Extension == "" {
+		a.Extension = a.FormatParsed.DefaultExtension()
+	}
+	if a.Extension == "" && a.FormatParsed != archiveformats.Binary {
+		return fmt.Errorf("%s: has no extension", what)
+	}
 
 	return nil
 }
 
+// ExtensionFor returns the file extension to use for an archive for goos.
+// For the binary format, .exe is added for Windows if not already set.
+func (a ArchiveType) ExtensionFor(goos string) string {
+	if a.FormatParsed == archiveformats.Binary && goos == "windows" && !strings.HasSuffix(a.Extension, ".exe") {
+		return a.Extension + ".exe"
+	}
+	return a.Extension
+}
+
 // IsZero is needed to get the shallow merge correct.
 func (a ArchiveType) IsZero() bool {
 	return a.Format == "" && a.Extension == ""
@@ -52,9 +52,22 @@ var _ logg.Fielder = BuildSettings{}
 type BuildSettings struct {
 	Binary string `toml:"binary"`
 
-	Env     []string `toml:"env"`
-	Ldflags string   `toml:"ldflags"`
-	Flags   []string `toml:"flags"`
+	// The project relative main package to build, e.g. "./cmd/hugo".
+	// Defaults to the package in the project root.
+	Main string `toml:"main"`
+
+	// Environment variables on the form KEY=value set for the build, overriding the inherited environment.
+	// The values are Go templates with the same context as name_template.
+	Env []string `toml:"env"`
+
+	// Passed as is to go build -ldflags, so values with spaces can be quoted, e.g. "-X 'main.msg=hello world'".
+	// This is a Go template with the same context as name_template, e.g. "-X main.version={{ .Tag }}".
+	Ldflags string `toml:"ldflags"`
+
+	// Passed to go build verbatim after -ldflags, e.g. ["-trimpath", "-tags", "extended"].
+	// An -ldflags here overrides ldflags above, as go build uses the last one.
+	// The flags are Go templates with the same context as name_template, empty flags are skipped.
+	Flags []string `toml:"flags"`
 
 	GoSettings GoSettings `toml:"go_settings"`
 }
@@ -77,6 +90,14 @@ type Builds []Build
 type BuildArch struct {
 	Goarch string `toml:"goarch"`
 
+	// Alternative GOARCH names that path filters may use to match this arch, e.g. "x86_64" for "amd64".
+	// This allows old -paths and release paths to keep working when an arch is renamed.
+	Aliases []string `toml:"aliases"`
+
+	// ExtraLdflags will be appended to the ldflags in BuildSettings for this arch only.
+	// This is a Go template with the same context as the archive name_template.
+	ExtraLdflags string `toml:"extra_ldflags"`
+
 	BuildSettings BuildSettings `toml:"build_settings"`
 
 	// Tree navigation.
@@ -26,8 +26,20 @@ type Config struct {
 	Project                  string            `toml:"project"`
 	ArchiveAliasReplacements map[string]string `toml:"archive_alias_replacements"`
 
+	// Go templates added before and after the name (before the extension) of all
+	// archives and the checksums file, e.g. to prefix every asset with a product slug.
+	AssetNamePrefix string `toml:"asset_name_prefix"`
+	AssetNameSuffix string `toml:"asset_name_suffix"`
+
+	// Environment variables to expose as .Env in templates.
+	// Only these are available, to avoid leaking secrets.
+	TemplateEnv []string `toml:"template_env"`
+
 	GoSettings GoSettings `toml:"go_settings"`
 
+	// Commands to run before any archive is created.
+	Generate []GenerateCommand `toml:"generate"`
+
 	Builds   Builds   `toml:"builds"`
 	Archives Archives `toml:"archives"`
 	Releases Releases `toml:"releases"`
@@ -47,7 +59,8 @@ func (c Config) FindReleases(filter matchers.Matcher) []Release {
 	return releases
 }
 
-// FindArchs returns the archs that match the given filter
+// FindArchs returns the archs that match the given filter,
+// either by their path or any of their alias paths.
 func (c Config) FindArchs(filter matchers.Matcher) []BuildArchPath {
 	var archs []BuildArchPath
 	for _, build := range c.Builds {
@@ -55,9 +68,12 @@ func (c Config) FindArchs(filter matchers.Matcher) []BuildArchPath {
 		for _, os := range build.Os {
 			osPath := buildPath + "/" + os.Goos
 			for _, arch := range os.Archs {
-				archPath := osPath + "/" + arch.Goarch
-				if filter.Match(archPath) {
-					archs = append(archs, BuildArchPath{Arch: arch, Path: archPath})
+				archPath := BuildArchPath{Arch: arch, Path: osPath + "/" + arch.Goarch}
+				for _, alias := range arch.Aliases {
+					archPath.PathAliases = append(archPath.PathAliases, osPath+"/"+alias)
+				}
+				if archPath.MatchedBy(filter) {
+					archs = append(archs, archPath)
 				}
 			}
 		}
@@ -65,6 +81,26 @@ func (c Config) FindArchs(filter matchers.Matcher) []BuildArchPath {
 	return archs
 }
 
+// FindArch returns the arch with the given build path, GOOS and GOARCH.
+func (c Config) FindArch(buildPath, goos, goarch string) (BuildArch, bool) {
+	for _, build := range c.Builds {
+		if build.Path != buildPath {
+			continue
+		}
+		for _, os := range build.Os {
+			if os.Goos != goos {
+				continue
+			}
+			for _, arch := range os.Archs {
+				if arch.Goarch == goarch {
+					return arch, true
+				}
+			}
+		}
+	}
+	return BuildArch{}, false
+}
+
 type Plugin struct {
 	ID      string   `toml:"id"`
 	Type    string   `toml:"type"`
@@ -104,8 +140,40 @@ func (t Plugin) IsZero() bool {
 	return t.ID == ""
 }
 
+// GenerateCommand is a command that produces files to be included in archives
+// (see archive_settings.extra_files) or uploaded as release assets (see release_settings.extra_files),
+// e.g. shell completions and man pages.
+// The command runs in the project directory with HUGORELEASER_PROJECT,
+// HUGORELEASER_TAG and HUGORELEASER_DIST set in the environment.
+type GenerateCommand struct {
+	Command string   `toml:"command"`
+	Args    []string `toml:"args"`
+}
+
+func (g *GenerateCommand) Init() error {
+	what := "generate"
+	if g.Command == "" {
+		return fmt.Errorf("%s: command is required", what)
+	}
+	return nil
+}
+
+// ArchiveFileInfo describes a file to add to an archive.
+// In extra_files, SourcePath can be a glob pattern (e.g. "licenses/*.txt"),
+// in which case TargetPath is the directory to add the matched files to.
 type ArchiveFileInfo struct {
-	SourcePath string      `toml:"source_path"`
-	TargetPath string      `toml:"target_path"`
-	Mode       fs.FileMode `toml:"mode"`
+	SourcePath string `toml:"source_path"`
+	TargetPath string `toml:"target_path"`
+
+	// The file mode in the archive, e.g. 0o755 for a script. Defaults to the source file's mode.
+	Mode fs.FileMode `toml:"mode"`
+
+	// If set, a symbolic link to this path is added at TargetPath instead of a copy of SourcePath,
+	// e.g. "hugo-1.2.0" to add an unversioned link to a versioned binary.
+	// Only supported in extra_files.
+	SymlinkTarget string `toml:"symlink_target"`
+
+	// Don't fail if SourcePath is a glob pattern matching no files.
+	// Only supported in extra_files.
+	AllowEmpty bool `toml:"allow_empty"`
 }
@@ -17,9 +17,13 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
 	"github.com/pelletier/go-toml/v2"
 
 	qt "github.com/frankban/quicktest"
@@ -35,9 +39,169 @@ func TestDecode(t *testing.T) {
 format = "foo"
 `
 
-		_, err := DecodeAndApplyDefaults(strings.NewReader(file))
+		_, err := DecodeAndApplyDefaults(strings.NewReader(file), "")
 		c.Assert(err, qt.Not(qt.IsNil))
 	})
+
+	c.Run("HTTP settings", func(c *qt.C) {
+		file := `
+[release_settings]
+type = "github"
+[release_settings.http_settings]
+read_timeout = "20m"
+[[releases]]
+path = "a"
+paths = ["archives/**"]
+[[releases]]
+path = "b"
+paths = ["archives/**"]
+[releases.release_settings.http_settings]
+connect_timeout = "5s"
+`
+		cfg, err := DecodeAndApplyDefaults(strings.NewReader(file), "")
+		c.Assert(err, qt.IsNil)
+		c.Assert(cfg.Releases[0].ReleaseSettings.HTTPSettings.ConnectTimeoutParsed, qt.Equals, 30*time.Second)
+		c.Assert(cfg.Releases[0].ReleaseSettings.HTTPSettings.ReadTimeoutParsed, qt.Equals, 20*time.Minute)
+		c.Assert(cfg.Releases[0].ReleaseSettings.HTTPSettings.TimeoutParsed, qt.Equals, time.Duration(0))
+		c.Assert(cfg.Releases[1].ReleaseSettings.HTTPSettings.ConnectTimeoutParsed, qt.Equals, 5*time.Second)
+		c.Assert(cfg.Releases[1].ReleaseSettings.HTTPSettings.ReadTimeoutParsed, qt.Equals, 20*time.Minute)
+
+		_, err = DecodeAndApplyDefaults(strings.NewReader(strings.Replace(file, `"5s"`, `"5 seconds"`, 1)), "")
+		c.Assert(err, qt.ErrorMatches, `.*invalid connect_timeout.*`)
+	})
+
+	c.Run("Compression level", func(c *qt.C) {
+		file := `
+[archive_settings]
+compression_level = 5
+[archive_settings.type]
+format = "tar.gz"
+extension = ".tar.gz"
+[[archives]]
+paths = ["builds/**/linux/**"]
+[[archives]]
+paths = ["builds/**/darwin/**"]
+[archives.archive_settings]
+compression_level = 0
+`
+		cfg, err := DecodeAndApplyDefaults(strings.NewReader(file), "")
+		c.Assert(err, qt.IsNil)
+		c.Assert(*cfg.Archives[0].ArchiveSettings.CompressionLevel, qt.Equals, 5)
+		// 0 means no compression and is not replaced by the inherited value.
+		c.Assert(*cfg.Archives[1].ArchiveSettings.CompressionLevel, qt.Equals, 0)
+
+		cfg, err = DecodeAndApplyDefaults(strings.NewReader(strings.Replace(file, "compression_level = 5", "", 1)), "")
+		c.Assert(err, qt.IsNil)
+		c.Assert(cfg.Archives[0].ArchiveSettings.CompressionLevel, qt.IsNil)
+
+		_, err = DecodeAndApplyDefaults(strings.NewReader(strings.Replace(file, "compression_level = 0", "compression_level = 10", 1)), "")
+		c.Assert(err, qt.ErrorMatches, `.*compression_level must be between 0 and 9 for the tar.gz format, got 10`)
+
+		// zstd has its own scale.
+		zst := strings.Replace(strings.Replace(file, `"tar.gz"`, `"tar.zst"`, 1), `".tar.gz"`, `".tar.zst"`, 1)
+		cfg, err = DecodeAndApplyDefaults(strings.NewReader(strings.Replace(zst, "compression_level = 0", "compression_level = 19", 1)), "")
+		c.Assert(err, qt.IsNil)
+		c.Assert(*cfg.Archives[1].ArchiveSettings.CompressionLevel, qt.Equals, 19)
+		_, err = DecodeAndApplyDefaults(strings.NewReader(zst), "")
+		c.Assert(err, qt.ErrorMatches, `.*compression_level must be between 1 and 22 for the tar.zst format, got 0`)
+	})
+
+	c.Run("Binary format", func(c *qt.C) {
+		file := `
+[archive_settings.type]
+format = "binary"
+[[archives]]
+paths = ["builds/**"]
+`
+		_, err := DecodeAndApplyDefaults(strings.NewReader(file), "")
+		c.Assert(err, qt.IsNil)
+
+		for _, setting := range []string{
+			`extra_files = [{ source_path = "README.md", target_path = "README.md" }]`,
+			`template_files = [{ source_path = "meta.json", target_path = "meta.json" }]`,
+			`install_file = true`,
+			`extra_binaries = [{ build = "helpers" }]`,
+		} {
+			_, err := DecodeAndApplyDefaults(strings.NewReader(strings.Replace(file, "[archive_settings.type]", "[archive_settings]\n"+setting+"\n[archive_settings.type]", 1)), "")
+			c.Assert(err, qt.ErrorMatches, `.*extra_files, extra_binaries, template_files and install_file are not supported for the binary format`, qt.Commentf(setting))
+		}
+
+		// Also in format_overrides.
+		_, err = DecodeAndApplyDefaults(strings.NewReader(`
+[archive_settings]
+install_file = true
+format_overrides = [{ goos = "linux", type = { format = "binary" } }]
+[archive_settings.type]
+format = "tar.gz"
+[[archives]]
+paths = ["builds/**"]
+`), "")
+		c.Assert(err, qt.ErrorMatches, `.*not supported for the binary format`)
+	})
+
+	c.Run("Conventional commits", func(c *qt.C) {
+		file := `
+[release_settings]
+type = "github"
+[release_settings.release_notes_settings]
+conventional_commits = true
+[[releases]]
+path = "a"
+paths = ["archives/**"]
+[[releases]]
+path = "b"
+paths = ["archives/**"]
+[releases.release_settings.release_notes_settings]
+groups = [{ title = "Features", types = ["Feat", "perf"] }]
+`
+		cfg, err := DecodeAndApplyDefaults(strings.NewReader(file), "")
+		c.Assert(err, qt.IsNil)
+
+		groups := cfg.Releases[0].ReleaseSettings.ReleaseNotesSettings.Groups
+		c.Assert(groups, qt.HasLen, 4)
+		c.Assert(groups[0].Match("feat!: Remove foo", "feat", true), qt.IsTrue)
+		c.Assert(groups[1].Match("feat: Add foo", "feat", false), qt.IsTrue)
+		c.Assert(groups[2].Match("feat: Add foo", "feat", false), qt.IsFalse)
+		c.Assert(groups[3].Match("Add foo", "", false), qt.IsTrue)
+
+		groups = cfg.Releases[1].ReleaseSettings.ReleaseNotesSettings.Groups
+		c.Assert(groups, qt.HasLen, 1)
+		c.Assert(groups[0].Match("perf: Speed up foo", "perf", false), qt.IsTrue)
+		c.Assert(groups[0].Match("fix: Fix feat", "fix", false), qt.IsFalse)
+
+		_, err = DecodeAndApplyDefaults(strings.NewReader(strings.Replace(file, `types = ["Feat", "perf"]`, `ordinal = 1`, 1)), "")
+		c.Assert(err, qt.ErrorMatches, `.*one of regexp, types or breaking must be set`)
+	})
+
+	c.Run("Signing key file", func(c *qt.C) {
+		key, err := openpgp.NewEntity("Hugoreleaser Test", "", "test@example.org", nil)
+		c.Assert(err, qt.IsNil)
+		projectDir := c.TempDir()
+		f, err := os.Create(filepath.Join(projectDir, "key.asc"))
+		c.Assert(err, qt.IsNil)
+		w, err := armor.Encode(f, openpgp.PrivateKeyType, nil)
+		c.Assert(err, qt.IsNil)
+		c.Assert(key.SerializePrivate(w, nil), qt.IsNil)
+		c.Assert(w.Close(), qt.IsNil)
+		c.Assert(f.Close(), qt.IsNil)
+
+		file := `
+[release_settings]
+type = "github"
+[release_settings.signing_settings]
+key_file = "key.asc"
+[[releases]]
+path = "a"
+paths = ["archives/**"]
+`
+		// The key file is relative to the project dir.
+		cfg, err := DecodeAndApplyDefaults(strings.NewReader(file), projectDir)
+		c.Assert(err, qt.IsNil)
+		c.Assert(cfg.Releases[0].ReleaseSettings.SigningSettings.Key.PrimaryKey.KeyId, qt.Equals, key.PrimaryKey.KeyId)
+
+		_, err = DecodeAndApplyDefaults(strings.NewReader(file), c.TempDir())
+		c.Assert(err, qt.ErrorMatches, `.*key_file: .*no such file.*`)
+	})
 }
 
 func TestDecodeFile(t *testing.T) {
@@ -47,7 +211,7 @@ func TestDecodeFile(t *testing.T) {
 	c.Assert(err, qt.IsNil)
 	defer f.Close()
 
-	cfg, err := DecodeAndApplyDefaults(f)
+	cfg, err := DecodeAndApplyDefaults(f, "")
 	if err != nil {
 		switch v := err.(type) {
 		case *toml.DecodeError:
@@ -29,7 +29,8 @@ var zeroType = reflect.TypeOf((*zeroer)(nil)).Elem()
 
 // DecodeAndApplyDefaults first expand any environment variables in r (${var}),
 // decodes it and applies default values.
-func DecodeAndApplyDefaults(r io.Reader) (Config, error) {
+// Relative filenames in the config, e.g. http_settings.ca_file, are resolved relative to projectDir.
+func DecodeAndApplyDefaults(r io.Reader, projectDir string) (Config, error) {
 	cfg := &Config{}
 
 	// Expand environment variables in the source.
@@ -95,6 +96,16 @@ func DecodeAndApplyDefaults(r io.Reader) (Config, error) {
 	for i := range cfg.Releases {
 		shallowMerge(&cfg.Releases[i].ReleaseSettings, cfg.ReleaseSettings)
 		shallowMerge(&cfg.Releases[i].ReleaseSettings.ReleaseNotesSettings, cfg.ReleaseSettings.ReleaseNotesSettings)
+		shallowMerge(&cfg.Releases[i].ReleaseSettings.HTTPSettings, cfg.ReleaseSettings.HTTPSettings)
+		shallowMerge(&cfg.Releases[i].ReleaseSettings.SigningSettings, cfg.ReleaseSettings.SigningSettings)
+		shallowMerge(&cfg.Releases[i].ReleaseSettings.S3Settings, cfg.ReleaseSettings.S3Settings)
+	}
+
+	// Init and validate generate commands.
+	for i := range cfg.Generate {
+		if err := cfg.Generate[i].Init(); err != nil {
+			return *cfg, err
+		}
 	}
 
 	// Init and validate build settings.
@@ -113,7 +124,7 @@ func DecodeAndApplyDefaults(r io.Reader) (Config, error) {
 
 	// Init and validate release configs.
 	for i := range cfg.Releases {
-		if err := cfg.Releases[i].Init(); err != nil {
+		if err := cfg.Releases[i].Init(projectDir); err != nil {
 			return *cfg, err
 		}
 	}
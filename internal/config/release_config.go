@@ -15,13 +15,23 @@
 package config
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
+	"os"
 	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"text/template"
+	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/gohugoio/hugoreleaser/internal/common/matchers"
+	"github.com/gohugoio/hugoreleaser/internal/common/templ"
+	"github.com/gohugoio/hugoreleaser/internal/releases/checksumalgos"
 	"github.com/gohugoio/hugoreleaser/internal/releases/releasetypes"
 )
 
@@ -41,7 +51,7 @@ type Release struct {
 	ArchsCompiled []BuildArchPath `toml:"-"`
 }
 
-func (a *Release) Init() error {
+func (a *Release) Init(projectDir string) error {
 	what := "releases"
 
 	if a.Path == "" {
@@ -64,7 +74,7 @@ func (a *Release) Init() error {
 		return fmt.Errorf("failed to compile archive paths glob %q: %v", a.Paths, err)
 	}
 
-	if err := a.ReleaseSettings.Init(); err != nil {
+	if err := a.ReleaseSettings.Init(projectDir); err != nil {
 		return fmt.Errorf("%s: %v", what, err)
 	}
 
@@ -77,12 +87,310 @@ type ReleaseSettings struct {
 	Name            string `toml:"name"`
 	Repository      string `toml:"repository"`
 	RepositoryOwner string `toml:"repository_owner"`
-	Draft           bool   `toml:"draft"`
-	Prerelease      bool   `toml:"prerelease"`
+
+	// The URL of a self-hosted instance, e.g. https://gitlab.example.com.
+	// Defaults to https://gitlab.com for the gitlab and https://gitea.com for the gitea release type.
+	// For the s3 release type, this is a custom endpoint, e.g. for MinIO or Cloudflare R2.
+	BaseURL string `toml:"base_url"`
+
+	Draft      bool `toml:"draft"`
+	Prerelease bool `toml:"prerelease"`
+
+	// Project relative paths to extra files to upload as release assets.
+	ExtraFiles []string `toml:"extra_files"`
+
+	// Go template for each line in the checksums file with .Hash, .Name and .Algorithm available.
+	// Defaults to "{{ .Hash }}  {{ .Name }}", the format used by sha256sum.
+	ChecksumLineTemplate string `toml:"checksum_line_template"`
+
+	// The hash algorithms to use in the checksums file, "sha256" (the default) and/or "sha512".
+	// The lines are grouped per algorithm in the order given.
+	ChecksumAlgorithms []string `toml:"checksum_algorithms"`
+
+	// The name of the checksums file, a Go template with the same context as name_template
+	// (Goos and Goarch are empty), e.g. "SHA256SUMS". Defaults to <project>_<version>_checksums.txt.
+	// With separate_checksum_files, the algorithm is added before any .txt extension, e.g. SHA256SUMS-sha512.
+	ChecksumFilename string `toml:"checksum_filename"`
+
+	// Write the checksums of all but the first algorithm to separate files,
+	// e.g. hugo_1.2.0_checksums-sha512.txt.
+	SeparateChecksumFiles bool `toml:"separate_checksum_files"`
+
+	// How to write the checksums: "combined" (the default) writes one checksums file for all files,
+	// "per-file" writes a sidecar file per release file named after the algorithm, e.g. hugo_1.2.0_linux-amd64.tar.gz.sha256.
+	ChecksumMode string `toml:"checksum_mode"`
+
+	// Max number of concurrent uploads for this release, e.g. to avoid GitHub's secondary rate limits.
+	// Defaults to the number of -workers.
+	UploadConcurrency int `toml:"upload_concurrency"`
+
+	// Max size in bytes of a single release asset, checked before the release is created.
+	// Defaults to the limit of the release client, e.g. 2 GiB for GitHub.
+	MaxAssetSize int64 `toml:"max_asset_size"`
+
+	// What to do with assets already in the release when uploading to an existing release
+	// (-existing or -release-id): "fail" (the default) or "replace" (delete, then upload).
+	OnExisting string `toml:"on_existing"`
+
+	// Additional targets to publish the same files to, e.g. an S3 bucket mirroring a GitHub release.
+	// Each mirror needs a type and its target settings (repository, repository_owner, base_url and s3_settings),
+	// the other settings default to the settings of this release.
+	// The checksums, signatures and release notes are created once and shared with the mirrors.
+	Mirrors []ReleaseSettings `toml:"mirrors"`
 
 	ReleaseNotesSettings ReleaseNotesSettings `toml:"release_notes_settings"`
+	HTTPSettings         HTTPSettings         `toml:"http_settings"`
+	SigningSettings      SigningSettings      `toml:"signing_settings"`
+	S3Settings           S3Settings           `toml:"s3_settings"`
+
+	TypeParsed                   releasetypes.Type         `toml:"-"`
+	ChecksumLineTemplateCompiled *template.Template        `toml:"-"`
+	ChecksumAlgorithmsParsed     []checksumalgos.Algorithm `toml:"-"`
+}
+
+// HTTPSettings configures the HTTP client used to talk to the release target.
+// All values are durations on the form "30s", "5m" etc.
+type HTTPSettings struct {
+	// Max time to wait for a connection to be established. Defaults to 30s.
+	ConnectTimeout string `toml:"connect_timeout"`
+
+	// Max time to wait for the response headers after the request (e.g. the asset upload) is written.
+	// Defaults to 5m.
+	ReadTimeout string `toml:"read_timeout"`
+
+	// Max time for a single request, including reading the response body.
+	// Defaults to no limit other than the global -timeout.
+	Timeout string `toml:"timeout"`
+
+	// TLS settings, e.g. for endpoints requiring mutual TLS.
+	// Each can be a filename, relative to the project dir, or the PEM encoded content itself (e.g. "${MY_CA_PEM}").
+	// CAFile adds to the system roots. CertFile and KeyFile is the client certificate and must be set together.
+	CAFile   string `toml:"ca_file"`
+	CertFile string `toml:"cert_file"`
+	KeyFile  string `toml:"key_file"`
+
+	ConnectTimeoutParsed time.Duration `toml:"-"`
+	ReadTimeoutParsed    time.Duration `toml:"-"`
+	TimeoutParsed        time.Duration `toml:"-"`
+
+	// Nil if no TLS settings are set.
+	TLSConfig *tls.Config `toml:"-"`
+}
+
+func (h *HTTPSettings) Init(projectDir string) error {
+	what := "http_settings"
+
+	parse := func(name, s string, dflt time.Duration) (time.Duration, error) {
+		if s == "" {
+			return dflt, nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("%s: invalid %s: %v", what, name, err)
+		}
+		if d < 0 {
+			return 0, fmt.Errorf("%s: %s must not be negative", what, name)
+		}
+		return d, nil
+	}
+
+	var err error
+	if h.ConnectTimeoutParsed, err = parse("connect_timeout", h.ConnectTimeout, 30*time.Second); err != nil {
+		return err
+	}
+	if h.ReadTimeoutParsed, err = parse("read_timeout", h.ReadTimeout, 5*time.Minute); err != nil {
+		return err
+	}
+	if h.TimeoutParsed, err = parse("timeout", h.Timeout, 0); err != nil {
+		return err
+	}
+
+	if h.TLSConfig, err = h.tlsConfig(projectDir); err != nil {
+		return fmt.Errorf("%s: %v", what, err)
+	}
 
-	TypeParsed releasetypes.Type `toml:"-"`
+	return nil
+}
+
+func (h *HTTPSettings) tlsConfig(projectDir string) (*tls.Config, error) {
+	if h.CAFile == "" && h.CertFile == "" && h.KeyFile == "" {
+		return nil, nil
+	}
+	if (h.CertFile == "") != (h.KeyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must be set together")
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if h.CAFile != "" {
+		b, err := readPEM(projectDir, h.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("ca_file: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("ca_file: no certificates found")
+		}
+		cfg.RootCAs = pool
+	}
+
+	if h.CertFile != "" {
+		certPEM, err := readPEM(projectDir, h.CertFile)
+		if err != nil {
+			return nil, fmt.Errorf("cert_file: %v", err)
+		}
+		keyPEM, err := readPEM(projectDir, h.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("key_file: %v", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("cert_file/key_file: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// readPEM returns s if it's PEM encoded content, else the content of the file named s,
+// relative to dir if not absolute.
+func readPEM(dir, s string) ([]byte, error) {
+	if strings.HasPrefix(strings.TrimSpace(s), "-----BEGIN") {
+		return []byte(s), nil
+	}
+	if !filepath.IsAbs(s) {
+		s = filepath.Join(dir, s)
+	}
+	return os.ReadFile(s)
+}
+
+// S3Settings configures the s3 release type.
+// Credentials are resolved the standard AWS way, e.g. from AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY or ~/.aws.
+type S3Settings struct {
+	// The bucket to upload the release files to. Required for the s3 release type.
+	Bucket string `toml:"bucket"`
+
+	// The key prefix, defaults to the project name. The files are uploaded below prefix/tag/.
+	Prefix string `toml:"prefix"`
+
+	// The region, defaults to the standard AWS config, e.g. AWS_REGION.
+	Region string `toml:"region"`
+
+	// Make the uploaded files publicly readable using the public-read canned ACL.
+	PublicRead bool `toml:"public_read"`
+
+	// If set, each uploaded file is also copied to this key below prefix/, giving a stable download URL for the latest release.
+	// It's a Go template with .Project, .Tag, .Version (the tag without the v prefix) and .Name (the filename) available,
+	// e.g. "latest/{{ replace .Name .Version `latest` }}".
+	// Prereleases are not copied unless latest_include_prerelease is set.
+	LatestKeyTemplate string `toml:"latest_key_template"`
+
+	// Also copy the files of prereleases to latest_key_template.
+	LatestIncludePrerelease bool `toml:"latest_include_prerelease"`
+}
+
+// LatestKeyContext is the template context for S3Settings.LatestKeyTemplate.
+type LatestKeyContext struct {
+	Project string
+	Tag     string
+	Version string
+	Name    string
+}
+
+// SigningSettings configures GPG signing of the release files.
+// Signing is enabled if key_id or key_file is set, and the armored detached signatures,
+// e.g. hugo_1.2.0_checksums.txt.asc, are uploaded with the other release files.
+type SigningSettings struct {
+	// The key to sign with, as a key ID, fingerprint or email address.
+	// Without key_file, the key is taken from the gpg keyring, which needs gpg installed.
+	KeyID string `toml:"key_id"`
+
+	// An armored private key, a filename relative to the project dir or the content itself (e.g. "${MY_GPG_KEY}").
+	// If it holds more than one key, key_id selects the key, else the first key is used.
+	KeyFile string `toml:"key_file"`
+
+	// The env var holding the passphrase of the key, if any. Defaults to HUGORELEASER_GPG_PASSPHRASE.
+	PassphraseEnv string `toml:"passphrase_env"`
+
+	// Also sign each archive and extra file, not only the checksums file(s).
+	SignArchives bool `toml:"sign_archives"`
+
+	// If set, the armored public key is uploaded with the release files under this name, e.g. "hugo_public_key.asc",
+	// and the release notes get instructions on how to verify the signatures with it.
+	PublicKeyFilename string `toml:"public_key_filename"`
+
+	// The key read from KeyFile, nil if not set.
+	Key *openpgp.Entity `toml:"-"`
+}
+
+// Enabled reports whether signing is configured.
+func (s SigningSettings) Enabled() bool {
+	return s.KeyID != "" || s.KeyFile != ""
+}
+
+func (s *SigningSettings) Init(projectDir string) error {
+	what := "signing_settings"
+
+	if s.PassphraseEnv == "" {
+		s.PassphraseEnv = "HUGORELEASER_GPG_PASSPHRASE"
+	}
+
+	if s.PublicKeyFilename != "" {
+		if !s.Enabled() {
+			return fmt.Errorf("%s: public_key_filename requires key_id or key_file to be set", what)
+		}
+		if filepath.Base(s.PublicKeyFilename) != s.PublicKeyFilename {
+			return fmt.Errorf("%s: public_key_filename must be a filename without a directory, got %q", what, s.PublicKeyFilename)
+		}
+	}
+
+	if s.KeyFile == "" {
+		return nil
+	}
+
+	b, err := readPEM(projectDir, s.KeyFile)
+	if err != nil {
+		return fmt.Errorf("%s: key_file: %v", what, err)
+	}
+	keyRing, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("%s: key_file: %v", what, err)
+	}
+
+	for _, e := range keyRing {
+		if e.PrivateKey == nil || !matchesKeyID(e, s.KeyID) {
+			continue
+		}
+		s.Key = e
+		break
+	}
+	if s.Key == nil {
+		return fmt.Errorf("%s: key_file: no private key found matching key_id %q", what, s.KeyID)
+	}
+
+	return nil
+}
+
+// matchesKeyID reports whether e matches id, a (short) key ID, fingerprint or email address.
+// An empty id matches any key.
+func matchesKeyID(e *openpgp.Entity, id string) bool {
+	if id == "" {
+		return true
+	}
+	hexID := strings.ToUpper(strings.TrimPrefix(strings.ReplaceAll(id, " ", ""), "0x"))
+	if len(hexID) >= 8 && strings.HasSuffix(fmt.Sprintf("%X", e.PrimaryKey.Fingerprint), hexID) {
+		return true
+	}
+	for _, ident := range e.Identities {
+		if ident.UserId != nil && strings.EqualFold(ident.UserId.Email, id) {
+			return true
+		}
+	}
+	return false
 }
 
 type ReleaseNotesSettings struct {
@@ -92,6 +400,48 @@ type ReleaseNotesSettings struct {
 	TemplateFilename string              `toml:"template_filename"`
 	Groups           []ReleaseNotesGroup `toml:"groups"`
 
+	// An explicit Git commit range (fromRef..toRef) to collect the changes from,
+	// overriding the range from the previous tag to the tag/commitish.
+	// It's a Go template with the same context as name_template, e.g. "v1.1.0..{{ .Tag }}".
+	// Both refs must exist, except for a toRef that is the tag not yet created, which resolves to the commitish.
+	ChangelogRange string `toml:"changelog_range"`
+
+	// Only include commits touching these paths in the release notes, e.g. ["modules/foo"] in a monorepo.
+	// The paths are Git pathspecs relative to the project dir.
+	IncludePaths []string `toml:"include_paths"`
+
+	// Fail the release if the generated release notes have no changes,
+	// which usually means that the commit range is wrong.
+	FailIfEmpty bool `toml:"fail_if_empty"`
+
+	// Compose the release notes from these template sections, rendered in order.
+	// Sections rendering to only whitespace are left out.
+	// Can not be combined with template_filename.
+	Sections []ReleaseNotesSection `toml:"sections"`
+
+	// Project relative path to a changelog file (e.g. CHANGELOG.md) to insert the release notes into
+	// as a new section for the tag. The file is created if it does not exist.
+	// Committing the updated file is left to the user.
+	ChangelogFilename string `toml:"changelog_filename"`
+
+	// Also upload the release notes file as a release asset.
+	// By default it is only used as the release body.
+	Upload bool `toml:"upload"`
+
+	// Group the changes by conventional commit type when no groups are set,
+	// e.g. "feat(api): Add foo" into "Features" and "fix: Fix bar" into "Bug fixes",
+	// with breaking changes first.
+	ConventionalCommits bool `toml:"conventional_commits"`
+
+	// The repository URL used to link pull requests in the release notes as <repository_url>/pull/<number>,
+	// e.g. https://github.com/gohugoio/hugo.
+	// Defaults to the GitHub repository for the github release type without a base_url.
+	RepositoryURL string `toml:"repository_url"`
+
+	// Add a "Thanks to" section with the usernames of the contributors to the release notes.
+	// The usernames are resolved by the release client, e.g. the GitHub login.
+	Contributors bool `toml:"contributors"`
+
 	// Can be used to collapse releases with a few number (less than threshold) of changes into one title.
 	ShortThreshold int    `toml:"short_threshold"`
 	ShortTitle     string `toml:"short_title"`
@@ -103,6 +453,36 @@ func (g *ReleaseNotesSettings) Init() error {
 			return fmt.Errorf("[%d]: %v", i, err)
 		}
 	}
+	if g.ChangelogRange != "" && !strings.Contains(g.ChangelogRange, "..") {
+		return fmt.Errorf("release_notes_settings: changelog_range must be on the form fromRef..toRef, got %q", g.ChangelogRange)
+	}
+	if len(g.Sections) > 0 && g.TemplateFilename != "" {
+		return fmt.Errorf("release_notes_settings: sections and template_filename can not both be set")
+	}
+	for i := range g.Sections {
+		if err := g.Sections[i].Init(); err != nil {
+			return fmt.Errorf("[%d]: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// ReleaseNotesSection is a part of the release notes.
+// It is a Go template with the release notes context, set inline or in a file.
+// The default release notes template can be included with {{ template "release-notes" . }}.
+type ReleaseNotesSection struct {
+	// Project relative path to the template file.
+	Filename string `toml:"filename"`
+
+	// Inline template.
+	Template string `toml:"template"`
+}
+
+func (s *ReleaseNotesSection) Init() error {
+	what := "release_notes_settings.sections"
+	if (s.Filename == "") == (s.Template == "") {
+		return fmt.Errorf("%s: exactly one of filename and template must be set", what)
+	}
 	return nil
 }
 
@@ -112,13 +492,51 @@ type ReleaseNotesGroup struct {
 	Ignore  bool   `toml:"ignore"`
 	Ordinal int    `toml:"ordinal"`
 
+	// Match changes with these conventional commit types, e.g. ["feat"] for "feat(api): Add foo".
+	Types []string `toml:"types"`
+
+	// Match breaking changes only, marked with a "!" after the conventional commit type/scope
+	// or a "BREAKING CHANGE:" footer.
+	Breaking bool `toml:"breaking"`
+
+	// Group the changes further by the conventional commit scope, e.g. "api" in "feat(api): Add foo".
+	GroupByScope bool `toml:"group_by_scope"`
+
 	RegexpCompiled matchers.Matcher `toml:"-"`
 }
 
+// Match reports whether a change with the given subject, conventional commit type
+// and breaking change marker belongs to this group.
+// All of regexp, types and breaking must match if set.
+func (g ReleaseNotesGroup) Match(subject, typ string, breaking bool) bool {
+	if g.Breaking && !breaking {
+		return false
+	}
+	if len(g.Types) > 0 {
+		var found bool
+		for _, t := range g.Types {
+			if t == typ {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return g.RegexpCompiled == nil || g.RegexpCompiled.Match(subject)
+}
+
 func (g *ReleaseNotesGroup) Init() error {
 	what := "release.release_settings.group"
+	for i, t := range g.Types {
+		g.Types[i] = strings.ToLower(strings.TrimSpace(t))
+	}
 	if g.Regexp == "" {
-		return fmt.Errorf("%s: regexp is not set", what)
+		if len(g.Types) == 0 && !g.Breaking {
+			return fmt.Errorf("%s: one of regexp, types or breaking must be set", what)
+		}
+		return nil
 	}
 
 	if !strings.HasPrefix(g.Regexp, "(?") {
@@ -139,7 +557,19 @@ func (g *ReleaseNotesGroup) Init() error {
 
 }
 
-func (r *ReleaseSettings) Init() error {
+// Values for ReleaseSettings.ChecksumMode.
+const (
+	ChecksumModeCombined = "combined"
+	ChecksumModePerFile  = "per-file"
+)
+
+// Values for ReleaseSettings.OnExisting.
+const (
+	OnExistingFail    = "fail"
+	OnExistingReplace = "replace"
+)
+
+func (r *ReleaseSettings) Init(projectDir string) error {
 	what := "release.release_settings"
 	if r.Type == "" {
 		return fmt.Errorf("%s: release type is not set", what)
@@ -150,6 +580,31 @@ func (r *ReleaseSettings) Init() error {
 		return fmt.Errorf("%s: %v", what, err)
 	}
 
+	if r.TypeParsed == releasetypes.S3 && r.S3Settings.Bucket == "" {
+		return fmt.Errorf("%s: s3_settings: bucket must be set for the s3 release type", what)
+	}
+
+	if r.S3Settings.LatestKeyTemplate != "" {
+		// Catch any invalid field references early.
+		data := LatestKeyContext{Project: "hugo", Tag: "v1.2.0", Version: "1.2.0", Name: "file.txt"}
+		if _, err := templ.Sprintt(r.S3Settings.LatestKeyTemplate, data); err != nil {
+			return fmt.Errorf("%s: s3_settings: latest_key_template: %v", what, err)
+		}
+	}
+
+	if r.ReleaseNotesSettings.RepositoryURL == "" && r.TypeParsed == releasetypes.GitHub && r.BaseURL == "" && r.RepositoryOwner != "" && r.Repository != "" {
+		r.ReleaseNotesSettings.RepositoryURL = fmt.Sprintf("https://github.com/%s/%s", r.RepositoryOwner, r.Repository)
+	}
+
+	if len(r.ReleaseNotesSettings.Groups) == 0 && r.ReleaseNotesSettings.ConventionalCommits {
+		r.ReleaseNotesSettings.Groups = []ReleaseNotesGroup{
+			{Title: "Breaking changes", Breaking: true},
+			{Title: "Features", Types: []string{"feat"}},
+			{Title: "Bug fixes", Types: []string{"fix"}},
+			{Title: "Other changes", Regexp: ".*"},
+		}
+	}
+
 	if len(r.ReleaseNotesSettings.Groups) == 0 {
 		// Add a default group matching all.
 		r.ReleaseNotesSettings.Groups = []ReleaseNotesGroup{
@@ -164,7 +619,109 @@ func (r *ReleaseSettings) Init() error {
 		return fmt.Errorf("%s: %v", what, err)
 	}
 
+	if err := r.SigningSettings.Init(projectDir); err != nil {
+		return fmt.Errorf("%s: %v", what, err)
+	}
+
+	if err := r.HTTPSettings.Init(projectDir); err != nil {
+		return fmt.Errorf("%s: %v", what, err)
+	}
+
+	if r.UploadConcurrency < 0 {
+		return fmt.Errorf("%s: upload_concurrency must not be negative", what)
+	}
+
+	if r.MaxAssetSize < 0 {
+		return fmt.Errorf("%s: max_asset_size must not be negative", what)
+	}
+
+	switch r.ChecksumMode {
+	case "", ChecksumModeCombined, ChecksumModePerFile:
+	default:
+		return fmt.Errorf("%s: checksum_mode must be one of %q or %q, got %q", what, ChecksumModeCombined, ChecksumModePerFile, r.ChecksumMode)
+	}
+	if r.ChecksumMode == ChecksumModePerFile && r.SeparateChecksumFiles {
+		return fmt.Errorf("%s: separate_checksum_files can not be combined with checksum_mode %q", what, ChecksumModePerFile)
+	}
+
+	switch r.OnExisting {
+	case "", OnExistingFail, OnExistingReplace:
+	default:
+		return fmt.Errorf("%s: on_existing must be one of %q or %q, got %q", what, OnExistingFail, OnExistingReplace, r.OnExisting)
+	}
+
+	if r.ChecksumFilename != "" {
+		if _, err := templ.Parse(r.ChecksumFilename); err != nil {
+			return fmt.Errorf("%s: checksum_filename: %v", what, err)
+		}
+	}
+
+	if r.ChecksumLineTemplate != "" {
+		if r.ChecksumLineTemplateCompiled, err = templ.Parse(r.ChecksumLineTemplate); err != nil {
+			return fmt.Errorf("%s: checksum_line_template: %v", what, err)
+		}
+		// Catch any invalid field references early.
+		data := struct{ Hash, Name, Algorithm string }{"abc", "file.txt", "sha256"}
+		if err := r.ChecksumLineTemplateCompiled.Execute(io.Discard, data); err != nil {
+			return fmt.Errorf("%s: checksum_line_template: %v", what, err)
+		}
+	}
+
+	r.ChecksumAlgorithmsParsed = nil
+	seen := make(map[checksumalgos.Algorithm]bool)
+	for _, s := range r.ChecksumAlgorithms {
+		a, err := checksumalgos.Parse(s)
+		if err != nil {
+			return fmt.Errorf("%s: checksum_algorithms: %v", what, err)
+		}
+		if seen[a] {
+			return fmt.Errorf("%s: checksum_algorithms: duplicate algorithm %q", what, s)
+		}
+		seen[a] = true
+		r.ChecksumAlgorithmsParsed = append(r.ChecksumAlgorithmsParsed, a)
+	}
+	if len(r.ChecksumAlgorithmsParsed) == 0 {
+		r.ChecksumAlgorithmsParsed = []checksumalgos.Algorithm{checksumalgos.SHA256}
+	}
+
+	// The mirrors may be shared with other releases via the global release settings.
+	r.Mirrors = append([]ReleaseSettings(nil), r.Mirrors...)
+	for i := range r.Mirrors {
+		if err := r.initMirror(projectDir, &r.Mirrors[i]); err != nil {
+			return fmt.Errorf("%s: mirrors: %v", what, err)
+		}
+	}
+
 	return nil
 }
 
+// initMirror applies the settings in r to the unset settings in m,
+// except for the target settings, and initializes it.
+func (r ReleaseSettings) initMirror(projectDir string, m *ReleaseSettings) error {
+	if len(m.Mirrors) > 0 {
+		return fmt.Errorf("mirrors can not be nested")
+	}
+	if m.Type == "" {
+		return fmt.Errorf("release type is not set")
+	}
+
+	target := *m
+	parent := r
+	parent.Mirrors = nil
+	shallowMerge(m, parent)
+	shallowMerge(&m.HTTPSettings, parent.HTTPSettings)
+
+	// These belong to the target.
+	m.Repository = target.Repository
+	m.RepositoryOwner = target.RepositoryOwner
+	m.BaseURL = target.BaseURL
+	m.S3Settings = target.S3Settings
+
+	// These are shared with the parent release.
+	m.ReleaseNotesSettings = parent.ReleaseNotesSettings
+	m.SigningSettings = SigningSettings{}
+
+	return m.Init(projectDir)
+}
+
 type Releases []Release
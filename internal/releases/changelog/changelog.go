@@ -1,6 +1,7 @@
 package changelog
 
 import (
+	"errors"
 	"fmt"
 	"os/exec"
 	"regexp"
@@ -47,18 +48,84 @@ func GroupByTitleFunc(g Changes, f func(Change) (string, int, bool)) ([]TitleCha
 
 }
 
+// GroupByScope groups g by Change.Scope, sorted by scope with any changes without a scope last.
+func GroupByScope(g Changes) []ScopeChanges {
+	var sc []ScopeChanges
+	for _, gi := range g {
+		idx := -1
+		for j, s := range sc {
+			if s.Scope == gi.Scope {
+				idx = j
+				break
+			}
+		}
+		if idx == -1 {
+			sc = append(sc, ScopeChanges{Scope: gi.Scope})
+			idx = len(sc) - 1
+		}
+		sc[idx].Changes = append(sc[idx].Changes, gi)
+	}
+
+	sort.SliceStable(sc, func(i, j int) bool {
+		if sc[i].Scope == "" || sc[j].Scope == "" {
+			return sc[j].Scope == "" && sc[i].Scope != ""
+		}
+		return sc[i].Scope < sc[j].Scope
+	})
+
+	return sc
+}
+
+// Usernames returns the sorted and deduplicated usernames of the changes in g.
+// Changes without a resolved username are skipped.
+func Usernames(g []TitleChanges) []string {
+	seen := make(map[string]bool)
+	var usernames []string
+	for _, tc := range g {
+		for _, change := range tc.Changes {
+			if change.Username == "" || seen[change.Username] {
+				continue
+			}
+			seen[change.Username] = true
+			usernames = append(usernames, change.Username)
+		}
+	}
+	sort.Strings(usernames)
+	return usernames
+}
+
 // Change represents a git commit.
 type Change struct {
 	// Fetched from git log.
-	Hash    string
-	Author  string
-	Subject string
-	Body    string
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Subject string `json:"subject"`
+	Body    string `json:"body,omitempty"`
+
+	Issues []int `json:"issues,omitempty"`
+
+	// Subject without the pull request suffix, e.g. "Fix foo" for "Fix foo (#1234)".
+	// Same as Subject if there is no pull request number.
+	SubjectWithoutPR string `json:"subject_without_pr,omitempty"`
 
-	Issues []int
+	// The pull request number from a squash merge subject, e.g. 1234 in "Fix foo (#1234)".
+	PR int `json:"pr,omitempty"`
+
+	// The link to the pull request, only set if Options.RepositoryURL is set.
+	PRURL string `json:"pr_url,omitempty"`
+
+	// The type of a conventional commit subject, lower case, e.g. "feat" in "feat(api): Add foo".
+	Type string `json:"type,omitempty"`
+
+	// The scope of a conventional commit subject, e.g. "api" in "feat(api): Add foo".
+	Scope string `json:"scope,omitempty"`
+
+	// Whether this is a breaking change, marked with a "!" after the type/scope
+	// or a "BREAKING CHANGE:" footer in the body.
+	Breaking bool `json:"breaking,omitempty"`
 
 	// Resolved from GitHub.
-	Username string
+	Username string `json:"username,omitempty"`
 }
 
 // Changes represents a list of git commits.
@@ -76,30 +143,84 @@ type Options struct {
 	Tag       string
 	Commitish string
 	RepoPath  string
+
+	// From and To, if set, override the range detected from PrevTag, Tag and Commitish.
+	// Both must be set and be valid Git refs, except that To may be Tag before it's created,
+	// which then resolves to Commitish.
+	From string
+	To   string
+
+	// If set, used to create the pull request links, e.g. https://github.com/gohugoio/hugo.
+	RepositoryURL string
+
+	// Conventional commit types to recognize in addition to ConventionalTypes,
+	// e.g. the types used in the release notes groups.
+	Types []string
+
+	// If set, only include commits touching these paths (Git pathspecs, e.g. "cmd/foo"),
+	// relative to RepoPath or the current directory.
+	IncludePaths []string
 }
 
 // TitleChanges represents a list of changes grouped by title.
 type TitleChanges struct {
-	Title   string
-	Changes Changes
+	Title   string  `json:"title"`
+	Changes Changes `json:"changes"`
+
+	// Changes grouped by scope, only set if the group is configured to do so.
+	Scopes []ScopeChanges `json:"scopes,omitempty"`
 
 	ordinal int
 }
 
+// ScopeChanges represents a list of changes with the same scope.
+type ScopeChanges struct {
+	Scope   string  `json:"scope"`
+	Changes Changes `json:"changes"`
+}
+
 type collector struct {
 	opts Options
 }
 
 func (c *collector) collect() (Changes, error) {
-	log, err := gitLog(c.opts.RepoPath, c.opts.PrevTag, c.opts.Tag, c.opts.Commitish)
+	var (
+		log string
+		err error
+	)
+	if c.opts.From != "" || c.opts.To != "" {
+		to := c.opts.To
+		if to != "" && to == c.opts.Tag && c.opts.Commitish != "" {
+			// The tag is usually created when the release is published.
+			exists, err := gitRefExists(c.opts.RepoPath, to)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				to = c.opts.Commitish
+			}
+		}
+		log, err = gitLogRange(c.opts.RepoPath, c.opts.From, to, c.opts.IncludePaths...)
+	} else {
+		log, err = gitLog(c.opts.RepoPath, c.opts.PrevTag, c.opts.Tag, c.opts.Commitish, c.opts.IncludePaths...)
+	}
 	if err != nil {
 		return nil, err
 	}
-	g, err := gitLogToGitInfos(log)
+	g, err := gitLogToGitInfos(log, c.opts.Types)
 	if err != nil {
 		return nil, err
 	}
 
+	if c.opts.RepositoryURL != "" {
+		repoURL := strings.TrimSuffix(c.opts.RepositoryURL, "/")
+		for i, gi := range g {
+			if gi.PR != 0 {
+				g[i].PRURL = fmt.Sprintf("%s/pull/%d", repoURL, gi.PR)
+			}
+		}
+	}
+
 	if c.opts.ResolveUserName != nil {
 		for i, gi := range g {
 			username, err := c.opts.ResolveUserName(gi.Hash, gi.Author)
@@ -126,7 +247,7 @@ func git(repo string, args ...string) (string, error) {
 	return string(out), nil
 }
 
-func gitLog(repo, prevTag, tag, commitish string) (string, error) {
+func gitLog(repo, prevTag, tag, commitish string, paths ...string) (string, error) {
 	var err error
 	if prevTag != "" {
 		exists, err := gitTagExists(repo, prevTag)
@@ -168,7 +289,31 @@ func gitLog(repo, prevTag, tag, commitish string) (string, error) {
 		}
 	}
 
+	return gitLogFromTo(repo, from, to, paths...)
+}
+
+// gitLogRange is gitLog with an explicit range, validating that both refs exist.
+func gitLogRange(repo, from, to string, paths ...string) (string, error) {
+	for _, ref := range []string{from, to} {
+		if ref == "" {
+			return "", fmt.Errorf("invalid range %q: both refs must be set", from+".."+to)
+		}
+		exists, err := gitRefExists(repo, ref)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return "", fmt.Errorf("ref %q does not exist", ref)
+		}
+	}
+	return gitLogFromTo(repo, from, to, paths...)
+}
+
+func gitLogFromTo(repo, from, to string, paths ...string) (string, error) {
 	args := []string{"log", "--pretty=format:%x1e%h%x1f%aE%x1f%s%x1f%b", "--abbrev-commit", from + ".." + to}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
 
 	log, err := git(repo, args...)
 	if err != nil {
@@ -178,12 +323,16 @@ func gitLog(repo, prevTag, tag, commitish string) (string, error) {
 	return log, err
 }
 
-func gitLogToGitInfos(log string) (Changes, error) {
+func gitLogToGitInfos(log string, extraTypes []string) (Changes, error) {
 	var g Changes
 	log = strings.Trim(log, "\n\x1e'")
 	entries := strings.Split(log, "\x1e")
 
 	for _, entry := range entries {
+		if entry == "" {
+			// No commits in range.
+			continue
+		}
 		items := strings.Split(entry, "\x1f")
 		var gi Change
 
@@ -195,6 +344,7 @@ func gitLogToGitInfos(log string) (Changes, error) {
 		}
 		if len(items) > 2 {
 			gi.Subject = items[2]
+			gi.SubjectWithoutPR, gi.PR = parsePR(gi.Subject)
 		}
 		if len(items) > 3 {
 			gi.Body = items[3]
@@ -202,6 +352,7 @@ func gitLogToGitInfos(log string) (Changes, error) {
 			// Parse issues.
 			gi.Issues = parseIssues(gi.Body)
 		}
+		gi.Type, gi.Scope, gi.Breaking = parseConventionalCommit(gi.Subject, gi.Body, extraTypes)
 
 		g = append(g, gi)
 	}
@@ -227,6 +378,32 @@ func gitTagExists(repo, tag string) (bool, error) {
 	return false, nil
 }
 
+func gitRefExists(repo, ref string) (bool, error) {
+	args := []string{"rev-parse", "--verify", "--quiet", ref + "^{commit}"}
+	if repo != "" {
+		args = append([]string{"-C", repo}, args...)
+	}
+	cmd := exec.Command("git", args...)
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return false, nil
+		}
+		return false, fmt.Errorf("git failed: %q (%q)", err, args)
+	}
+	return true, nil
+}
+
+// ShortSHA returns the abbreviated commit SHA of ref in the Git repository in repo,
+// or an empty string if ref does not exist.
+func ShortSHA(repo, ref string) (string, error) {
+	exists, err := gitRefExists(repo, ref)
+	if err != nil || !exists {
+		return "", err
+	}
+	return gitShort(repo, "rev-parse", "--short", ref+"^{commit}")
+}
+
 func gitVersionTagBefore(repo, ref string) (string, error) {
 	if strings.HasPrefix(ref, "v") {
 		ref += "^"
@@ -234,6 +411,63 @@ func gitVersionTagBefore(repo, ref string) (string, error) {
 	return gitShort(repo, "describe", "--tags", "--abbrev=0", "--always", "--match", "v[0-9]*", ref)
 }
 
+var (
+	conventionalRe   = regexp.MustCompile(`^(\w+)(?:\(([^)]+)\))?(!)?:`)
+	breakingFooterRe = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: `)
+)
+
+// ConventionalTypes are the conventional commit types recognized without any configuration.
+var ConventionalTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+// parseConventionalCommit returns the type, scope and whether it's a breaking change
+// of a conventional commit, e.g. "feat(api)!: Remove foo".
+// Only ConventionalTypes and extraTypes are recognized, so e.g. "releases: Add foo" is not a conventional commit.
+// A "BREAKING CHANGE:" footer in body also marks the change as breaking.
+func parseConventionalCommit(subject, body string, extraTypes []string) (typ, scope string, breaking bool) {
+	if m := conventionalRe.FindStringSubmatch(subject); m != nil && isConventionalType(strings.ToLower(m[1]), extraTypes) {
+		typ = strings.ToLower(m[1])
+		scope = strings.TrimSpace(m[2])
+		breaking = m[3] == "!"
+	}
+	if !breaking {
+		breaking = breakingFooterRe.MatchString(body)
+	}
+	return
+}
+
+func isConventionalType(typ string, extraTypes []string) bool {
+	for _, types := range [][]string{ConventionalTypes, extraTypes} {
+		for _, t := range types {
+			if t == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseScope returns the scope of a conventional commit subject, e.g. "api" in "feat(api): Add foo".
+func parseScope(subject string) string {
+	_, scope, _ := parseConventionalCommit(subject, "", nil)
+	return scope
+}
+
+var prRe = regexp.MustCompile(`\s*\(#(\d+)\)\s*$`)
+
+// parsePR parses the pull request number GitHub appends to squash merge subjects, e.g. "Fix foo (#1234)",
+// and returns the subject without it.
+func parsePR(subject string) (string, int) {
+	m := prRe.FindStringSubmatchIndex(subject)
+	if m == nil {
+		return subject, 0
+	}
+	pr, err := strconv.Atoi(subject[m[2]:m[3]])
+	if err != nil {
+		return subject, 0
+	}
+	return subject[:m[0]], pr
+}
+
 var issueRe = regexp.MustCompile(`(?i)(?:Updates?|Closes?|Fix.*|See) #(\d+)`)
 
 func parseIssues(body string) []int {
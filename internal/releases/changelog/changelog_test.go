@@ -1,6 +1,7 @@
 package changelog
 
 import (
+	"encoding/json"
 	"os"
 	"testing"
 
@@ -32,7 +33,7 @@ func TestGetOps(t *testing.T) {
 	c.Assert(err, qt.IsNil)
 	c.Assert(log, qt.Contains, "Shuffle chunked builds")
 
-	infos, err := gitLogToGitInfos(log)
+	infos, err := gitLogToGitInfos(log, nil)
 	c.Assert(err, qt.IsNil)
 	c.Assert(len(infos), qt.Equals, 3)
 
@@ -75,3 +76,120 @@ func TestGetVersionTagBefore(t *testing.T) {
 	}
 
 }
+
+func TestParseScope(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(parseScope("feat(api): Add foo"), qt.Equals, "api")
+	c.Assert(parseScope("fix(cli)!: Remove bar"), qt.Equals, "cli")
+	c.Assert(parseScope("feat: Add foo"), qt.Equals, "")
+	c.Assert(parseScope("Add foo (api)"), qt.Equals, "")
+}
+
+func TestParseConventionalCommit(t *testing.T) {
+	c := qt.New(t)
+
+	for _, test := range []struct {
+		subject, body string
+		typ, scope    string
+		breaking      bool
+	}{
+		{"feat(api): Add foo", "", "feat", "api", false},
+		{"fix: Fix bar", "", "fix", "", false},
+		{"Feat!: Remove foo", "", "feat", "", true},
+		{"refactor(cli)!: Rename flags", "", "refactor", "cli", true},
+		{"feat: Add foo", "Some text.\n\nBREAKING CHANGE: The foo flag is gone.", "feat", "", true},
+		{"Add foo", "", "", "", false},
+		{"Add foo: bar", "", "", "", false},
+		{"releases: Add foo", "", "", "", false},
+		{"releasecmd(s3)!: Remove foo", "", "", "", false},
+		{"deps: Update foo", "", "deps", "", false},
+		{"chore(deps): Update foo", "", "chore", "deps", false},
+	} {
+		typ, scope, breaking := parseConventionalCommit(test.subject, test.body, []string{"deps"})
+		c.Assert(typ, qt.Equals, test.typ, qt.Commentf(test.subject))
+		c.Assert(scope, qt.Equals, test.scope, qt.Commentf(test.subject))
+		c.Assert(breaking, qt.Equals, test.breaking, qt.Commentf(test.subject))
+	}
+}
+
+func TestParsePR(t *testing.T) {
+	c := qt.New(t)
+
+	for _, test := range []struct {
+		subject, expectSubjectWithoutPR string
+		pr                              int
+	}{
+		{"Fix foo (#1234)", "Fix foo", 1234},
+		{"feat(api): Add foo (#56) ", "feat(api): Add foo", 56},
+		{"Fix foo (#12) and bar", "Fix foo (#12) and bar", 0},
+		{"Fix foo #12", "Fix foo #12", 0},
+		{"Fix foo", "Fix foo", 0},
+	} {
+		subjectWithoutPR, pr := parsePR(test.subject)
+		c.Assert(subjectWithoutPR, qt.Equals, test.expectSubjectWithoutPR)
+		c.Assert(pr, qt.Equals, test.pr)
+	}
+}
+
+func TestGroupByScope(t *testing.T) {
+	c := qt.New(t)
+
+	changes := Changes{
+		{Hash: "a", Scope: "cli"},
+		{Hash: "b"},
+		{Hash: "c", Scope: "api"},
+		{Hash: "d", Scope: "cli"},
+	}
+
+	scopes := GroupByScope(changes)
+	c.Assert(scopes, qt.DeepEquals, []ScopeChanges{
+		{Scope: "api", Changes: Changes{changes[2]}},
+		{Scope: "cli", Changes: Changes{changes[0], changes[3]}},
+		{Scope: "", Changes: Changes{changes[1]}},
+	})
+}
+
+func TestTitleChangesJSON(t *testing.T) {
+	c := qt.New(t)
+
+	groups := []TitleChanges{
+		{Title: "Features", Changes: Changes{{Hash: "a", Author: "Jane", Subject: "feat: Add foo", Type: "feat", Username: "jane"}}},
+	}
+
+	b, err := json.Marshal(groups)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b), qt.Equals, `[{"title":"Features","changes":[{"hash":"a","author":"Jane","subject":"feat: Add foo","type":"feat","username":"jane"}]}]`)
+}
+
+func TestUsernames(t *testing.T) {
+	c := qt.New(t)
+
+	groups := []TitleChanges{
+		{Title: "Features", Changes: Changes{{Hash: "a", Username: "jane"}, {Hash: "b"}}},
+		{Title: "Bug fixes", Changes: Changes{{Hash: "c", Username: "bep"}, {Hash: "d", Username: "jane"}}},
+	}
+
+	c.Assert(Usernames(groups), qt.DeepEquals, []string{"bep", "jane"})
+	c.Assert(Usernames(nil), qt.IsNil)
+}
+
+func TestGitLogToGitInfosPR(t *testing.T) {
+	c := qt.New(t)
+
+	infos, err := gitLogToGitInfos("\x1eabc123\x1fjane@example.org\x1ffeat: Add foo (#1234)\x1f", nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(infos, qt.HasLen, 1)
+	c.Assert(infos[0].Subject, qt.Equals, "feat: Add foo (#1234)")
+	c.Assert(infos[0].SubjectWithoutPR, qt.Equals, "feat: Add foo")
+	c.Assert(infos[0].PR, qt.Equals, 1234)
+	c.Assert(infos[0].Type, qt.Equals, "feat")
+}
+
+func TestGitLogToGitInfosEmpty(t *testing.T) {
+	c := qt.New(t)
+
+	infos, err := gitLogToGitInfos("", nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(infos, qt.HasLen, 0)
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// ChangelogMarker marks where new sections are inserted in the changelog file.
+const ChangelogMarker = "<!-- hugoreleaser:changelog -->"
+
+// UpdateChangelogFile inserts notes as a new section for tag right below
+// ChangelogMarker in filename, creating the file if it does not exist.
+// Any headings in notes, outside of fenced code blocks, are moved one level down to fit below the tag heading.
+// It returns false if the file already has a section for tag.
+func UpdateChangelogFile(filename, tag string, notes []byte) (bool, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+		content = []byte("# Changelog\n\n" + ChangelogMarker + "\n")
+	}
+
+	sectionMarker := fmt.Sprintf("<!-- hugoreleaser:%s -->", tag)
+	if bytes.Contains(content, []byte(sectionMarker)) {
+		return false, nil
+	}
+
+	idx := bytes.Index(content, []byte(ChangelogMarker))
+	if idx == -1 {
+		return false, fmt.Errorf("%q: missing %s marker, add it where new releases should be inserted", filename, ChangelogMarker)
+	}
+	idx += len(ChangelogMarker)
+
+	var buf bytes.Buffer
+	buf.Write(content[:idx])
+	fmt.Fprintf(&buf, "\n\n%s\n## %s\n\n", sectionMarker, tag)
+	var fence []byte
+	for i, line := range bytes.Split(bytes.TrimSpace(notes), []byte("\n")) {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		// Lines starting with a # inside fenced code blocks, e.g. shell comments, are not headings.
+		if trimmed := bytes.TrimLeft(line, " "); bytes.HasPrefix(trimmed, []byte("```")) || bytes.HasPrefix(trimmed, []byte("~~~")) {
+			if fence == nil {
+				fence = trimmed[:3]
+			} else if bytes.HasPrefix(trimmed, fence) {
+				fence = nil
+			}
+		} else if fence == nil && bytes.HasPrefix(line, []byte("#")) {
+			buf.WriteString("#")
+		}
+		buf.Write(line)
+	}
+	buf.Write(content[idx:])
+
+	return true, os.WriteFile(filename, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestUpdateChangelogFile(t *testing.T) {
+	c := qt.New(t)
+
+	filename := filepath.Join(t.TempDir(), "CHANGELOG.md")
+
+	updated, err := UpdateChangelogFile(filename, "v1.1.0", []byte("## Fixes\n\n* Fix a bug\n"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(updated, qt.IsTrue)
+	updated, err = UpdateChangelogFile(filename, "v1.2.0", []byte("## Features\n\n* Add a feature\n\n"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(updated, qt.IsTrue)
+
+	// Running it again for the same tag does nothing.
+	updated, err = UpdateChangelogFile(filename, "v1.2.0", []byte("## Features\n\n* Add a feature\n\n"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(updated, qt.IsFalse)
+
+	b, err := os.ReadFile(filename)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b), qt.Equals, `# Changelog
+
+<!-- hugoreleaser:changelog -->
+
+<!-- hugoreleaser:v1.2.0 -->
+## v1.2.0
+
+### Features
+
+* Add a feature
+
+<!-- hugoreleaser:v1.1.0 -->
+## v1.1.0
+
+### Fixes
+
+* Fix a bug
+`)
+
+	// Lines starting with # in fenced code blocks are not headings.
+	filename = filepath.Join(t.TempDir(), "CHANGELOG.md")
+	notes := "## Notes\n\n```bash\n# Install\ngo install foo\n```\n\n## Fixes\n"
+	_, err = UpdateChangelogFile(filename, "v1.3.0", []byte(notes))
+	c.Assert(err, qt.IsNil)
+	b, err = os.ReadFile(filename)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b), qt.Contains, "### Notes\n\n```bash\n# Install\ngo install foo\n```\n\n### Fixes\n")
+
+	c.Assert(os.WriteFile(filename, []byte("# Changelog\n"), 0o644), qt.IsNil)
+	_, err = UpdateChangelogFile(filename, "v1.2.0", []byte("## Features\n"))
+	c.Assert(err, qt.ErrorMatches, `.*missing <!-- hugoreleaser:changelog --> marker.*`)
+}
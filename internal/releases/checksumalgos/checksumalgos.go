@@ -0,0 +1,82 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checksumalgos
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/gohugoio/hugoreleaser/internal/common/mapsh"
+)
+
+const (
+	InvalidAlgorithm Algorithm = iota
+	SHA256
+	SHA512
+)
+
+var algorithmString = map[Algorithm]string{
+	// The string values is what users can specify in the config.
+	SHA256: "sha256",
+	SHA512: "sha512",
+}
+
+var stringAlgorithm = map[string]Algorithm{}
+
+func init() {
+	for k, v := range algorithmString {
+		stringAlgorithm[v] = k
+	}
+}
+
+// Parse parses a string into an Algorithm.
+func Parse(s string) (Algorithm, error) {
+	a := stringAlgorithm[strings.ToLower(s)]
+	if a == InvalidAlgorithm {
+		return a, fmt.Errorf("invalid checksum algorithm %q, must be one of %s", s, mapsh.KeysSorted(algorithmString))
+	}
+	return a, nil
+}
+
+// MustParse parses a string into an Algorithm and panics if it fails.
+func MustParse(s string) Algorithm {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Algorithm represents a hash algorithm used to create checksums.
+type Algorithm int
+
+func (a Algorithm) String() string {
+	return algorithmString[a]
+}
+
+// New returns a new hash.Hash for the algorithm.
+func (a Algorithm) New() hash.Hash {
+	switch a {
+	case SHA256:
+		return sha256.New()
+	case SHA512:
+		return sha512.New()
+	default:
+		panic(fmt.Sprintf("invalid checksum algorithm %d", a))
+	}
+}
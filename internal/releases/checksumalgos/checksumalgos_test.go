@@ -0,0 +1,37 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checksumalgos
+
+import (
+	"encoding/hex"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestAlgorithm(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(MustParse("sha256"), qt.Equals, SHA256)
+	c.Assert(MustParse("SHA512").String(), qt.Equals, "sha512")
+
+	h := SHA512.New()
+	h.Write([]byte("hello"))
+	c.Assert(hex.EncodeToString(h.Sum(nil)), qt.HasLen, 128)
+
+	_, err := Parse("md5")
+	c.Assert(err, qt.ErrorMatches, `invalid checksum algorithm "md5", must be one of \[sha256 sha512\]`)
+	c.Assert(func() { MustParse("md5") }, qt.PanicMatches, `invalid.*`)
+}
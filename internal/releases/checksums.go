@@ -15,48 +15,129 @@
 package releases
 
 import (
+	"bufio"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
+	"text/template"
 
 	"github.com/bep/workers"
+	"github.com/gohugoio/hugoreleaser/internal/releases/checksumalgos"
 )
 
-// CreateChecksumLines writes the SHA256 checksums as lowercase hex digits followed by
-// two spaces and then the base of filename and returns a sorted slice.
-func CreateChecksumLines(w *workers.Workforce, filenames ...string) ([]string, error) {
+// maxOpenFiles is the maximum number of files kept open at the same time
+// when creating checksums, independent of the number of workers.
+const maxOpenFiles = 64
+
+// ChecksumLine is the context used to render a line in the checksums file.
+type ChecksumLine struct {
+	// The checksum as lowercase hex digits.
+	Hash string
+	// The base of the filename.
+	Name string
+	// The hash algorithm, e.g. "sha256".
+	Algorithm string
+}
+
+// CreateChecksumLines writes the checksums for each of algorithms (SHA256 if none) as lowercase hex digits followed by
+// two spaces and then the base of filename and returns a sorted slice per algorithm, in the order of algorithms.
+// If lineTemplate is set, it's used to format each line with a ChecksumLine as context.
+// See CreateChecksums for precomputedDir.
+func CreateChecksumLines(w *workers.Workforce, lineTemplate *template.Template, precomputedDir string, algorithms []checksumalgos.Algorithm, filenames ...string) ([][]string, error) {
+	if len(algorithms) == 0 {
+		algorithms = []checksumalgos.Algorithm{checksumalgos.SHA256}
+	}
+
+	checksums, err := CreateChecksums(w, precomputedDir, algorithms, filenames...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([][]string, len(algorithms))
+	for filename, sums := range checksums {
+		for i, sum := range sums {
+			line, err := FormatChecksumLine(lineTemplate, ChecksumLine{Hash: sum, Name: filepath.Base(filename), Algorithm: algorithms[i].String()})
+			if err != nil {
+				return nil, err
+			}
+			result[i] = append(result[i], line)
+		}
+	}
+
+	for _, lines := range result {
+		sort.Strings(lines)
+	}
+
+	return result, nil
+}
+
+// CreateChecksums creates the checksums of filenames as lowercase hex digits, keyed by filename,
+// with one checksum per algorithm in the order of algorithms.
+// Each file is read once. For files below precomputedDir, SHA256 checksums precomputed with WriteChecksumFile
+// are used if up to date. An empty precomputedDir means that all checksums are computed.
+func CreateChecksums(w *workers.Workforce, precomputedDir string, algorithms []checksumalgos.Algorithm, filenames ...string) (map[string][]string, error) {
 	var mu sync.Mutex
-	var result []string
+	result := make(map[string][]string)
 
 	r, _ := w.Start(context.Background())
 
-	createChecksum := func(filename string) (string, error) {
+	openFiles := make(chan struct{}, maxOpenFiles)
+
+	createChecksums := func(filename string) ([]string, error) {
+		openFiles <- struct{}{}
+		defer func() { <-openFiles }()
+
+		checksums := make([]string, len(algorithms))
+		hashes := make([]hash.Hash, len(algorithms))
+		var writers []io.Writer
+		for i, a := range algorithms {
+			if a == checksumalgos.SHA256 && isBelowDir(precomputedDir, filename) {
+				if checksum, found := ReadChecksumFile(filename); found {
+					checksums[i] = checksum
+					continue
+				}
+			}
+			hashes[i] = a.New()
+			writers = append(writers, hashes[i])
+		}
+
+		if len(writers) == 0 {
+			return checksums, nil
+		}
+
 		f, err := os.Open(filename)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		defer f.Close()
-		h := sha256.New()
-		if _, err := io.Copy(h, f); err != nil {
-			return "", err
+		if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+			return nil, err
 		}
-		return hex.EncodeToString(h.Sum(nil)), nil
+		for i, h := range hashes {
+			if h != nil {
+				checksums[i] = hex.EncodeToString(h.Sum(nil))
+			}
+		}
+		return checksums, nil
 	}
 
 	for _, filename := range filenames {
 		filename := filename
 		r.Run(func() error {
-			checksum, err := createChecksum(filename)
+			checksums, err := createChecksums(filename)
 			if err != nil {
 				return err
 			}
 			mu.Lock()
-			result = append(result, checksum+"  "+filepath.Base(filename))
+			result[filename] = checksums
 			mu.Unlock()
 
 			return nil
@@ -67,7 +148,104 @@ func CreateChecksumLines(w *workers.Workforce, filenames ...string) ([]string, e
 		return nil, err
 	}
 
-	sort.Strings(result)
-
 	return result, nil
 }
+
+func isBelowDir(dir, filename string) bool {
+	if dir == "" {
+		return false
+	}
+	return strings.HasPrefix(filepath.Clean(filename), filepath.Clean(dir)+string(filepath.Separator))
+}
+
+// FormatChecksumLine formats line with lineTemplate, or in the sha256sum format if lineTemplate is nil.
+func FormatChecksumLine(lineTemplate *template.Template, line ChecksumLine) (string, error) {
+	if lineTemplate == nil {
+		return line.Hash + "  " + line.Name, nil
+	}
+	var buf strings.Builder
+	if err := lineTemplate.Execute(&buf, line); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ParseChecksumLines parses checksum lines as written by CreateChecksumLines with the default line format
+// and returns a map of file base name to checksum.
+// If the same file is listed more than once, e.g. for multiple algorithms, the first checksum wins.
+func ParseChecksumLines(r io.Reader) (map[string]string, error) {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		checksum, name, found := strings.Cut(line, "  ")
+		if !found {
+			return nil, fmt.Errorf("invalid checksum line %q", line)
+		}
+		if _, found := checksums[name]; !found {
+			checksums[name] = checksum
+		}
+	}
+	return checksums, scanner.Err()
+}
+
+// ChecksumFilename returns the filename of the SHA256 checksum file for filename.
+func ChecksumFilename(filename string) string {
+	return filename + ".sha256"
+}
+
+// checksumFileMetaPrefix starts the comment line in the checksum file written by WriteChecksumFile
+// with the size and modification time of the file the checksum was computed from.
+// sha256sum -c ignores comment lines.
+const checksumFileMetaPrefix = "# hugoreleaser: "
+
+// WriteChecksumFile writes checksum, the SHA256 checksum of filename, to ChecksumFilename(filename)
+// in the sha256sum format, so it doesn't need to be computed again when creating the checksums file.
+// The size and modification time of filename are stored with it, see ReadChecksumFile.
+func WriteChecksumFile(filename, checksum string) error {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	content := fmt.Sprintf("%s  %s\n%ssize=%d mtime=%d\n", checksum, filepath.Base(filename), checksumFileMetaPrefix, fi.Size(), fi.ModTime().UnixNano())
+	return os.WriteFile(ChecksumFilename(filename), []byte(content), 0o644)
+}
+
+// ReadChecksumFile reads the checksum written by WriteChecksumFile.
+// It returns false if the checksum file does not exist or if the size or modification time
+// of filename does not match the one stored in the checksum file.
+func ReadChecksumFile(filename string) (string, bool) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return "", false
+	}
+	b, err := os.ReadFile(ChecksumFilename(filename))
+	if err != nil {
+		return "", false
+	}
+	var checksum string
+	var metaFound bool
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.HasPrefix(line, checksumFileMetaPrefix) {
+			var size, mtime int64
+			if _, err := fmt.Sscanf(strings.TrimPrefix(line, checksumFileMetaPrefix), "size=%d mtime=%d", &size, &mtime); err != nil {
+				return "", false
+			}
+			if size != fi.Size() || mtime != fi.ModTime().UnixNano() {
+				return "", false
+			}
+			metaFound = true
+			continue
+		}
+		if checksum == "" {
+			checksum, _, _ = strings.Cut(strings.TrimSpace(line), "  ")
+		}
+	}
+	if !metaFound || len(checksum) != sha256.Size*2 {
+		return "", false
+	}
+	return checksum, true
+}
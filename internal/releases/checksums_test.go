@@ -19,10 +19,14 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bep/workers"
 	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser/internal/common/templ"
+	"github.com/gohugoio/hugoreleaser/internal/releases/checksumalgos"
 )
 
 func TestCreateChecksumLines(t *testing.T) {
@@ -45,9 +49,9 @@ func TestCreateChecksumLines(t *testing.T) {
 		filenames = append(filenames, filename)
 	}
 
-	checksums, err := CreateChecksumLines(w, filenames...)
+	checksums, err := CreateChecksumLines(w, nil, "", nil, filenames...)
 	c.Assert(err, qt.IsNil)
-	c.Assert(checksums, qt.DeepEquals, []string{
+	c.Assert(checksums[0], qt.DeepEquals, []string{
 		"196373310827669cb58f4c688eb27aabc40e600dc98615bd329f410ab7430cff  file6.txt",
 		"47ea70cf08872bdb4afad3432b01d963ac7d165f6b575cd72ef47498f4459a90  file3.txt",
 		"4e74512f1d8e5016f7a9d9eaebbeedb1549fed5b63428b736eecfea98292d75f  file9.txt",
@@ -60,3 +64,125 @@ func TestCreateChecksumLines(t *testing.T) {
 		"e361a57a7406adee653f1dcff660d84f0ca302907747af2a387f67821acfce33  file4.txt",
 	})
 }
+
+func TestParseChecksumLines(t *testing.T) {
+	c := qt.New(t)
+
+	checksums, err := ParseChecksumLines(strings.NewReader("abc  file1.txt\ndef  file2.txt\n\n"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(checksums, qt.DeepEquals, map[string]string{"file1.txt": "abc", "file2.txt": "def"})
+
+	// Grouped per algorithm, the first wins.
+	checksums, err = ParseChecksumLines(strings.NewReader("abc  file1.txt\n\nabcdef  file1.txt\n"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(checksums, qt.DeepEquals, map[string]string{"file1.txt": "abc"})
+
+	_, err = ParseChecksumLines(strings.NewReader("abc file1.txt"))
+	c.Assert(err, qt.ErrorMatches, `invalid checksum line "abc file1.txt"`)
+}
+
+func TestCreateChecksumLinesTemplate(t *testing.T) {
+	c := qt.New(t)
+
+	w := workers.New(runtime.NumCPU())
+
+	filename := filepath.Join(t.TempDir(), "file0.txt")
+	c.Assert(os.WriteFile(filename, []byte("hello0"), 0o644), qt.IsNil)
+
+	tmpl, err := templ.Parse("{{ .Hash }} *{{ .Name }}")
+	c.Assert(err, qt.IsNil)
+
+	checksums, err := CreateChecksumLines(w, tmpl, "", nil, filename)
+	c.Assert(err, qt.IsNil)
+	c.Assert(checksums[0], qt.DeepEquals, []string{
+		"5a936ee19a0cf3c70d8cb0006111b7a52f45ec01703e0af8cdc8c6d81ac5850c *file0.txt",
+	})
+}
+
+func TestCreateChecksumLinesAlgorithms(t *testing.T) {
+	c := qt.New(t)
+
+	w := workers.New(runtime.NumCPU())
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "file0.txt")
+	c.Assert(os.WriteFile(filename, []byte("hello0"), 0o644), qt.IsNil)
+
+	// A fake precomputed SHA256 checksum, the SHA512 checksum must still be computed.
+	precomputed := strings.Repeat("a", 64)
+	c.Assert(WriteChecksumFile(filename, precomputed), qt.IsNil)
+
+	tmpl, err := templ.Parse("{{ .Algorithm }}:{{ .Hash }}  {{ .Name }}")
+	c.Assert(err, qt.IsNil)
+
+	checksums, err := CreateChecksumLines(w, tmpl, dir, []checksumalgos.Algorithm{checksumalgos.SHA512, checksumalgos.SHA256}, filename)
+	c.Assert(err, qt.IsNil)
+	c.Assert(checksums, qt.DeepEquals, [][]string{
+		{"sha512:1fb42d3b9c0601833c23148d3e5eb6ed9f50d6af423c26bd6fdd9b36f0437010fec5bab8884e4a2a619799ce4363976b3cc6246f2c2c901863f79e3a5017ec15  file0.txt"},
+		{"sha256:" + precomputed + "  file0.txt"},
+	})
+}
+
+func TestCreateChecksums(t *testing.T) {
+	c := qt.New(t)
+
+	w := workers.New(runtime.NumCPU())
+
+	filename := filepath.Join(t.TempDir(), "file0.txt")
+	c.Assert(os.WriteFile(filename, []byte("hello0"), 0o644), qt.IsNil)
+
+	checksums, err := CreateChecksums(w, "", []checksumalgos.Algorithm{checksumalgos.SHA256}, filename)
+	c.Assert(err, qt.IsNil)
+	c.Assert(checksums, qt.DeepEquals, map[string][]string{
+		filename: {"5a936ee19a0cf3c70d8cb0006111b7a52f45ec01703e0af8cdc8c6d81ac5850c"},
+	})
+
+	line, err := FormatChecksumLine(nil, ChecksumLine{Hash: "abc", Name: "file0.txt", Algorithm: "sha256"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(line, qt.Equals, "abc  file0.txt")
+}
+
+func TestCreateChecksumLinesPrecomputed(t *testing.T) {
+	c := qt.New(t)
+
+	w := workers.New(runtime.NumCPU())
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "file0.txt")
+	c.Assert(os.WriteFile(filename, []byte("hello0"), 0o644), qt.IsNil)
+	const expected = "5a936ee19a0cf3c70d8cb0006111b7a52f45ec01703e0af8cdc8c6d81ac5850c  file0.txt"
+
+	// A fake checksum to verify that it's used.
+	precomputed := strings.Repeat("a", 64)
+	c.Assert(WriteChecksumFile(filename, precomputed), qt.IsNil)
+	b, err := os.ReadFile(ChecksumFilename(filename))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b), qt.Matches, precomputed+"  file0.txt\n# hugoreleaser: size=6 mtime=\\d+\n")
+
+	checksums, err := CreateChecksumLines(w, nil, dir, nil, filename)
+	c.Assert(err, qt.IsNil)
+	c.Assert(checksums[0], qt.DeepEquals, []string{precomputed + "  file0.txt"})
+
+	// Checksum files outside of the given dir are not trusted.
+	checksums, err = CreateChecksumLines(w, nil, "", nil, filename)
+	c.Assert(err, qt.IsNil)
+	c.Assert(checksums[0], qt.DeepEquals, []string{expected})
+	checksums, err = CreateChecksumLines(w, nil, filepath.Join(dir, "sub"), nil, filename)
+	c.Assert(err, qt.IsNil)
+	c.Assert(checksums[0], qt.DeepEquals, []string{expected})
+
+	// The file is modified after the checksum file was written.
+	modTime := time.Now().Add(time.Hour)
+	c.Assert(os.Chtimes(filename, modTime, modTime), qt.IsNil)
+	_, found := ReadChecksumFile(filename)
+	c.Assert(found, qt.IsFalse)
+
+	checksums, err = CreateChecksumLines(w, nil, dir, nil, filename)
+	c.Assert(err, qt.IsNil)
+	c.Assert(checksums[0], qt.DeepEquals, []string{expected})
+
+	// A checksum file in the sha256sum format without the size and modification time.
+	c.Assert(os.WriteFile(ChecksumFilename(filename), []byte(precomputed+"  file0.txt\n"), 0o644), qt.IsNil)
+	_, found = ReadChecksumFile(filename)
+	c.Assert(found, qt.IsFalse)
+}
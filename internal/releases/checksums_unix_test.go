@@ -0,0 +1,57 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+
+package releases
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/bep/workers"
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCreateChecksumLinesManyFiles(t *testing.T) {
+	c := qt.New(t)
+
+	const (
+		fdLimit  = 128
+		numFiles = 4 * fdLimit
+	)
+
+	tempDir := t.TempDir()
+	var filenames []string
+	for i := 0; i < numFiles; i++ {
+		filename := filepath.Join(tempDir, fmt.Sprintf("file%d.txt", i))
+		c.Assert(os.WriteFile(filename, []byte(fmt.Sprintf("hello%d", i)), 0o644), qt.IsNil)
+		filenames = append(filenames, filename)
+	}
+
+	var rlimit syscall.Rlimit
+	c.Assert(syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit), qt.IsNil)
+	c.Assert(syscall.Setrlimit(syscall.RLIMIT_NOFILE, &syscall.Rlimit{Cur: fdLimit, Max: rlimit.Max}), qt.IsNil)
+	defer syscall.Setrlimit(syscall.RLIMIT_NOFILE, &rlimit)
+
+	// More workers than the file descriptor limit.
+	w := workers.New(2 * fdLimit)
+
+	checksums, err := CreateChecksumLines(w, nil, "", nil, filenames...)
+	c.Assert(err, qt.IsNil)
+	c.Assert(checksums[0], qt.HasLen, numFiles)
+}
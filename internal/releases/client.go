@@ -2,8 +2,13 @@ package releases
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/bep/workers"
 	"github.com/gohugoio/hugoreleaser/internal/config"
 )
 
@@ -12,9 +17,181 @@ type ReleaseInfo struct {
 	Tag       string
 	Commitish string
 	Settings  config.ReleaseSettings
+
+	// Retry configures retries of temporary errors, e.g. when uploading assets.
+	Retry RetrySettings
+
+	// OnUploadProgress, if set, is called every UploadProgressInterval while a file is uploaded
+	// with UploadAssetsFileWithRetries, with the number of bytes the client has read from the file so far and its size.
+	// The count starts from 0 on every retry.
+	OnUploadProgress func(filename string, n, size int64)
 }
 
 type Client interface {
 	Release(ctx context.Context, info ReleaseInfo) (int64, error)
-	UploadAssetsFile(ctx context.Context, info ReleaseInfo, f *os.File, releaseID int64) error
+	// UploadAssetsFile uploads f to the release. The label is an optional display name
+	// for the asset, clients not supporting labels will ignore it.
+	UploadAssetsFile(ctx context.Context, info ReleaseInfo, f *os.File, label string, releaseID int64) error
+	ListAssets(ctx context.Context, info ReleaseInfo, releaseID int64) ([]Asset, error)
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string
+
+	// The client specific ID of the asset, 0 if not known.
+	ID int64
+
+	// The size in bytes, -1 if not known.
+	Size int64
+
+	// The SHA256 checksum, if known.
+	Checksum string
+}
+
+// AssetDeleter is implemented by clients that can delete assets from a release.
+type AssetDeleter interface {
+	// DeleteAsset deletes the asset from the release, identified by its ID if set, else by its name.
+	// A missing asset is not an error.
+	DeleteAsset(ctx context.Context, info ReleaseInfo, releaseID int64, asset Asset) error
+}
+
+// ReleaseFinder is implemented by clients that can look up an existing release.
+type ReleaseFinder interface {
+	// FindRelease returns the ID of the release tagged info.Tag, 0 if not found.
+	FindRelease(ctx context.Context, info ReleaseInfo) (int64, error)
+}
+
+// ReleaseURLProvider is implemented by clients that know the web URL of the releases they created.
+type ReleaseURLProvider interface {
+	// ReleaseURL returns the web URL of the release created by this client, empty if not known.
+	ReleaseURL(releaseID int64) string
+}
+
+// VerifyAssets checks that the assets in the release matches filenames exactly, by name and size (if known).
+// This catches partial uploads that somehow did not fail.
+// If existing is set, the release was created elsewhere and may have other assets, which are then not reported.
+func VerifyAssets(ctx context.Context, client Client, info ReleaseInfo, releaseID int64, existing bool, filenames ...string) error {
+	expected := make(map[string]int64)
+	for _, filename := range filenames {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		expected[filepath.Base(filename)] = fi.Size()
+	}
+
+	assets, err := client.ListAssets(ctx, info, releaseID)
+	if err != nil {
+		return fmt.Errorf("failed to list release assets: %w", err)
+	}
+
+	var problems []string
+	for _, asset := range assets {
+		size, found := expected[asset.Name]
+		if !found {
+			if !existing {
+				problems = append(problems, fmt.Sprintf("%q: unexpected", asset.Name))
+			}
+			continue
+		}
+		delete(expected, asset.Name)
+		if asset.Size >= 0 && size != asset.Size {
+			problems = append(problems, fmt.Sprintf("%q: expected size %d, got %d", asset.Name, size, asset.Size))
+		}
+	}
+	for name := range expected {
+		problems = append(problems, fmt.Sprintf("%q: missing", name))
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("release %q does not match the uploaded files:\n%s", info.Tag, strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
+
+// UploadAssetsFiles uploads the files to the release with the given ID in parallel using workforce,
+// or with at most info.Settings.UploadConcurrency uploads at a time if set.
+// The labels are looked up by filename and onUpload, if set, is called before each upload.
+// See UploadAssetsFileWithRetries.
+func UploadAssetsFiles(ctx context.Context, workforce *workers.Workforce, client Client, info ReleaseInfo, releaseID int64, labels map[string]string, onUpload func(filename, label string), filenames ...string) error {
+	if n := info.Settings.UploadConcurrency; n > 0 {
+		workforce = workers.New(n)
+	}
+	r, ctx := workforce.Start(ctx)
+
+	for _, filename := range filenames {
+		filename := filename
+		r.Run(func() error {
+			openFile := func() (*os.File, error) {
+				return os.Open(filename)
+			}
+			label := labels[filename]
+			if onUpload != nil {
+				onUpload(filename, label)
+			}
+			return UploadAssetsFileWithRetries(ctx, client, info, label, releaseID, openFile)
+		})
+	}
+
+	return r.Wait()
+}
+
+// UploadAssetsFileWithRetries is a wrapper around UploadAssetsFile that retries on temporary errors.
+func UploadAssetsFileWithRetries(ctx context.Context, client Client, info ReleaseInfo, label string, releaseID int64, openFile func() (*os.File, error)) error {
+	return withRetries(ctx, info.Retry, func() (error, bool) {
+		f, err := openFile()
+		if err != nil {
+			return err, false
+		}
+		defer f.Close()
+		uploadCtx := ctx
+		if info.OnUploadProgress != nil {
+			var stop func()
+			uploadCtx, stop, err = reportUploadProgress(ctx, f, info.OnUploadProgress)
+			if err != nil {
+				return err, false
+			}
+			defer stop()
+		}
+		err = client.UploadAssetsFile(uploadCtx, info, f, label, releaseID)
+		if err != nil && isTemporaryError(err) {
+			return err, true
+		}
+		return err, false
+	})
+
+}
+
+// ReleaseWithRetries is a wrapper around Release that retries on temporary errors.
+// If client is a ReleaseFinder, a release created by a failed attempt (e.g. on a timeout) is reused on retry.
+func ReleaseWithRetries(ctx context.Context, client Client, info ReleaseInfo) (int64, error) {
+	var (
+		releaseID int64
+		attempts  int
+	)
+	err := withRetries(ctx, info.Retry, func() (error, bool) {
+		attempts++
+		if finder, ok := client.(ReleaseFinder); ok && attempts > 1 {
+			var err error
+			releaseID, err = finder.FindRelease(ctx, info)
+			if err != nil || releaseID != 0 {
+				return err, err != nil && isTemporaryError(err)
+			}
+		}
+		var err error
+		releaseID, err = client.Release(ctx, info)
+		return err, err != nil && isTemporaryError(err)
+	})
+	return releaseID, err
+}
+
+// DeleteAssetWithRetries is a wrapper around DeleteAsset that retries on temporary errors, e.g. rate limits.
+func DeleteAssetWithRetries(ctx context.Context, client AssetDeleter, info ReleaseInfo, releaseID int64, asset Asset) error {
+	return withRetries(ctx, info.Retry, func() (error, bool) {
+		err := client.DeleteAsset(ctx, info, releaseID, asset)
+		return err, err != nil && isTemporaryError(err)
+	})
 }
@@ -0,0 +1,341 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bep/workers"
+	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+)
+
+func TestVerifyAssets(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	tempDir := t.TempDir()
+
+	writeFile := func(name, content string) string {
+		filename := filepath.Join(tempDir, name)
+		c.Assert(os.WriteFile(filename, []byte(content), 0o644), qt.IsNil)
+		return filename
+	}
+
+	upload := func(client *FakeClient, releaseID int64, filename string) {
+		f, err := os.Open(filename)
+		c.Assert(err, qt.IsNil)
+		defer f.Close()
+		c.Assert(client.UploadAssetsFile(ctx, ReleaseInfo{}, f, "", releaseID), qt.IsNil)
+	}
+
+	a := writeFile("a.tar.gz", "aaa")
+	b := writeFile("b.tar.gz", "bbb")
+	checksums := writeFile("checksums.txt", "sums")
+
+	client := &FakeClient{}
+	info := ReleaseInfo{Tag: "v1.2.0"}
+	releaseID, err := client.Release(ctx, info)
+	c.Assert(err, qt.IsNil)
+	upload(client, releaseID, a)
+	upload(client, releaseID, b)
+
+	c.Assert(VerifyAssets(ctx, client, info, releaseID, false, a, b), qt.IsNil)
+	c.Assert(VerifyAssets(ctx, client, info, releaseID, false, a, b, checksums), qt.ErrorMatches, `(?s)release "v1.2.0" does not match.*"checksums.txt": missing`)
+	c.Assert(VerifyAssets(ctx, client, info, releaseID, false, a), qt.ErrorMatches, `(?s).*"b.tar.gz": unexpected`)
+
+	// Modified after upload.
+	writeFile("b.tar.gz", "bbbb")
+	c.Assert(VerifyAssets(ctx, client, info, releaseID, false, a, b), qt.ErrorMatches, `(?s).*"b.tar.gz": expected size 4, got 3`)
+
+	// An existing release may have assets uploaded elsewhere.
+	c.Assert(VerifyAssets(ctx, client, info, releaseID, true, a), qt.IsNil)
+	c.Assert(VerifyAssets(ctx, client, info, releaseID, true, a, checksums), qt.ErrorMatches, `(?s).*"checksums.txt": missing`)
+	c.Assert(VerifyAssets(ctx, client, info, releaseID, true, b), qt.ErrorMatches, `(?s).*"b.tar.gz": expected size 4, got 3`)
+}
+
+type testDeleter struct {
+	failures int
+	deleted  []string
+}
+
+func (d *testDeleter) DeleteAsset(ctx context.Context, info ReleaseInfo, releaseID int64, asset Asset) error {
+	if d.failures > 0 {
+		d.failures--
+		return TemporaryError{errors.New("rate limited")}
+	}
+	d.deleted = append(d.deleted, asset.Name)
+	return nil
+}
+
+func TestDeleteAssetWithRetries(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	info := ReleaseInfo{Retry: RetrySettings{Set: true, MaxRetries: 2, InitialDelay: time.Millisecond}}
+
+	d := &testDeleter{failures: 2}
+	c.Assert(DeleteAssetWithRetries(ctx, d, info, 1, Asset{Name: "a.tar.gz"}), qt.IsNil)
+	c.Assert(d.deleted, qt.DeepEquals, []string{"a.tar.gz"})
+
+	d = &testDeleter{failures: 3}
+	c.Assert(DeleteAssetWithRetries(ctx, d, info, 1, Asset{Name: "a.tar.gz"}), qt.ErrorMatches, "rate limited")
+	c.Assert(d.deleted, qt.IsNil)
+}
+
+// testReleaser creates the release, but fails with a temporary error on the first attempt, e.g. a timeout.
+type testReleaser struct {
+	FakeClient
+	releaseCalls int
+	created      int64
+}
+
+func (r *testReleaser) Release(ctx context.Context, info ReleaseInfo) (int64, error) {
+	r.releaseCalls++
+	r.created = 42
+	if r.releaseCalls == 1 {
+		return 0, TemporaryError{errors.New("timeout")}
+	}
+	return r.created, nil
+}
+
+func (r *testReleaser) FindRelease(ctx context.Context, info ReleaseInfo) (int64, error) {
+	return r.created, nil
+}
+
+func TestReleaseWithRetriesReusesCreatedRelease(t *testing.T) {
+	c := qt.New(t)
+
+	info := ReleaseInfo{Retry: RetrySettings{Set: true, MaxRetries: 2, InitialDelay: time.Millisecond}}
+	r := &testReleaser{}
+	releaseID, err := ReleaseWithRetries(context.Background(), r, info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(releaseID, qt.Equals, int64(42))
+	c.Assert(r.releaseCalls, qt.Equals, 1)
+}
+
+type testUploader struct {
+	FakeClient
+	cancel   context.CancelFunc
+	attempts int
+}
+
+func (u *testUploader) UploadAssetsFile(ctx context.Context, info ReleaseInfo, f *os.File, label string, releaseID int64) error {
+	u.attempts++
+	u.cancel()
+	return TemporaryError{ctx.Err()}
+}
+
+type flakyUploader struct {
+	FakeClient
+	attempts int
+}
+
+func (u *flakyUploader) UploadAssetsFile(ctx context.Context, info ReleaseInfo, f *os.File, label string, releaseID int64) error {
+	u.attempts++
+	if u.attempts == 1 {
+		return fmt.Errorf("upload: %w", TemporaryError{errors.New("bad gateway")})
+	}
+	return nil
+}
+
+func TestUploadAssetsFileWithRetriesTemporaryError(t *testing.T) {
+	c := qt.New(t)
+
+	filename := filepath.Join(t.TempDir(), "a.tar.gz")
+	c.Assert(os.WriteFile(filename, []byte("aaa"), 0o644), qt.IsNil)
+
+	info := ReleaseInfo{Retry: RetrySettings{Set: true, MaxRetries: 2, InitialDelay: time.Millisecond}}
+	u := &flakyUploader{}
+	openFile := func() (*os.File, error) { return os.Open(filename) }
+	c.Assert(UploadAssetsFileWithRetries(context.Background(), u, info, "", 1, openFile), qt.IsNil)
+	c.Assert(u.attempts, qt.Equals, 2)
+}
+
+func TestUploadAssetsFileWithRetriesCancelled(t *testing.T) {
+	c := qt.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	filename := filepath.Join(t.TempDir(), "a.tar.gz")
+	c.Assert(os.WriteFile(filename, []byte("aaa"), 0o644), qt.IsNil)
+
+	info := ReleaseInfo{Retry: RetrySettings{Set: true, MaxRetries: 5, InitialDelay: time.Millisecond}}
+	u := &testUploader{cancel: cancel}
+	openFile := func() (*os.File, error) { return os.Open(filename) }
+	c.Assert(UploadAssetsFileWithRetries(ctx, u, info, "", 1, openFile), qt.ErrorIs, context.Canceled)
+	c.Assert(u.attempts, qt.Equals, 1)
+}
+
+type slowUploader struct {
+	FakeClient
+	progress chan int64
+}
+
+func (u *slowUploader) UploadAssetsFile(ctx context.Context, info ReleaseInfo, f *os.File, label string, releaseID int64) error {
+	if _, err := uploadBody(ctx, f).Read(make([]byte, 2)); err != nil {
+		return err
+	}
+	// Wait for the progress to be reported.
+	for n := range u.progress {
+		if n == 2 {
+			return nil
+		}
+	}
+	return nil
+}
+
+func TestUploadAssetsFileWithRetriesProgress(t *testing.T) {
+	c := qt.New(t)
+
+	defer func(d time.Duration) { UploadProgressInterval = d }(UploadProgressInterval)
+	UploadProgressInterval = time.Millisecond
+
+	filename := filepath.Join(t.TempDir(), "a.tar.gz")
+	c.Assert(os.WriteFile(filename, []byte("aaaa"), 0o644), qt.IsNil)
+
+	u := &slowUploader{progress: make(chan int64)}
+	var sizes []int64
+	info := ReleaseInfo{
+		OnUploadProgress: func(name string, n, size int64) {
+			c.Check(name, qt.Equals, filename)
+			sizes = append(sizes, size)
+			select {
+			case u.progress <- n:
+			default:
+			}
+		},
+	}
+	openFile := func() (*os.File, error) { return os.Open(filename) }
+	c.Assert(UploadAssetsFileWithRetries(context.Background(), u, info, "", 1, openFile), qt.IsNil)
+	c.Assert(sizes[len(sizes)-1], qt.Equals, int64(4))
+}
+
+// concurrentUploader records the peak number of concurrent uploads.
+type concurrentUploader struct {
+	FakeClient
+
+	mu      sync.Mutex
+	current int
+	peak    int
+	labels  []string
+}
+
+func (u *concurrentUploader) UploadAssetsFile(ctx context.Context, info ReleaseInfo, f *os.File, label string, releaseID int64) error {
+	u.mu.Lock()
+	u.current++
+	if u.current > u.peak {
+		u.peak = u.current
+	}
+	u.labels = append(u.labels, label)
+	u.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	u.mu.Lock()
+	u.current--
+	u.mu.Unlock()
+	return nil
+}
+
+func TestUploadAssetsFiles(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	var filenames []string
+	for _, name := range []string{"a.tar.gz", "b.tar.gz", "c.tar.gz", "d.tar.gz", "e.tar.gz", "f.tar.gz"} {
+		filename := filepath.Join(dir, name)
+		c.Assert(os.WriteFile(filename, []byte(name), 0o644), qt.IsNil)
+		filenames = append(filenames, filename)
+	}
+	labels := map[string]string{filenames[0]: "Label A"}
+
+	upload := func(uploadConcurrency int) *concurrentUploader {
+		u := &concurrentUploader{}
+		info := ReleaseInfo{Settings: config.ReleaseSettings{UploadConcurrency: uploadConcurrency}}
+		var uploaded []string
+		var mu sync.Mutex
+		onUpload := func(filename, label string) {
+			mu.Lock()
+			uploaded = append(uploaded, filename)
+			mu.Unlock()
+		}
+		c.Assert(UploadAssetsFiles(context.Background(), workers.New(6), u, info, 1, labels, onUpload, filenames...), qt.IsNil)
+		c.Assert(uploaded, qt.HasLen, len(filenames))
+		c.Assert(u.labels, qt.Contains, "Label A")
+		return u
+	}
+
+	c.Assert(upload(1).peak, qt.Equals, 1)
+	c.Assert(upload(2).peak, qt.Equals, 2)
+	// Not set, use the workforce.
+	c.Assert(upload(0).peak > 2, qt.IsTrue)
+}
+
+func TestUploadBody(t *testing.T) {
+	c := qt.New(t)
+
+	filename := filepath.Join(t.TempDir(), "a.tar.gz")
+	c.Assert(os.WriteFile(filename, []byte("aaaa"), 0o644), qt.IsNil)
+	f, err := os.Open(filename)
+	c.Assert(err, qt.IsNil)
+	defer f.Close()
+
+	// No progress reporting.
+	c.Assert(uploadBody(context.Background(), f), qt.Equals, io.ReadSeeker(f))
+
+	n := new(atomic.Int64)
+	body := uploadBody(context.WithValue(context.Background(), uploadProgressKey{}, n), f)
+	_, err = io.ReadAll(body)
+	c.Assert(err, qt.IsNil)
+	c.Assert(n.Load(), qt.Equals, int64(4))
+
+	// E.g. a client that hashes the body before sending it.
+	_, err = body.Seek(0, io.SeekStart)
+	c.Assert(err, qt.IsNil)
+	c.Assert(n.Load(), qt.Equals, int64(0))
+	_, err = body.Read(make([]byte, 2))
+	c.Assert(err, qt.IsNil)
+	c.Assert(n.Load(), qt.Equals, int64(2))
+}
+
+func TestFakeClientDeleteAsset(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	filename := filepath.Join(t.TempDir(), "a.tar.gz")
+	c.Assert(os.WriteFile(filename, []byte("aaa"), 0o644), qt.IsNil)
+
+	client := &FakeClient{}
+	f, err := os.Open(filename)
+	c.Assert(err, qt.IsNil)
+	defer f.Close()
+	c.Assert(client.UploadAssetsFile(ctx, ReleaseInfo{}, f, "", 42), qt.IsNil)
+
+	c.Assert(client.DeleteAsset(ctx, ReleaseInfo{}, 42, Asset{Name: "a.tar.gz"}), qt.IsNil)
+	// Already deleted.
+	c.Assert(client.DeleteAsset(ctx, ReleaseInfo{}, 42, Asset{Name: "a.tar.gz"}), qt.IsNil)
+	assets, err := client.ListAssets(ctx, ReleaseInfo{}, 42)
+	c.Assert(err, qt.IsNil)
+	c.Assert(assets, qt.HasLen, 0)
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import "sort"
+
+// AssetChangeStatus describes how an asset differs between the local files and an existing release.
+type AssetChangeStatus string
+
+const (
+	// The asset does not exist in the release.
+	AssetAdded AssetChangeStatus = "add"
+
+	// The asset exists in the release with different content.
+	AssetReplaced AssetChangeStatus = "replace"
+
+	// The asset exists in the release with the same content.
+	AssetUnchanged AssetChangeStatus = "unchanged"
+
+	// The asset exists in the release only.
+	AssetRemoteOnly AssetChangeStatus = "remote only"
+)
+
+// AssetChange is the status of one asset.
+type AssetChange struct {
+	Name   string
+	Status AssetChangeStatus
+}
+
+// DiffAssets compares the local assets with the remote assets of an existing release.
+// The content is compared using the checksums if set on both sides, else the size.
+// The result is sorted by name.
+func DiffAssets(local, remote []Asset) []AssetChange {
+	remoteByName := make(map[string]Asset)
+	for _, a := range remote {
+		remoteByName[a.Name] = a
+	}
+
+	var changes []AssetChange
+	for _, l := range local {
+		r, found := remoteByName[l.Name]
+		if !found {
+			changes = append(changes, AssetChange{Name: l.Name, Status: AssetAdded})
+			continue
+		}
+		delete(remoteByName, l.Name)
+
+		same := l.Size == r.Size
+		if l.Checksum != "" && r.Checksum != "" {
+			same = l.Checksum == r.Checksum
+		}
+		if same {
+			changes = append(changes, AssetChange{Name: l.Name, Status: AssetUnchanged})
+		} else {
+			changes = append(changes, AssetChange{Name: l.Name, Status: AssetReplaced})
+		}
+	}
+
+	for name := range remoteByName {
+		changes = append(changes, AssetChange{Name: name, Status: AssetRemoteOnly})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes
+}
@@ -0,0 +1,53 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestDiffAssets(t *testing.T) {
+	c := qt.New(t)
+
+	local := []Asset{
+		{Name: "new.tar.gz", Size: 10, Checksum: "aaa"},
+		{Name: "same.tar.gz", Size: 10, Checksum: "bbb"},
+		{Name: "changed.tar.gz", Size: 10, Checksum: "ccc"},
+		{Name: "checksums.txt", Size: 20},
+		{Name: "resized.txt", Size: 31},
+	}
+	remote := []Asset{
+		{Name: "same.tar.gz", Size: 10, Checksum: "bbb"},
+		{Name: "changed.tar.gz", Size: 10, Checksum: "ddd"},
+		{Name: "checksums.txt", Size: 20},
+		{Name: "resized.txt", Size: 30},
+		{Name: "old.tar.gz", Size: 10},
+	}
+
+	c.Assert(DiffAssets(local, remote), qt.DeepEquals, []AssetChange{
+		{Name: "changed.tar.gz", Status: AssetReplaced},
+		{Name: "checksums.txt", Status: AssetUnchanged},
+		{Name: "new.tar.gz", Status: AssetAdded},
+		{Name: "old.tar.gz", Status: AssetRemoteOnly},
+		{Name: "resized.txt", Status: AssetReplaced},
+		{Name: "same.tar.gz", Status: AssetUnchanged},
+	})
+
+	c.Assert(DiffAssets(local[:1], nil), qt.DeepEquals, []AssetChange{
+		{Name: "new.tar.gz", Status: AssetAdded},
+	})
+}
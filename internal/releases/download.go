@@ -0,0 +1,110 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/bep/workers"
+)
+
+// AssetDownloader is implemented by clients that can download assets from an existing release.
+type AssetDownloader interface {
+	// DownloadAssetsFile writes the content of the asset with the given name
+	// in the release tagged info.Tag to w.
+	DownloadAssetsFile(ctx context.Context, info ReleaseInfo, name string, w io.Writer) error
+}
+
+// DownloadAssets downloads the named assets from the release tagged info.Tag in parallel.
+// It returns the local filenames in the same order as names.
+// See DownloadAssetsFileWithRetries.
+func DownloadAssets(ctx context.Context, workforce *workers.Workforce, client AssetDownloader, info ReleaseInfo, cacheDir string, names ...string) ([]string, error) {
+	filenames := make([]string, len(names))
+
+	r, ctx := workforce.Start(ctx)
+
+	for i, name := range names {
+		i, name := i, name
+		r.Run(func() error {
+			filename, err := DownloadAssetsFileWithRetries(ctx, client, info, cacheDir, name)
+			if err != nil {
+				return err
+			}
+			filenames[i] = filename
+			return nil
+		})
+	}
+
+	if err := r.Wait(); err != nil {
+		return nil, err
+	}
+
+	return filenames, nil
+}
+
+// DownloadAssetsFileWithRetries is the counterpart to UploadAssetsFileWithRetries.
+// It downloads the named asset to cacheDir/<tag>/<name>, retrying on temporary errors,
+// and returns the local filename.
+// Assets already in the cache are not downloaded again.
+func DownloadAssetsFileWithRetries(ctx context.Context, client AssetDownloader, info ReleaseInfo, cacheDir, name string) (string, error) {
+	if info.Tag == "" {
+		return "", errors.New("download: tag is required")
+	}
+	if name == "" || name != filepath.Base(name) {
+		return "", fmt.Errorf("download: invalid asset name %q", name)
+	}
+
+	dir := filepath.Join(cacheDir, info.Tag)
+	filename := filepath.Join(dir, name)
+
+	if _, err := os.Stat(filename); err == nil {
+		return filename, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	err := withRetries(ctx, info.Retry, func() (error, bool) {
+		// Download to a temporary file to avoid caching partial downloads.
+		f, err := os.CreateTemp(dir, name+".*.tmp")
+		if err != nil {
+			return err, false
+		}
+		tmpFilename := f.Name()
+		err = client.DownloadAssetsFile(ctx, info, name, f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmpFilename, filename)
+		}
+		if err != nil {
+			os.Remove(tmpFilename)
+			return err, isTemporaryError(err)
+		}
+		return nil, false
+	})
+	if err != nil {
+		return "", fmt.Errorf("download: failed to download %q from release %q: %w", name, info.Tag, err)
+	}
+
+	return filename, nil
+}
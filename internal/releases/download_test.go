@@ -0,0 +1,100 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/bep/workers"
+	qt "github.com/frankban/quicktest"
+)
+
+type testDownloader struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (d *testDownloader) DownloadAssetsFile(ctx context.Context, info ReleaseInfo, name string, w io.Writer) error {
+	d.mu.Lock()
+	d.calls[name]++
+	n := d.calls[name]
+	d.mu.Unlock()
+
+	switch name {
+	case "flaky.txt":
+		if n == 1 {
+			io.WriteString(w, "partial")
+			return TemporaryError{errors.New("connection reset")}
+		}
+	case "missing.txt":
+		return errors.New("not found")
+	}
+
+	_, err := fmt.Fprintf(w, "%s/%s", info.Tag, name)
+	return err
+}
+
+func TestDownloadAssets(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+	w := workers.New(4)
+	cacheDir := t.TempDir()
+	d := &testDownloader{calls: make(map[string]int)}
+	info := ReleaseInfo{Tag: "v1.2.0"}
+
+	names := []string{"a.txt", "b.txt", "flaky.txt"}
+
+	filenames, err := DownloadAssets(ctx, w, d, info, cacheDir, names...)
+	c.Assert(err, qt.IsNil)
+	c.Assert(filenames, qt.HasLen, 3)
+
+	for i, name := range names {
+		c.Assert(filenames[i], qt.Equals, filepath.Join(cacheDir, "v1.2.0", name))
+		b, err := os.ReadFile(filenames[i])
+		c.Assert(err, qt.IsNil)
+		c.Assert(string(b), qt.Equals, "v1.2.0/"+name)
+	}
+	c.Assert(d.calls["flaky.txt"], qt.Equals, 2)
+
+	// Cached.
+	_, err = DownloadAssets(ctx, w, d, info, cacheDir, names...)
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.calls["a.txt"], qt.Equals, 1)
+	c.Assert(d.calls["flaky.txt"], qt.Equals, 2)
+
+	// The cache is keyed by tag.
+	_, err = DownloadAssetsFileWithRetries(ctx, d, ReleaseInfo{Tag: "v1.1.0"}, cacheDir, "a.txt")
+	c.Assert(err, qt.IsNil)
+	c.Assert(d.calls["a.txt"], qt.Equals, 2)
+
+	// Non-temporary errors are not retried and nothing is cached.
+	_, err = DownloadAssets(ctx, w, d, info, cacheDir, "missing.txt")
+	c.Assert(err, qt.ErrorMatches, `download: failed to download "missing.txt" from release "v1.2.0": not found`)
+	c.Assert(d.calls["missing.txt"], qt.Equals, 1)
+	entries, err := os.ReadDir(filepath.Join(cacheDir, "v1.2.0"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(entries, qt.HasLen, 3)
+
+	_, err = DownloadAssetsFileWithRetries(ctx, d, info, cacheDir, "../a.txt")
+	c.Assert(err, qt.ErrorMatches, `download: invalid asset name.*`)
+}
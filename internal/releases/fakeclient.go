@@ -5,11 +5,42 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 )
 
 // Fake client is only used in tests.
 type FakeClient struct {
 	releaseID int64
+
+	mu     sync.Mutex
+	assets []Asset
+}
+
+// fakeToken is the token value that makes the release clients return a FakeClient.
+const fakeToken = "faketoken"
+
+// fakeExistingAssetsEnvVar can be set in tests to a comma separated list of asset names
+// that an existing release (e.g. -release-id) starts out with in the fake client.
+const fakeExistingAssetsEnvVar = "HUGORELEASER_FAKE_EXISTING_ASSETS"
+
+func newFakeClient() *FakeClient {
+	c := &FakeClient{}
+	for _, name := range strings.Split(os.Getenv(fakeExistingAssetsEnvVar), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			c.assets = append(c.assets, Asset{Name: name, Size: -1})
+		}
+	}
+	return c
+}
+
+// UseFakeClients sets the token env vars of all the release types to fakeToken,
+// so no release gets published, e.g. when running with the -try or -snapshot flag.
+func UseFakeClients() {
+	for _, envVar := range []string{tokenEnvVar, gitLabTokenEnvVar, giteaTokenEnvVar, s3AccessKeyEnvVar} {
+		os.Setenv(envVar, fakeToken)
+	}
 }
 
 func (c *FakeClient) Release(ctx context.Context, info ReleaseInfo) (int64, error) {
@@ -21,16 +52,75 @@ func (c *FakeClient) Release(ctx context.Context, info ReleaseInfo) (int64, erro
 			return 0, err
 		}
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// A new release has no assets.
+	c.assets = nil
 	c.releaseID = rand.Int63()
 	return c.releaseID, nil
 }
 
-func (c *FakeClient) UploadAssetsFile(ctx context.Context, info ReleaseInfo, f *os.File, releaseID int64) error {
-	if c.releaseID != releaseID {
-		return fmt.Errorf("fake: releaseID mismatch: %d != %d", c.releaseID, releaseID)
+func (c *FakeClient) UploadAssetsFile(ctx context.Context, info ReleaseInfo, f *os.File, label string, releaseID int64) error {
+	c.mu.Lock()
+	if c.releaseID == 0 {
+		// Uploading to an existing release, e.g. -release-id.
+		c.releaseID = releaseID
+	}
+	currentID := c.releaseID
+	c.mu.Unlock()
+	if currentID != releaseID {
+		return fmt.Errorf("fake: releaseID mismatch: %d != %d", currentID, releaseID)
 	}
 	if f == nil {
 		return fmt.Errorf("fake: nil file")
 	}
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.assets = append(c.assets, Asset{Name: filepath.Base(f.Name()), Size: fi.Size()})
+	c.mu.Unlock()
+	return nil
+}
+
+// ReleaseURL returns a fake URL for the release created by this client.
+func (c *FakeClient) ReleaseURL(releaseID int64) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if releaseID != c.releaseID {
+		return ""
+	}
+	return fmt.Sprintf("https://example.org/releases/%d", releaseID)
+}
+
+// FindRelease always reports that the release does not exist.
+func (c *FakeClient) FindRelease(ctx context.Context, info ReleaseInfo) (int64, error) {
+	return 0, nil
+}
+
+func (c *FakeClient) DeleteAsset(ctx context.Context, info ReleaseInfo, releaseID int64, asset Asset) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Printf("fake: delete asset: %s\n", asset.Name)
+	for i, a := range c.assets {
+		if a.Name == asset.Name {
+			c.assets = append(c.assets[:i], c.assets[i+1:]...)
+			break
+		}
+	}
 	return nil
 }
+
+func (c *FakeClient) ListAssets(ctx context.Context, info ReleaseInfo, releaseID int64) ([]Asset, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.releaseID == 0 {
+		// An existing release, e.g. -release-id.
+		c.releaseID = releaseID
+	}
+	if c.releaseID != releaseID {
+		return nil, fmt.Errorf("fake: releaseID mismatch: %d != %d", c.releaseID, releaseID)
+	}
+	return append([]Asset(nil), c.assets...), nil
+}
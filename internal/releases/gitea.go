@@ -0,0 +1,241 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gohugoio/hugoreleaser/internal/config"
+	"github.com/gohugoio/hugoreleaser/internal/releases/releasetypes"
+)
+
+const (
+	giteaTokenEnvVar    = "GITEA_TOKEN"
+	giteaDefaultBaseURL = "https://gitea.com"
+)
+
+func init() {
+	RegisterClient(releasetypes.Gitea, giteaClientFactory{})
+}
+
+type giteaClientFactory struct{}
+
+func (giteaClientFactory) Validate() error {
+	token := os.Getenv(giteaTokenEnvVar)
+	if token == "" {
+		return fmt.Errorf("release: missing %q env var", giteaTokenEnvVar)
+	}
+	return nil
+}
+
+func (giteaClientFactory) New(ctx context.Context, settings config.ReleaseSettings) (Client, error) {
+	token := os.Getenv(giteaTokenEnvVar)
+
+	// Set in tests and when running with the -try or -snapshot flag.
+	if token == fakeToken {
+		return newFakeClient(), nil
+	}
+
+	baseURL := settings.BaseURL
+	if baseURL == "" {
+		baseURL = giteaDefaultBaseURL
+	}
+
+	return newGiteaClient(strings.TrimSuffix(baseURL, "/"), token, newHTTPClient(settings.HTTPSettings)), nil
+}
+
+func newGiteaClient(baseURL, token string, httpClient *http.Client) *GiteaClient {
+	return &GiteaClient{
+		baseURL: baseURL,
+		rest: &restClient{
+			name:       "gitea",
+			httpClient: httpClient,
+			setAuth: func(req *http.Request) {
+				req.Header.Set("Authorization", "token "+token)
+			},
+		},
+		usernameCache: make(map[string]string),
+	}
+}
+
+var (
+	_ UsernameResolver = &GiteaClient{}
+	_ ReleaseFinder    = &GiteaClient{}
+	_ AssetDeleter     = &GiteaClient{}
+)
+
+// GiteaClient is a release client for Gitea (and Forgejo), using the REST API v1.
+type GiteaClient struct {
+	// The web URL of the Gitea instance, e.g. https://gitea.com.
+	baseURL string
+	rest    *restClient
+
+	usernameCacheMu sync.Mutex
+	usernameCache   map[string]string
+}
+
+type giteaRelease struct {
+	ID              int64  `json:"id,omitempty"`
+	TagName         string `json:"tag_name"`
+	TargetCommitish string `json:"target_commitish,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Body            string `json:"body,omitempty"`
+	Draft           bool   `json:"draft"`
+	Prerelease      bool   `json:"prerelease"`
+}
+
+type giteaAttachment struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+func (c *GiteaClient) ResolveUsername(ctx context.Context, sha, author string, info ReleaseInfo) (string, error) {
+	c.usernameCacheMu.Lock()
+	defer c.usernameCacheMu.Unlock()
+	if username, ok := c.usernameCache[author]; ok {
+		return username, nil
+	}
+
+	// Author is only set if the commit email belongs to a Gitea user.
+	var commit struct {
+		Author *struct {
+			Login string `json:"login"`
+		} `json:"author"`
+	}
+	if err := c.rest.do(ctx, http.MethodGet, c.repoURL(info, "git", "commits", sha), "", nil, &commit); err != nil {
+		if isNotFoundError(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if commit.Author == nil || commit.Author.Login == "" {
+		return "", nil
+	}
+
+	c.usernameCache[author] = commit.Author.Login
+	return c.usernameCache[author], nil
+}
+
+func (c *GiteaClient) Release(ctx context.Context, info ReleaseInfo) (int64, error) {
+	settings := info.Settings
+
+	var body string
+	if settings.ReleaseNotesSettings.Filename != "" {
+		b, err := os.ReadFile(settings.ReleaseNotesSettings.Filename)
+		if err != nil {
+			return 0, err
+		}
+		body = string(b)
+	}
+
+	r := giteaRelease{
+		TagName:         info.Tag,
+		TargetCommitish: info.Commitish,
+		Name:            settings.Name,
+		Body:            body,
+		Draft:           settings.Draft,
+		Prerelease:      settings.Prerelease,
+	}
+
+	var rel giteaRelease
+	if err := c.rest.doJSON(ctx, http.MethodPost, c.repoURL(info, "releases"), r, &rel); err != nil {
+		return 0, err
+	}
+
+	return rel.ID, nil
+}
+
+// UploadAssetsFile uploads f as an attachment to the release.
+// Gitea attachments have no label, so label is ignored.
+func (c *GiteaClient) UploadAssetsFile(ctx context.Context, info ReleaseInfo, f *os.File, label string, releaseID int64) error {
+	name := filepath.Base(f.Name())
+	u := c.repoURL(info, "releases", fmt.Sprint(releaseID), "assets") + "?name=" + url.QueryEscape(name)
+	return c.rest.doMultipart(ctx, u, "attachment", name, uploadBody(ctx, f), nil)
+}
+
+func (c *GiteaClient) FindRelease(ctx context.Context, info ReleaseInfo) (int64, error) {
+	var rel giteaRelease
+	if err := c.rest.do(ctx, http.MethodGet, c.repoURL(info, "releases", "tags", info.Tag), "", nil, &rel); err != nil {
+		if isNotFoundError(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return rel.ID, nil
+}
+
+func (c *GiteaClient) ListAssets(ctx context.Context, info ReleaseInfo, releaseID int64) ([]Asset, error) {
+	attachments, err := c.listAttachments(ctx, info, releaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var assets []Asset
+	for _, a := range attachments {
+		assets = append(assets, Asset{Name: a.Name, Size: a.Size})
+	}
+
+	return assets, nil
+}
+
+func (c *GiteaClient) DeleteAsset(ctx context.Context, info ReleaseInfo, releaseID int64, asset Asset) error {
+	attachments, err := c.listAttachments(ctx, info, releaseID)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range attachments {
+		if a.Name != asset.Name {
+			continue
+		}
+		err := c.rest.do(ctx, http.MethodDelete, c.repoURL(info, "releases", fmt.Sprint(releaseID), "assets", fmt.Sprint(a.ID)), "", nil, nil)
+		if err != nil && !isNotFoundError(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Already deleted.
+	return nil
+}
+
+func (c *GiteaClient) listAttachments(ctx context.Context, info ReleaseInfo, releaseID int64) ([]giteaAttachment, error) {
+	var attachments []giteaAttachment
+	err := c.rest.do(ctx, http.MethodGet, c.repoURL(info, "releases", fmt.Sprint(releaseID), "assets"), "", nil, &attachments)
+	return attachments, err
+}
+
+// repoURL returns the API URL for the repository with the path elements escaped and appended.
+func (c *GiteaClient) repoURL(info ReleaseInfo, elem ...string) string {
+	var sb strings.Builder
+	sb.WriteString(c.baseURL)
+	sb.WriteString("/api/v1/repos/")
+	sb.WriteString(url.PathEscape(info.Settings.RepositoryOwner))
+	sb.WriteString("/")
+	sb.WriteString(url.PathEscape(info.Settings.Repository))
+	for _, e := range elem {
+		sb.WriteString("/")
+		sb.WriteString(url.PathEscape(e))
+	}
+	return sb.String()
+}
@@ -0,0 +1,212 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+)
+
+// fakeGitea is a minimal in-memory Gitea API for repository "gohugoio/hugo".
+type fakeGitea struct {
+	mu          sync.Mutex
+	tokens      []string
+	releases    map[string]*giteaRelease
+	attachments map[int64][]giteaAttachment
+	nextID      int64
+}
+
+func (s *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens = append(s.tokens, r.Header.Get("Authorization"))
+
+	writeJSON := func(status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+
+	const repoPrefix = "/api/v1/repos/gohugoio/hugo/"
+	if !strings.HasPrefix(r.URL.Path, repoPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, repoPrefix), "/")
+
+	releaseAttachments := func() (int64, bool) {
+		var id int64
+		fmt.Sscan(parts[1], &id)
+		_, found := s.attachments[id]
+		if !found {
+			http.NotFound(w, r)
+		}
+		return id, found
+	}
+
+	switch {
+	case r.Method == http.MethodPost && parts[0] == "releases" && len(parts) == 1:
+		var rel giteaRelease
+		if err := json.NewDecoder(r.Body).Decode(&rel); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, found := s.releases[rel.TagName]; found {
+			http.Error(w, "release is already created", http.StatusConflict)
+			return
+		}
+		s.nextID++
+		rel.ID = s.nextID
+		s.releases[rel.TagName] = &rel
+		s.attachments[rel.ID] = []giteaAttachment{}
+		writeJSON(http.StatusCreated, rel)
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[1] == "tags":
+		rel, found := s.releases[parts[2]]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(http.StatusOK, rel)
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[2] == "assets":
+		if id, ok := releaseAttachments(); ok {
+			writeJSON(http.StatusOK, s.attachments[id])
+		}
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[2] == "assets":
+		id, ok := releaseAttachments()
+		if !ok {
+			return
+		}
+		f, _, err := r.FormFile("attachment")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		size, err := io.Copy(io.Discard, f)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.nextID++
+		a := giteaAttachment{ID: s.nextID, Name: r.URL.Query().Get("name"), Size: size}
+		s.attachments[id] = append(s.attachments[id], a)
+		writeJSON(http.StatusCreated, a)
+	case r.Method == http.MethodDelete && len(parts) == 4 && parts[2] == "assets":
+		id, ok := releaseAttachments()
+		if !ok {
+			return
+		}
+		for i, a := range s.attachments[id] {
+			if fmt.Sprint(a.ID) == parts[3] {
+				s.attachments[id] = append(s.attachments[id][:i], s.attachments[id][i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[1] == "commits":
+		if parts[2] != "abc123" {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(http.StatusOK, map[string]any{"author": map[string]string{"login": "jane"}})
+	default:
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+	}
+}
+
+func TestGiteaClient(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	fake := &fakeGitea{releases: make(map[string]*giteaRelease), attachments: make(map[int64][]giteaAttachment)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := newGiteaClient(server.URL, "secret", server.Client())
+
+	tempDir := t.TempDir()
+	notes := filepath.Join(tempDir, "release-notes.md")
+	c.Assert(os.WriteFile(notes, []byte("## Changes"), 0o644), qt.IsNil)
+	archive := filepath.Join(tempDir, "hugo_1.2.0_linux-amd64.tar.gz")
+	c.Assert(os.WriteFile(archive, []byte("archive"), 0o644), qt.IsNil)
+
+	info := ReleaseInfo{
+		Tag:       "v1.2.0",
+		Commitish: "main",
+		Settings: config.ReleaseSettings{
+			RepositoryOwner:      "gohugoio",
+			Repository:           "hugo",
+			Name:                 "v1.2.0",
+			Draft:                true,
+			ReleaseNotesSettings: config.ReleaseNotesSettings{Filename: notes},
+		},
+	}
+
+	releaseID, err := client.FindRelease(ctx, info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(releaseID, qt.Equals, int64(0))
+
+	releaseID, err = client.Release(ctx, info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(releaseID, qt.Equals, int64(1))
+	c.Assert(*fake.releases["v1.2.0"], qt.DeepEquals, giteaRelease{
+		ID: 1, TagName: "v1.2.0", TargetCommitish: "main", Name: "v1.2.0", Body: "## Changes", Draft: true,
+	})
+
+	_, err = client.Release(ctx, info)
+	c.Assert(err, qt.ErrorMatches, `gitea: unexpected status code 409: release is already created`)
+	c.Assert(isTemporaryError(err), qt.IsFalse)
+
+	c.Assert(UploadAssetsFileWithRetries(ctx, client, info, "Linux", releaseID, func() (*os.File, error) {
+		return os.Open(archive)
+	}), qt.IsNil)
+
+	releaseID, err = client.FindRelease(ctx, info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(releaseID, qt.Equals, int64(1))
+	c.Assert(VerifyAssets(ctx, client, info, releaseID, false, archive), qt.IsNil)
+
+	username, err := client.ResolveUsername(ctx, "abc123", "Jane", info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(username, qt.Equals, "jane")
+	username, err = client.ResolveUsername(ctx, "def456", "John", info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(username, qt.Equals, "")
+
+	c.Assert(client.DeleteAsset(ctx, info, releaseID, Asset{Name: "hugo_1.2.0_linux-amd64.tar.gz"}), qt.IsNil)
+	// Already deleted.
+	c.Assert(client.DeleteAsset(ctx, info, releaseID, Asset{Name: "hugo_1.2.0_linux-amd64.tar.gz"}), qt.IsNil)
+	assets, err := client.ListAssets(ctx, info, releaseID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(assets, qt.HasLen, 0)
+
+	for _, token := range fake.tokens {
+		c.Assert(token, qt.Equals, "token secret")
+	}
+}
@@ -18,11 +18,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/gohugoio/hugoreleaser/internal/config"
 	"github.com/gohugoio/hugoreleaser/internal/releases/releasetypes"
 	"github.com/google/go-github/v45/github"
 	"golang.org/x/oauth2"
@@ -30,11 +36,16 @@ import (
 
 const tokenEnvVar = "GITHUB_TOKEN"
 
-// Validate validates the release type.
-func Validate(typ releasetypes.Type) error {
-	if typ != releasetypes.GitHub {
-		return fmt.Errorf("release: only github is supported for now")
-	}
+// All files uploaded to a GitHub release must be under 2 GiB.
+const gitHubMaxAssetSize = 2 << 30
+
+func init() {
+	RegisterClient(releasetypes.GitHub, gitHubClientFactory{})
+}
+
+type gitHubClientFactory struct{}
+
+func (gitHubClientFactory) Validate() error {
 	token := os.Getenv(tokenEnvVar)
 	if token == "" {
 		return fmt.Errorf("release: missing %q env var", tokenEnvVar)
@@ -42,60 +53,68 @@ func Validate(typ releasetypes.Type) error {
 	return nil
 }
 
-func NewClient(ctx context.Context, typ releasetypes.Type) (Client, error) {
-	if err := Validate(typ); err != nil {
-		return nil, err
-	}
+func (gitHubClientFactory) MaxAssetSize() int64 {
+	return gitHubMaxAssetSize
+}
 
+func (gitHubClientFactory) New(ctx context.Context, settings config.ReleaseSettings) (Client, error) {
 	token := os.Getenv(tokenEnvVar)
 
 	// Set in tests to test the all command.
-	// and when running with the -try flag.
-	if token == "faketoken" {
-		return &FakeClient{}, nil
+	// and when running with the -try or -snapshot flag.
+	if token == fakeToken {
+		return newFakeClient(), nil
 	}
 
 	tokenSource := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
 
+	// The oauth2 client will wrap the transport of the client set in the context.
+	downloadClient := newHTTPClient(settings.HTTPSettings)
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, downloadClient)
 	httpClient := oauth2.NewClient(ctx, tokenSource)
 
 	return &GitHubClient{
-		client:        github.NewClient(httpClient),
-		usernameCache: make(map[string]string),
+		client:         github.NewClient(httpClient),
+		downloadClient: downloadClient,
+		usernameCache:  make(map[string]string),
+		releaseURLs:    make(map[int64]string),
 	}, nil
 }
 
-// UploadAssetsFileWithRetries is a wrapper around UploadAssetsFile that retries on temporary errors.
-func UploadAssetsFileWithRetries(ctx context.Context, client Client, info ReleaseInfo, releaseID int64, openFile func() (*os.File, error)) error {
-	return withRetries(func() (error, bool) {
-		f, err := openFile()
-		if err != nil {
-			return err, false
-		}
-		defer f.Close()
-		err = client.UploadAssetsFile(ctx, info, f, releaseID)
-		if err != nil && errors.Is(err, TemporaryError{}) {
-			return err, true
-		}
-		return err, false
-	})
-
-}
-
 // UsernameResolver is an interface that allows to resolve the username of a commit.
 type UsernameResolver interface {
 	ResolveUsername(ctx context.Context, sha, author string, info ReleaseInfo) (string, error)
 }
 
-var _ UsernameResolver = &GitHubClient{}
+var (
+	_ UsernameResolver = &GitHubClient{}
+	_ AssetDownloader  = &GitHubClient{}
+	_ ReleaseFinder    = &GitHubClient{}
+	_ AssetDeleter     = &GitHubClient{}
+
+	_ ReleaseURLProvider = &GitHubClient{}
+)
 
 type GitHubClient struct {
 	client *github.Client
 
+	// Used to follow redirects to the asset storage, without any auth headers.
+	downloadClient *http.Client
+
 	usernameCacheMu sync.Mutex
 	usernameCache   map[string]string
+
+	// The HTML URLs of the releases created, keyed by ID.
+	releaseURLsMu sync.Mutex
+	releaseURLs   map[int64]string
+}
+
+func (c *GitHubClient) ReleaseURL(releaseID int64) string {
+	c.releaseURLsMu.Lock()
+	defer c.releaseURLsMu.Unlock()
+	return c.releaseURLs[releaseID]
 }
 
 func (c *GitHubClient) ResolveUsername(ctx context.Context, sha, author string, info ReleaseInfo) (string, error) {
@@ -156,29 +175,43 @@ func (c *GitHubClient) Release(ctx context.Context, info ReleaseInfo) (int64, er
 
 	rel, resp, err := c.client.Repositories.CreateRelease(ctx, settings.RepositoryOwner, settings.Repository, r)
 	if err != nil {
-		return 0, err
+		if resp != nil && !isTemporaryHttpStatus(resp.StatusCode) {
+			return 0, err
+		}
+		return 0, TemporaryError{err}
 	}
 
 	if resp.StatusCode != http.StatusCreated {
 		return 0, fmt.Errorf("github: unexpected status code: %d", resp.StatusCode)
 	}
 
+	c.releaseURLsMu.Lock()
+	c.releaseURLs[rel.GetID()] = rel.GetHTMLURL()
+	c.releaseURLsMu.Unlock()
+
 	return *rel.ID, nil
 }
 
-func (c *GitHubClient) UploadAssetsFile(ctx context.Context, info ReleaseInfo, f *os.File, releaseID int64) error {
+func (c *GitHubClient) UploadAssetsFile(ctx context.Context, info ReleaseInfo, f *os.File, label string, releaseID int64) error {
 	settings := info.Settings
 
-	_, resp, err := c.client.Repositories.UploadReleaseAsset(
-		ctx,
-		settings.RepositoryOwner,
-		settings.Repository,
-		releaseID,
-		&github.UploadOptions{
-			Name: filepath.Base(f.Name()),
-		},
-		f,
-	)
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// This is what Repositories.UploadReleaseAsset does, but that reads from the *os.File directly.
+	q := url.Values{"name": {filepath.Base(f.Name())}}
+	if label != "" {
+		q.Set("label", label)
+	}
+	u := fmt.Sprintf("repos/%s/%s/releases/%d/assets?%s", settings.RepositoryOwner, settings.Repository, releaseID, q.Encode())
+	req, err := c.client.NewUploadRequest(u, uploadBody(ctx, f), fi.Size(), mime.TypeByExtension(filepath.Ext(f.Name())))
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(ctx, req, new(github.ReleaseAsset))
 	if err == nil {
 		return nil
 	}
@@ -191,10 +224,221 @@ func (c *GitHubClient) UploadAssetsFile(ctx context.Context, info ReleaseInfo, f
 
 }
 
+func (c *GitHubClient) FindRelease(ctx context.Context, info ReleaseInfo) (int64, error) {
+	settings := info.Settings
+	rel, resp, err := c.client.Repositories.GetReleaseByTag(ctx, settings.RepositoryOwner, settings.Repository, info.Tag)
+	if err == nil {
+		return rel.GetID(), nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return 0, err
+	}
+
+	// Draft releases are not returned by GetReleaseByTag, so look for them in the release list.
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := c.client.Repositories.ListReleases(ctx, settings.RepositoryOwner, settings.Repository, opts)
+		if err != nil {
+			return 0, err
+		}
+		for _, rel := range page {
+			if rel.GetTagName() == info.Tag {
+				return rel.GetID(), nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return 0, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (c *GitHubClient) ListAssets(ctx context.Context, info ReleaseInfo, releaseID int64) ([]Asset, error) {
+	settings := info.Settings
+
+	var assets []Asset
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := c.client.Repositories.ListReleaseAssets(ctx, settings.RepositoryOwner, settings.Repository, releaseID, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, asset := range page {
+			assets = append(assets, Asset{Name: asset.GetName(), ID: asset.GetID(), Size: int64(asset.GetSize())})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return assets, nil
+}
+
+// DeleteAsset deletes the asset by its ID if set, else it's looked up by name.
+func (c *GitHubClient) DeleteAsset(ctx context.Context, info ReleaseInfo, releaseID int64, asset Asset) error {
+	settings := info.Settings
+
+	assetID := asset.ID
+	opts := &github.ListOptions{PerPage: 100}
+	for assetID == 0 {
+		page, resp, err := c.client.Repositories.ListReleaseAssets(ctx, settings.RepositoryOwner, settings.Repository, releaseID, opts)
+		if err != nil {
+			return gitHubDeleteAssetError(ctx, resp, err)
+		}
+		for _, a := range page {
+			if a.GetName() == asset.Name {
+				assetID = a.GetID()
+				break
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	if assetID == 0 {
+		// Already deleted.
+		return nil
+	}
+
+	resp, err := c.client.Repositories.DeleteReleaseAsset(ctx, settings.RepositoryOwner, settings.Repository, assetID)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return gitHubDeleteAssetError(ctx, resp, err)
+	}
+
+	return nil
+}
+
+// gitHubDeleteAssetError wraps err in a TemporaryError if it's worth retrying.
+// On rate limits, it first waits until the limit is reset.
+func gitHubDeleteAssetError(ctx context.Context, resp *github.Response, err error) error {
+	if wait, found := rateLimitWait(resp, err); found {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+		return TemporaryError{err}
+	}
+	if resp != nil && !isTemporaryHttpStatus(resp.StatusCode) {
+		return err
+	}
+	return TemporaryError{err}
+}
+
+// rateLimitWait returns how long to wait before the rate limit hit by the request is reset,
+// from the Retry-After or the X-RateLimit-Reset header of a 403 or 429 response.
+func rateLimitWait(resp *github.Response, err error) (time.Duration, bool) {
+	var (
+		rateLimitErr  *github.RateLimitError
+		abuseLimitErr *github.AbuseRateLimitError
+	)
+	switch {
+	case errors.As(err, &abuseLimitErr) && abuseLimitErr.RetryAfter != nil:
+		return *abuseLimitErr.RetryAfter, true
+	case errors.As(err, &rateLimitErr):
+		// Also returned without sending the request if the client knows that the limit is reached.
+		return nonNegative(time.Until(rateLimitErr.Rate.Reset.Time)), true
+	}
+
+	if resp == nil || resp.Response == nil || (resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests) {
+		return 0, false
+	}
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			return nonNegative(time.Duration(seconds) * time.Second), true
+		}
+	}
+	if v := resp.Header.Get("X-RateLimit-Reset"); v != "" && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return nonNegative(time.Until(time.Unix(reset, 0))), true
+		}
+	}
+	return 0, false
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+func (c *GitHubClient) DownloadAssetsFile(ctx context.Context, info ReleaseInfo, name string, w io.Writer) error {
+	settings := info.Settings
+
+	rel, resp, err := c.client.Repositories.GetReleaseByTag(ctx, settings.RepositoryOwner, settings.Repository, info.Tag)
+	if err != nil {
+		if resp != nil {
+			return gitHubDownloadError(resp.Response, err)
+		}
+		return TemporaryError{err}
+	}
+
+	var assetID int64
+	for _, asset := range rel.Assets {
+		if asset.GetName() == name {
+			assetID = asset.GetID()
+			break
+		}
+	}
+	if assetID == 0 {
+		return fmt.Errorf("github: asset %q not found in release %q", name, info.Tag)
+	}
+
+	rc, _, err := c.client.Repositories.DownloadReleaseAsset(ctx, settings.RepositoryOwner, settings.Repository, assetID, c.downloadClient)
+	if err != nil {
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) {
+			return gitHubDownloadError(errResp.Response, err)
+		}
+		return TemporaryError{err}
+	}
+	defer rc.Close()
+
+	if _, err := io.Copy(w, rc); err != nil {
+		return TemporaryError{err}
+	}
+
+	return nil
+}
+
+// gitHubDownloadError returns err as a TemporaryError unless the response status
+// tells that retrying will not help, e.g. a missing asset.
+func gitHubDownloadError(resp *http.Response, err error) error {
+	if resp != nil && (resp.StatusCode == http.StatusNotFound || !isTemporaryHttpStatus(resp.StatusCode)) {
+		return err
+	}
+	return TemporaryError{err}
+}
+
 type TemporaryError struct {
 	error
 }
 
+// Unwrap allows checking the cause, e.g. a cancelled context, with errors.Is.
+func (e TemporaryError) Unwrap() error {
+	return e.error
+}
+
+// isTemporaryError reports whether err is, or wraps, a TemporaryError that is worth retrying.
+// Note that errors.Is(err, TemporaryError{}) only matches a TemporaryError with a nil error.
+func isTemporaryError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// No point in retrying a cancelled or timed out operation.
+		return false
+	}
+	var terr TemporaryError
+	return errors.As(err, &terr)
+}
+
 // isTemporaryHttpStatus returns true if the status code is considered temporary, returning
 // true if not sure.
 func isTemporaryHttpStatus(status int) bool {
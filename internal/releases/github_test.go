@@ -0,0 +1,231 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+	"github.com/google/go-github/v45/github"
+)
+
+func newTestGitHubClient(c *qt.C, h http.Handler) *GitHubClient {
+	srv := httptest.NewServer(h)
+	c.Cleanup(srv.Close)
+	client := github.NewClient(srv.Client())
+	baseURL, err := url.Parse(srv.URL + "/")
+	c.Assert(err, qt.IsNil)
+	client.BaseURL = baseURL
+	client.UploadURL = baseURL
+	return &GitHubClient{
+		client:         client,
+		downloadClient: srv.Client(),
+		usernameCache:  make(map[string]string),
+		releaseURLs:    make(map[int64]string),
+	}
+}
+
+func TestGitHubFindReleaseDraft(t *testing.T) {
+	c := qt.New(t)
+
+	// Draft releases are not returned by the tag endpoint.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/gohugoio/hugo/releases/tags/", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/repos/gohugoio/hugo/releases", func(w http.ResponseWriter, r *http.Request) {
+		var releases []map[string]any
+		switch r.URL.Query().Get("page") {
+		case "", "1":
+			w.Header().Set("Link", fmt.Sprintf(`<%s?page=2>; rel="next"`, r.URL.Path))
+			releases = []map[string]any{{"id": 1, "tag_name": "v1.0.0"}}
+		case "2":
+			releases = []map[string]any{{"id": 2, "tag_name": "v1.1.0", "draft": true}}
+		}
+		json.NewEncoder(w).Encode(releases)
+	})
+
+	client := newTestGitHubClient(c, mux)
+	info := ReleaseInfo{Settings: config.ReleaseSettings{RepositoryOwner: "gohugoio", Repository: "hugo"}}
+
+	info.Tag = "v1.1.0"
+	id, err := client.FindRelease(context.Background(), info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(id, qt.Equals, int64(2))
+
+	info.Tag = "v1.2.0"
+	id, err = client.FindRelease(context.Background(), info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(id, qt.Equals, int64(0))
+}
+
+func TestGitHubUploadAssetsFile(t *testing.T) {
+	c := qt.New(t)
+
+	var query url.Values
+	var body string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/gohugoio/hugo/releases/32/assets", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id": 1}`)
+	})
+
+	filename := filepath.Join(t.TempDir(), "hugo_1.2.0_linux-amd64.tar.gz")
+	c.Assert(os.WriteFile(filename, []byte("archive"), 0o644), qt.IsNil)
+	f, err := os.Open(filename)
+	c.Assert(err, qt.IsNil)
+	defer f.Close()
+
+	client := newTestGitHubClient(c, mux)
+	info := ReleaseInfo{Settings: config.ReleaseSettings{RepositoryOwner: "gohugoio", Repository: "hugo"}}
+
+	c.Assert(client.UploadAssetsFile(context.Background(), info, f, "Linux", 32), qt.IsNil)
+	c.Assert(query.Get("name"), qt.Equals, "hugo_1.2.0_linux-amd64.tar.gz")
+	c.Assert(query.Get("label"), qt.Equals, "Linux")
+	c.Assert(body, qt.Equals, "archive")
+}
+
+func TestGitHubDownloadAssetsFile(t *testing.T) {
+	c := qt.New(t)
+
+	var (
+		mu       sync.Mutex
+		requests = make(map[string]int)
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/gohugoio/hugo/releases/tags/v1.2.0", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 1, "assets": [{"id": 5, "name": "a.txt"}, {"id": 6, "name": "b.txt"}]}`)
+	})
+	mux.HandleFunc("/repos/gohugoio/hugo/releases/assets/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		n := requests[r.URL.Path]
+		mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/5"):
+			http.NotFound(w, r)
+		case n == 1:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			fmt.Fprint(w, "b")
+		}
+	})
+
+	client := newTestGitHubClient(c, mux)
+	info := ReleaseInfo{
+		Tag:      "v1.2.0",
+		Settings: config.ReleaseSettings{RepositoryOwner: "gohugoio", Repository: "hugo"},
+		Retry:    RetrySettings{Set: true, MaxRetries: 3, InitialDelay: time.Millisecond},
+	}
+	cacheDir := t.TempDir()
+
+	// Not found is not retried.
+	_, err := DownloadAssetsFileWithRetries(context.Background(), client, info, cacheDir, "a.txt")
+	c.Assert(err, qt.ErrorMatches, `.*404.*`)
+	c.Assert(requests["/repos/gohugoio/hugo/releases/assets/5"], qt.Equals, 1)
+
+	// Server errors are.
+	filename, err := DownloadAssetsFileWithRetries(context.Background(), client, info, cacheDir, "b.txt")
+	c.Assert(err, qt.IsNil)
+	c.Assert(requests["/repos/gohugoio/hugo/releases/assets/6"], qt.Equals, 2)
+	b, err := os.ReadFile(filename)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b), qt.Equals, "b")
+}
+
+func TestGitHubDeleteAsset(t *testing.T) {
+	c := qt.New(t)
+
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/gohugoio/hugo/releases/assets/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if len(requests) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	client := newTestGitHubClient(c, mux)
+	info := ReleaseInfo{
+		Settings: config.ReleaseSettings{RepositoryOwner: "gohugoio", Repository: "hugo"},
+		Retry:    RetrySettings{Set: true, MaxRetries: 2, InitialDelay: time.Millisecond},
+	}
+
+	// The asset ID from the listing is used, no need to list the assets again.
+	c.Assert(DeleteAssetWithRetries(context.Background(), client, info, 1, Asset{Name: "a.tar.gz", ID: 32}), qt.IsNil)
+	c.Assert(requests, qt.DeepEquals, []string{
+		"DELETE /repos/gohugoio/hugo/releases/assets/32",
+		"DELETE /repos/gohugoio/hugo/releases/assets/32",
+	})
+}
+
+func TestRateLimitWait(t *testing.T) {
+	c := qt.New(t)
+
+	newResponse := func(status int, header ...string) *github.Response {
+		h := make(http.Header)
+		for i := 0; i < len(header); i += 2 {
+			h.Set(header[i], header[i+1])
+		}
+		return &github.Response{Response: &http.Response{StatusCode: status, Header: h}}
+	}
+
+	wait, found := rateLimitWait(newResponse(http.StatusTooManyRequests, "Retry-After", "3"), nil)
+	c.Assert(found, qt.IsTrue)
+	c.Assert(wait, qt.Equals, 3*time.Second)
+
+	reset := time.Now().Add(time.Minute).Unix()
+	wait, found = rateLimitWait(newResponse(http.StatusForbidden, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", fmt.Sprint(reset)), nil)
+	c.Assert(found, qt.IsTrue)
+	c.Assert(wait > 50*time.Second && wait <= time.Minute, qt.IsTrue)
+
+	// Reset in the past.
+	wait, found = rateLimitWait(newResponse(http.StatusForbidden, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", "1"), nil)
+	c.Assert(found, qt.IsTrue)
+	c.Assert(wait, qt.Equals, time.Duration(0))
+
+	// Not rate limited.
+	_, found = rateLimitWait(newResponse(http.StatusForbidden), nil)
+	c.Assert(found, qt.IsFalse)
+	_, found = rateLimitWait(newResponse(http.StatusInternalServerError, "Retry-After", "3"), nil)
+	c.Assert(found, qt.IsFalse)
+	_, found = rateLimitWait(nil, errors.New("connection reset"))
+	c.Assert(found, qt.IsFalse)
+}
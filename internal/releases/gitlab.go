@@ -0,0 +1,277 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gohugoio/hugoreleaser/internal/config"
+	"github.com/gohugoio/hugoreleaser/internal/releases/releasetypes"
+)
+
+const (
+	gitLabTokenEnvVar    = "GITLAB_TOKEN"
+	gitLabDefaultBaseURL = "https://gitlab.com"
+
+	// GitLab releases are identified by their tag, so all releases get this ID.
+	gitLabReleaseID = 1
+)
+
+func init() {
+	RegisterClient(releasetypes.GitLab, gitLabClientFactory{})
+}
+
+type gitLabClientFactory struct{}
+
+func (gitLabClientFactory) Validate() error {
+	token := os.Getenv(gitLabTokenEnvVar)
+	if token == "" {
+		return fmt.Errorf("release: missing %q env var", gitLabTokenEnvVar)
+	}
+	return nil
+}
+
+func (gitLabClientFactory) New(ctx context.Context, settings config.ReleaseSettings) (Client, error) {
+	token := os.Getenv(gitLabTokenEnvVar)
+
+	// Set in tests and when running with the -try or -snapshot flag.
+	if token == fakeToken {
+		return newFakeClient(), nil
+	}
+
+	baseURL := settings.BaseURL
+	if baseURL == "" {
+		baseURL = gitLabDefaultBaseURL
+	}
+
+	return newGitLabClient(strings.TrimSuffix(baseURL, "/"), token, newHTTPClient(settings.HTTPSettings)), nil
+}
+
+func newGitLabClient(baseURL, token string, httpClient *http.Client) *GitLabClient {
+	return &GitLabClient{
+		baseURL: baseURL,
+		rest: &restClient{
+			name:       "gitlab",
+			httpClient: httpClient,
+			setAuth: func(req *http.Request) {
+				req.Header.Set("PRIVATE-TOKEN", token)
+			},
+		},
+		usernameCache: make(map[string]string),
+	}
+}
+
+var (
+	_ UsernameResolver = &GitLabClient{}
+	_ ReleaseFinder    = &GitLabClient{}
+	_ AssetDeleter     = &GitLabClient{}
+)
+
+// GitLabClient is a release client for GitLab, using the REST API v4.
+// The project is repository_owner/repository, where the owner can be a (sub)group.
+// The files are uploaded to the project and added to the release as links.
+// GitLab has no draft releases, so draft and prerelease are ignored.
+type GitLabClient struct {
+	// The web URL of the GitLab instance, e.g. https://gitlab.com.
+	baseURL string
+	rest    *restClient
+
+	usernameCacheMu sync.Mutex
+	usernameCache   map[string]string
+}
+
+type gitLabRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  struct {
+		Links []gitLabLink `json:"links"`
+	} `json:"assets"`
+}
+
+type gitLabLink struct {
+	ID       int64  `json:"id,omitempty"`
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	LinkType string `json:"link_type,omitempty"`
+}
+
+func (c *GitLabClient) ResolveUsername(ctx context.Context, sha, author string, info ReleaseInfo) (string, error) {
+	c.usernameCacheMu.Lock()
+	defer c.usernameCacheMu.Unlock()
+	if username, ok := c.usernameCache[author]; ok {
+		return username, nil
+	}
+
+	var commit struct {
+		AuthorEmail string `json:"author_email"`
+	}
+	if err := c.rest.do(ctx, http.MethodGet, c.projectURL(info, "repository", "commits", sha), "", nil, &commit); err != nil {
+		if isNotFoundError(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if commit.AuthorEmail == "" {
+		return "", nil
+	}
+
+	// Users can only be found by their public email, unless the token belongs to an admin.
+	var users []struct {
+		Username string `json:"username"`
+	}
+	if err := c.rest.do(ctx, http.MethodGet, c.baseURL+"/api/v4/users?search="+url.QueryEscape(commit.AuthorEmail), "", nil, &users); err != nil {
+		return "", err
+	}
+	if len(users) == 0 {
+		return "", nil
+	}
+
+	c.usernameCache[author] = users[0].Username
+	return c.usernameCache[author], nil
+}
+
+func (c *GitLabClient) Release(ctx context.Context, info ReleaseInfo) (int64, error) {
+	settings := info.Settings
+
+	var description string
+	if settings.ReleaseNotesSettings.Filename != "" {
+		b, err := os.ReadFile(settings.ReleaseNotesSettings.Filename)
+		if err != nil {
+			return 0, err
+		}
+		description = string(b)
+	}
+
+	r := struct {
+		TagName     string `json:"tag_name"`
+		Name        string `json:"name,omitempty"`
+		Description string `json:"description,omitempty"`
+		Ref         string `json:"ref,omitempty"`
+	}{
+		TagName:     info.Tag,
+		Name:        settings.Name,
+		Description: description,
+		// Used to create the tag if it does not exist.
+		Ref: info.Commitish,
+	}
+
+	if err := c.rest.doJSON(ctx, http.MethodPost, c.projectURL(info, "releases"), r, nil); err != nil {
+		return 0, err
+	}
+
+	return gitLabReleaseID, nil
+}
+
+// UploadAssetsFile uploads f to the project and adds a link to it to the release.
+// GitLab links have no label, so label is ignored.
+func (c *GitLabClient) UploadAssetsFile(ctx context.Context, info ReleaseInfo, f *os.File, label string, releaseID int64) error {
+	name := filepath.Base(f.Name())
+
+	var upload struct {
+		URL      string `json:"url"`
+		FullPath string `json:"full_path"`
+	}
+	if err := c.rest.doMultipart(ctx, c.projectURL(info, "uploads"), "file", name, uploadBody(ctx, f), &upload); err != nil {
+		return err
+	}
+
+	// The full path is relative to the GitLab instance, the URL to the project.
+	linkURL := c.baseURL + upload.FullPath
+	if upload.FullPath == "" {
+		linkURL = c.baseURL + "/" + gitLabProjectPath(info.Settings) + upload.URL
+	}
+
+	link := gitLabLink{Name: name, URL: linkURL, LinkType: "package"}
+
+	return c.rest.doJSON(ctx, http.MethodPost, c.projectURL(info, "releases", info.Tag, "assets", "links"), link, nil)
+}
+
+func (c *GitLabClient) FindRelease(ctx context.Context, info ReleaseInfo) (int64, error) {
+	if _, err := c.getRelease(ctx, info); err != nil {
+		if isNotFoundError(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return gitLabReleaseID, nil
+}
+
+// ListAssets lists the links in the release.
+// GitLab does not know the size of the linked files, so Size is set to -1.
+func (c *GitLabClient) ListAssets(ctx context.Context, info ReleaseInfo, releaseID int64) ([]Asset, error) {
+	rel, err := c.getRelease(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+
+	var assets []Asset
+	for _, link := range rel.Assets.Links {
+		assets = append(assets, Asset{Name: link.Name, Size: -1})
+	}
+
+	return assets, nil
+}
+
+// DeleteAsset deletes the link with the asset's name from the release.
+// The uploaded file is kept in the project.
+func (c *GitLabClient) DeleteAsset(ctx context.Context, info ReleaseInfo, releaseID int64, asset Asset) error {
+	rel, err := c.getRelease(ctx, info)
+	if err != nil {
+		return err
+	}
+
+	for _, link := range rel.Assets.Links {
+		if link.Name != asset.Name {
+			continue
+		}
+		err := c.rest.do(ctx, http.MethodDelete, c.projectURL(info, "releases", info.Tag, "assets", "links", fmt.Sprint(link.ID)), "", nil, nil)
+		if err != nil && !isNotFoundError(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Already deleted.
+	return nil
+}
+
+func (c *GitLabClient) getRelease(ctx context.Context, info ReleaseInfo) (gitLabRelease, error) {
+	var rel gitLabRelease
+	err := c.rest.do(ctx, http.MethodGet, c.projectURL(info, "releases", info.Tag), "", nil, &rel)
+	return rel, err
+}
+
+// projectURL returns the API URL for the project with the path elements escaped and appended.
+func (c *GitLabClient) projectURL(info ReleaseInfo, elem ...string) string {
+	var sb strings.Builder
+	sb.WriteString(c.baseURL)
+	sb.WriteString("/api/v4/projects/")
+	sb.WriteString(url.PathEscape(gitLabProjectPath(info.Settings)))
+	for _, e := range elem {
+		sb.WriteString("/")
+		sb.WriteString(url.PathEscape(e))
+	}
+	return sb.String()
+}
+
+func gitLabProjectPath(settings config.ReleaseSettings) string {
+	return settings.RepositoryOwner + "/" + settings.Repository
+}
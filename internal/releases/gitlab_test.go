@@ -0,0 +1,210 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+)
+
+// fakeGitLab is a minimal in-memory GitLab API for project "group/sub/hugo".
+type fakeGitLab struct {
+	mu       sync.Mutex
+	tokens   []string
+	releases map[string]*gitLabRelease
+	nextID   int64
+}
+
+func (s *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens = append(s.tokens, r.Header.Get("PRIVATE-TOKEN"))
+
+	writeJSON := func(status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+
+	const projectPrefix = "/api/v4/projects/group%2Fsub%2Fhugo/"
+	if r.URL.Path == "/api/v4/users" {
+		if r.URL.Query().Get("search") == "jane@example.org" {
+			writeJSON(http.StatusOK, []map[string]string{{"username": "jane"}})
+			return
+		}
+		writeJSON(http.StatusOK, []map[string]string{})
+		return
+	}
+	if !strings.HasPrefix(r.URL.EscapedPath(), projectPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v4/projects/group/sub/hugo/"), "/")
+
+	switch {
+	case r.Method == http.MethodPost && parts[0] == "releases" && len(parts) == 1:
+		var rel gitLabRelease
+		if err := json.NewDecoder(r.Body).Decode(&rel); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, found := s.releases[rel.TagName]; found {
+			http.Error(w, "release already exists", http.StatusConflict)
+			return
+		}
+		s.releases[rel.TagName] = &rel
+		writeJSON(http.StatusCreated, rel)
+	case r.Method == http.MethodGet && parts[0] == "releases" && len(parts) == 2:
+		rel, found := s.releases[parts[1]]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(http.StatusOK, rel)
+	case r.Method == http.MethodPost && parts[0] == "uploads":
+		f, fh, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		if _, err := io.Copy(io.Discard, f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(http.StatusCreated, map[string]string{"url": "/uploads/abc/" + fh.Filename})
+	case r.Method == http.MethodPost && len(parts) == 4 && parts[3] == "links":
+		rel, found := s.releases[parts[1]]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		var link gitLabLink
+		if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.nextID++
+		link.ID = s.nextID
+		rel.Assets.Links = append(rel.Assets.Links, link)
+		writeJSON(http.StatusCreated, link)
+	case r.Method == http.MethodDelete && len(parts) == 5 && parts[3] == "links":
+		rel, found := s.releases[parts[1]]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		for i, link := range rel.Assets.Links {
+			if fmt.Sprint(link.ID) == parts[4] {
+				rel.Assets.Links = append(rel.Assets.Links[:i], rel.Assets.Links[i+1:]...)
+				writeJSON(http.StatusOK, link)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[1] == "commits":
+		if parts[2] != "abc123" {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(http.StatusOK, map[string]string{"author_email": "jane@example.org"})
+	default:
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+	}
+}
+
+func TestGitLabClient(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	fake := &fakeGitLab{releases: make(map[string]*gitLabRelease)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := newGitLabClient(server.URL, "secret", server.Client())
+
+	tempDir := t.TempDir()
+	notes := filepath.Join(tempDir, "release-notes.md")
+	c.Assert(os.WriteFile(notes, []byte("## Changes"), 0o644), qt.IsNil)
+	archive := filepath.Join(tempDir, "hugo_1.2.0_linux-amd64.tar.gz")
+	c.Assert(os.WriteFile(archive, []byte("archive"), 0o644), qt.IsNil)
+
+	info := ReleaseInfo{
+		Tag:       "v1.2.0",
+		Commitish: "main",
+		Settings: config.ReleaseSettings{
+			RepositoryOwner:      "group/sub",
+			Repository:           "hugo",
+			ReleaseNotesSettings: config.ReleaseNotesSettings{Filename: notes},
+		},
+	}
+
+	releaseID, err := client.FindRelease(ctx, info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(releaseID, qt.Equals, int64(0))
+
+	releaseID, err = client.Release(ctx, info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(releaseID, qt.Equals, int64(gitLabReleaseID))
+
+	_, err = client.Release(ctx, info)
+	c.Assert(err, qt.ErrorMatches, `gitlab: unexpected status code 409: release already exists`)
+	c.Assert(isTemporaryError(err), qt.IsFalse)
+
+	f, err := os.Open(archive)
+	c.Assert(err, qt.IsNil)
+	c.Assert(client.UploadAssetsFile(ctx, info, f, "Linux", releaseID), qt.IsNil)
+	f.Close()
+
+	c.Assert(fake.releases["v1.2.0"].Assets.Links, qt.DeepEquals, []gitLabLink{
+		{ID: 1, Name: "hugo_1.2.0_linux-amd64.tar.gz", URL: server.URL + "/group/sub/hugo/uploads/abc/hugo_1.2.0_linux-amd64.tar.gz", LinkType: "package"},
+	})
+
+	releaseID, err = client.FindRelease(ctx, info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(releaseID, qt.Equals, int64(gitLabReleaseID))
+	c.Assert(VerifyAssets(ctx, client, info, releaseID, false, archive), qt.IsNil)
+
+	username, err := client.ResolveUsername(ctx, "abc123", "Jane", info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(username, qt.Equals, "jane")
+	username, err = client.ResolveUsername(ctx, "def456", "John", info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(username, qt.Equals, "")
+
+	c.Assert(client.DeleteAsset(ctx, info, releaseID, Asset{Name: "hugo_1.2.0_linux-amd64.tar.gz"}), qt.IsNil)
+	// Already deleted.
+	c.Assert(client.DeleteAsset(ctx, info, releaseID, Asset{Name: "hugo_1.2.0_linux-amd64.tar.gz"}), qt.IsNil)
+	assets, err := client.ListAssets(ctx, info, releaseID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(assets, qt.HasLen, 0)
+
+	for _, token := range fake.tokens {
+		c.Assert(token, qt.Equals, "secret")
+	}
+}
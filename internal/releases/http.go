@@ -0,0 +1,44 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gohugoio/hugoreleaser/internal/config"
+)
+
+// newHTTPClient creates a new HTTP client with the timeouts and TLS settings in settings applied.
+func newHTTPClient(settings config.HTTPSettings) *http.Client {
+	return &http.Client{
+		Timeout: settings.TimeoutParsed,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   settings.ConnectTimeoutParsed,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   settings.ConnectTimeoutParsed,
+			ResponseHeaderTimeout: settings.ReadTimeoutParsed,
+			ExpectContinueTimeout: 1 * time.Second,
+			TLSClientConfig:       settings.TLSConfig,
+		},
+	}
+}
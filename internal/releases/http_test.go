@@ -0,0 +1,125 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+)
+
+func TestNewHTTPClientMutualTLS(t *testing.T) {
+	c := qt.New(t)
+
+	// A CA to sign the client certificate with.
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, qt.IsNil)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	c.Assert(err, qt.IsNil)
+	caCert, err := x509.ParseCertificate(caDER)
+	c.Assert(err, qt.IsNil)
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, qt.IsNil)
+	clientDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, caCert, &clientKey.PublicKey, caKey)
+	c.Assert(err, qt.IsNil)
+	clientKeyDER, err := x509.MarshalECPrivateKey(clientKey)
+	c.Assert(err, qt.IsNil)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(caCert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	// The project dir.
+	tempDir := t.TempDir()
+	writePEM := func(name, typ string, b []byte) string {
+		filename := filepath.Join(tempDir, name)
+		c.Assert(os.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: b}), 0o600), qt.IsNil)
+		// Relative to the project dir.
+		return name
+	}
+
+	newClient := func(settings config.HTTPSettings) *http.Client {
+		c.Helper()
+		c.Assert(settings.Init(tempDir), qt.IsNil)
+		return newHTTPClient(settings)
+	}
+
+	serverCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	c.Run("Client certificate", func(c *qt.C) {
+		client := newClient(config.HTTPSettings{
+			// Inline PEM, e.g. from an environment variable.
+			CAFile:   serverCA,
+			CertFile: writePEM("client.crt", "CERTIFICATE", clientDER),
+			KeyFile:  writePEM("client.key", "EC PRIVATE KEY", clientKeyDER),
+		})
+		resp, err := client.Get(server.URL)
+		c.Assert(err, qt.IsNil)
+		defer resp.Body.Close()
+		c.Assert(resp.StatusCode, qt.Equals, http.StatusOK)
+	})
+
+	c.Run("No client certificate", func(c *qt.C) {
+		client := newClient(config.HTTPSettings{CAFile: serverCA})
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		c.Assert(err, qt.IsNotNil)
+	})
+
+	c.Run("Invalid settings", func(c *qt.C) {
+		settings := config.HTTPSettings{CertFile: "client.crt"}
+		c.Assert(settings.Init(tempDir), qt.ErrorMatches, "http_settings: cert_file and key_file must be set together")
+		settings = config.HTTPSettings{CAFile: filepath.Join(tempDir, "nosuchfile.pem")}
+		c.Assert(settings.Init(tempDir), qt.ErrorMatches, "http_settings: ca_file: .*no such file.*")
+	})
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// UploadProgressInterval is the interval between calls to ReleaseInfo.OnUploadProgress.
+var UploadProgressInterval = 10 * time.Second
+
+type uploadProgressKey struct{}
+
+// uploadBody returns the body to send when uploading f.
+// If the upload progress is reported (see UploadAssetsFileWithRetries), the bytes read are counted.
+// The clients still get the *os.File itself, e.g. for its name and size, but must read the content from the body.
+func uploadBody(ctx context.Context, f *os.File) io.ReadSeeker {
+	if n, ok := ctx.Value(uploadProgressKey{}).(*atomic.Int64); ok {
+		return &countingReader{r: f, n: n}
+	}
+	return f
+}
+
+// countingReader counts the bytes read from r in n.
+type countingReader struct {
+	r io.ReadSeeker
+	n *atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
+
+// Seek resets the count to the new offset, e.g. when a client reads the body
+// to sign it before sending it.
+func (r *countingReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.r.Seek(offset, whence)
+	if err == nil {
+		r.n.Store(pos)
+	}
+	return pos, err
+}
+
+// reportUploadProgress calls onProgress every UploadProgressInterval with the number of
+// bytes read from the body returned by uploadBody for f until the returned stop func is called.
+func reportUploadProgress(ctx context.Context, f *os.File, onProgress func(filename string, n, size int64)) (_ context.Context, stop func(), err error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	n := new(atomic.Int64)
+	ctx = context.WithValue(ctx, uploadProgressKey{}, n)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	ticker := time.NewTicker(UploadProgressInterval)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				onProgress(f.Name(), n.Load(), fi.Size())
+			}
+		}
+	}()
+
+	return ctx, func() {
+		ticker.Stop()
+		close(done)
+		wg.Wait()
+	}, nil
+}
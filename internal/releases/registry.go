@@ -0,0 +1,75 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gohugoio/hugoreleaser/internal/config"
+	"github.com/gohugoio/hugoreleaser/internal/releases/releasetypes"
+)
+
+// ClientFactory creates release clients for a release type.
+type ClientFactory interface {
+	// Validate checks that the environment is set up for the client,
+	// e.g. that any needed tokens are set.
+	Validate() error
+
+	// New creates a new client for the given settings.
+	New(ctx context.Context, settings config.ReleaseSettings) (Client, error)
+}
+
+// AssetSizeLimiter may be implemented by a ClientFactory to report the max size of a single release asset.
+type AssetSizeLimiter interface {
+	MaxAssetSize() int64
+}
+
+var clientFactories = make(map[releasetypes.Type]ClientFactory)
+
+// RegisterClient registers the factory for the given release type, see releasetypes.Register.
+// This is intended to be called from init functions and is not safe for concurrent use.
+func RegisterClient(typ releasetypes.Type, factory ClientFactory) {
+	if _, found := clientFactories[typ]; found {
+		panic(fmt.Sprintf("release: client for type %q already registered", typ))
+	}
+	clientFactories[typ] = factory
+}
+
+// Validate validates the release type.
+func Validate(typ releasetypes.Type) error {
+	factory, found := clientFactories[typ]
+	if !found {
+		return fmt.Errorf("release: no client registered for release type %q", typ)
+	}
+	return factory.Validate()
+}
+
+// NewClient creates a new release client for the given settings.
+func NewClient(ctx context.Context, settings config.ReleaseSettings) (Client, error) {
+	if err := Validate(settings.TypeParsed); err != nil {
+		return nil, err
+	}
+	return clientFactories[settings.TypeParsed].New(ctx, settings)
+}
+
+// MaxAssetSize returns the max size in bytes of a single asset for the given release type,
+// 0 if not known.
+func MaxAssetSize(typ releasetypes.Type) int64 {
+	if limiter, ok := clientFactories[typ].(AssetSizeLimiter); ok {
+		return limiter.MaxAssetSize()
+	}
+	return 0
+}
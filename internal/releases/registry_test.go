@@ -0,0 +1,70 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+	"github.com/gohugoio/hugoreleaser/internal/releases/releasetypes"
+)
+
+type testClientFactory struct {
+	validateErr error
+}
+
+func (f testClientFactory) Validate() error {
+	return f.validateErr
+}
+
+func (f testClientFactory) New(ctx context.Context, settings config.ReleaseSettings) (Client, error) {
+	return &FakeClient{}, nil
+}
+
+func TestRegisterClient(t *testing.T) {
+	c := qt.New(t)
+
+	ctx := context.Background()
+
+	typ, err := releasetypes.Register("testclient")
+	c.Assert(err, qt.IsNil)
+	c.Assert(releasetypes.MustParse("TestClient"), qt.Equals, typ)
+
+	c.Assert(Validate(typ), qt.ErrorMatches, `release: no client registered for release type "testclient"`)
+
+	RegisterClient(typ, testClientFactory{})
+	c.Assert(func() { RegisterClient(typ, testClientFactory{}) }, qt.PanicMatches, `.*already registered`)
+
+	client, err := NewClient(ctx, config.ReleaseSettings{TypeParsed: typ})
+	c.Assert(err, qt.IsNil)
+	_, ok := client.(*FakeClient)
+	c.Assert(ok, qt.IsTrue)
+
+	typ2, err := releasetypes.Register("testclientinvalid")
+	c.Assert(err, qt.IsNil)
+	RegisterClient(typ2, testClientFactory{validateErr: errors.New("missing token")})
+	_, err = NewClient(ctx, config.ReleaseSettings{TypeParsed: typ2})
+	c.Assert(err, qt.ErrorMatches, "missing token")
+}
+
+func TestMaxAssetSize(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(MaxAssetSize(releasetypes.GitHub), qt.Equals, int64(2<<30))
+	c.Assert(MaxAssetSize(releasetypes.InvalidType), qt.Equals, int64(0))
+}
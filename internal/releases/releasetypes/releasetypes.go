@@ -26,10 +26,16 @@ type Type int
 const (
 	InvalidType Type = iota
 	GitHub
+	GitLab
+	Gitea
+	S3
 )
 
 var releaseTypeString = map[Type]string{
 	GitHub: "github",
+	GitLab: "gitlab",
+	Gitea:  "gitea",
+	S3:     "s3",
 }
 
 var stringReleaseType = map[string]Type{}
@@ -40,6 +46,22 @@ func init() {
 	}
 }
 
+// Register registers a new release type with the given name, e.g. "forgejo".
+// This is intended to be called from init functions and is not safe for concurrent use.
+func Register(name string) (Type, error) {
+	name = strings.ToLower(name)
+	if name == "" {
+		return InvalidType, fmt.Errorf("release type name cannot be empty")
+	}
+	if _, found := stringReleaseType[name]; found {
+		return InvalidType, fmt.Errorf("release type %q is already registered", name)
+	}
+	t := Type(len(releaseTypeString) + 1)
+	releaseTypeString[t] = name
+	stringReleaseType[name] = t
+	return t, nil
+}
+
 func (t Type) String() string {
 	return releaseTypeString[t]
 }
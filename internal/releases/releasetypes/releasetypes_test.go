@@ -16,3 +16,18 @@ func TestType(t *testing.T) {
 	c.Assert(func() { MustParse("invalid") }, qt.PanicMatches, `invalid.*`)
 
 }
+
+func TestRegister(t *testing.T) {
+	c := qt.New(t)
+
+	typ, err := Register("MyType")
+	c.Assert(err, qt.IsNil)
+	c.Assert(typ, qt.Not(qt.Equals), GitHub)
+	c.Assert(typ.String(), qt.Equals, "mytype")
+	c.Assert(MustParse("mytype"), qt.Equals, typ)
+
+	_, err = Register("github")
+	c.Assert(err, qt.ErrorMatches, `release type "github" is already registered`)
+	_, err = Register("")
+	c.Assert(err, qt.Not(qt.IsNil))
+}
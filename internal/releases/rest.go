@@ -0,0 +1,115 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// restClient is a minimal client for the JSON REST APIs of the GitLab and Gitea release clients.
+type restClient struct {
+	// Used in error messages, e.g. "gitlab".
+	name string
+
+	httpClient *http.Client
+
+	// setAuth adds the credentials to the request.
+	setAuth func(req *http.Request)
+}
+
+// statusError is returned for unexpected HTTP status codes.
+type statusError struct {
+	client     string
+	StatusCode int
+	Message    string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s: unexpected status code %d: %s", e.client, e.StatusCode, e.Message)
+}
+
+func isNotFoundError(err error) bool {
+	var serr *statusError
+	return errors.As(err, &serr) && serr.StatusCode == http.StatusNotFound
+}
+
+func (c *restClient) doJSON(ctx context.Context, method, u string, in, out any) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, method, u, "application/json", bytes.NewReader(b), out)
+}
+
+// doMultipart streams r as a multipart form file in field with the given filename.
+func (c *restClient) doMultipart(ctx context.Context, u, field, filename string, r io.Reader, out any) error {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		part, err := mw.CreateFormFile(field, filename)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return c.do(ctx, http.MethodPost, u, mw.FormDataContentType(), pr, out)
+}
+
+// do sends the request and decodes the JSON response into out, if set.
+// Network errors, rate limits and server errors are returned as a TemporaryError.
+func (c *restClient) do(ctx context.Context, method, u, contentType string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	c.setAuth(req)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return TemporaryError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := &statusError{client: c.name, StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+		// Other client errors, e.g. 409 when the release already exists, will not go away by retrying.
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return TemporaryError{err}
+		}
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
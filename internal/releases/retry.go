@@ -1,19 +1,52 @@
 package releases
 
 import (
+	"context"
 	"math/rand"
 	"time"
 )
 
-const numRetries = 10
+// DefaultRetrySettings is used when the RetrySettings are not set.
+var DefaultRetrySettings = RetrySettings{
+	MaxRetries:   9,
+	InitialDelay: 77 * time.Millisecond,
+}
+
+// RetrySettings configures how temporary errors (e.g. from uploads) are retried.
+type RetrySettings struct {
+	// Set must be true for the settings below to be used, even if they're all zero.
+	// If false, DefaultRetrySettings is used.
+	Set bool
+
+	// The max number of retries after the first attempt.
+	MaxRetries int
+
+	// The delay before the first retry. The delay grows by a random amount up to 2x per retry.
+	InitialDelay time.Duration
+
+	// The max delay between two retries. 0 means no limit.
+	MaxDelay time.Duration
+
+	// OnRetry, if set, is called before each retry with the retry number (starting at 1),
+	// the max number of retries in use, the delay before the retry and the temporary error that caused it.
+	OnRetry func(retry, maxRetries int, delay time.Duration, err error)
+}
+
+// withRetries calls f until it succeeds, returns an error that should not be retried or the retries are used up.
+// Waiting for the next retry stops when ctx is cancelled.
+func withRetries(ctx context.Context, settings RetrySettings, f func() (err error, shouldTryAgain bool)) error {
+	if !settings.Set {
+		onRetry := settings.OnRetry
+		settings = DefaultRetrySettings
+		settings.OnRetry = onRetry
+	}
 
-func withRetries(f func() (err error, shouldTryAgain bool)) error {
 	var (
 		lastErr      error
-		nextInterval time.Duration = 77 * time.Millisecond
+		nextInterval = settings.InitialDelay
 	)
 
-	for i := 0; i < numRetries; i++ {
+	for i := 0; i <= settings.MaxRetries; i++ {
 		err, shouldTryAgain := f()
 		if err == nil || !shouldTryAgain {
 			return err
@@ -21,8 +54,28 @@ func withRetries(f func() (err error, shouldTryAgain bool)) error {
 
 		lastErr = err
 
-		time.Sleep(nextInterval)
-		nextInterval += time.Duration(rand.Int63n(int64(nextInterval)))
+		if i == settings.MaxRetries {
+			break
+		}
+
+		if settings.OnRetry != nil {
+			settings.OnRetry(i+1, settings.MaxRetries, nextInterval, err)
+		}
+
+		timer := time.NewTimer(nextInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if nextInterval > 0 {
+			nextInterval += time.Duration(rand.Int63n(int64(nextInterval)))
+		}
+		if settings.MaxDelay > 0 && nextInterval > settings.MaxDelay {
+			nextInterval = settings.MaxDelay
+		}
 	}
 
 	return lastErr
@@ -0,0 +1,147 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestWithRetries(t *testing.T) {
+	c := qt.New(t)
+
+	errTemp := errors.New("temporary")
+
+	c.Run("Max retries", func(c *qt.C) {
+		var calls int
+		err := withRetries(context.Background(), RetrySettings{Set: true, MaxRetries: 3, InitialDelay: time.Millisecond}, func() (error, bool) {
+			calls++
+			return errTemp, true
+		})
+		c.Assert(err, qt.Equals, errTemp)
+		c.Assert(calls, qt.Equals, 4)
+	})
+
+	c.Run("No retries", func(c *qt.C) {
+		var calls int
+		err := withRetries(context.Background(), RetrySettings{Set: true}, func() (error, bool) {
+			calls++
+			return errTemp, true
+		})
+		c.Assert(err, qt.Equals, errTemp)
+		c.Assert(calls, qt.Equals, 1)
+	})
+
+	c.Run("On retry", func(c *qt.C) {
+		var retries []int
+		settings := RetrySettings{
+			Set:          true,
+			MaxRetries:   2,
+			InitialDelay: time.Millisecond,
+			OnRetry: func(retry, maxRetries int, delay time.Duration, err error) {
+				c.Assert(err, qt.Equals, errTemp)
+				c.Assert(maxRetries, qt.Equals, 2)
+				c.Assert(delay >= time.Millisecond, qt.IsTrue)
+				retries = append(retries, retry)
+			},
+		}
+		err := withRetries(context.Background(), settings, func() (error, bool) {
+			return errTemp, true
+		})
+		c.Assert(err, qt.Equals, errTemp)
+		c.Assert(retries, qt.DeepEquals, []int{1, 2})
+	})
+
+	c.Run("Permanent error", func(c *qt.C) {
+		var calls int
+		err := withRetries(context.Background(), RetrySettings{Set: true, MaxRetries: 3}, func() (error, bool) {
+			calls++
+			return errTemp, false
+		})
+		c.Assert(err, qt.Equals, errTemp)
+		c.Assert(calls, qt.Equals, 1)
+	})
+
+	c.Run("Max delay", func(c *qt.C) {
+		var (
+			calls int
+			last  time.Time
+			gaps  []time.Duration
+		)
+		err := withRetries(context.Background(), RetrySettings{Set: true, MaxRetries: 8, InitialDelay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond}, func() (error, bool) {
+			calls++
+			if !last.IsZero() {
+				gaps = append(gaps, time.Since(last))
+			}
+			last = time.Now()
+			if calls == 6 {
+				return nil, false
+			}
+			return errTemp, true
+		})
+		c.Assert(err, qt.IsNil)
+		c.Assert(calls, qt.Equals, 6)
+		for _, gap := range gaps {
+			// Allow some slack for slow test machines.
+			c.Assert(gap < 20*time.Millisecond+50*time.Millisecond, qt.IsTrue, qt.Commentf("%v", gaps))
+		}
+	})
+
+	c.Run("Cancelled while waiting", func(c *qt.C) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls int
+		start := time.Now()
+		err := withRetries(ctx, RetrySettings{Set: true, MaxRetries: 3, InitialDelay: time.Hour}, func() (error, bool) {
+			calls++
+			cancel()
+			return errTemp, true
+		})
+		c.Assert(err, qt.Equals, context.Canceled)
+		c.Assert(calls, qt.Equals, 1)
+		c.Assert(time.Since(start) < time.Minute, qt.IsTrue)
+	})
+
+	c.Run("Defaults", func(c *qt.C) {
+		var calls int
+		settings := RetrySettings{
+			OnRetry: func(retry, maxRetries int, delay time.Duration, err error) {
+				c.Assert(maxRetries, qt.Equals, DefaultRetrySettings.MaxRetries)
+			},
+		}
+		err := withRetries(context.Background(), settings, func() (error, bool) {
+			calls++
+			if calls == 2 {
+				return nil, false
+			}
+			return errTemp, true
+		})
+		c.Assert(err, qt.IsNil)
+		c.Assert(calls, qt.Equals, 2)
+	})
+}
+
+func TestIsTemporaryError(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(isTemporaryError(TemporaryError{errors.New("temporary")}), qt.IsTrue)
+	c.Assert(isTemporaryError(errors.New("permanent")), qt.IsFalse)
+	c.Assert(isTemporaryError(TemporaryError{context.Canceled}), qt.IsFalse)
+	c.Assert(isTemporaryError(TemporaryError{fmt.Errorf("upload: %w", context.DeadlineExceeded)}), qt.IsFalse)
+}
@@ -0,0 +1,240 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/gohugoio/hugoreleaser/internal/common/templ"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+	"github.com/gohugoio/hugoreleaser/internal/releases/releasetypes"
+)
+
+// S3 has no releases, the files for a tag are stored below the same prefix, so all releases get this ID.
+const s3ReleaseID = 1
+
+func init() {
+	RegisterClient(releasetypes.S3, s3ClientFactory{})
+}
+
+// The AWS SDK reads the credentials from this and other sources, it's checked here for fakeToken only.
+const s3AccessKeyEnvVar = "AWS_ACCESS_KEY_ID"
+
+type s3ClientFactory struct{}
+
+// Validate is a no-op, the credentials are resolved by the AWS SDK when the client is created.
+func (s3ClientFactory) Validate() error {
+	return nil
+}
+
+func (s3ClientFactory) New(ctx context.Context, settings config.ReleaseSettings) (Client, error) {
+	// Set in tests and when running with the -try or -snapshot flag.
+	if os.Getenv(s3AccessKeyEnvVar) == fakeToken {
+		return newFakeClient(), nil
+	}
+
+	var opts []func(*awsconfig.LoadOptions) error
+	opts = append(opts, awsconfig.WithHTTPClient(newHTTPClient(settings.HTTPSettings)))
+	if settings.S3Settings.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(settings.S3Settings.Region))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newS3Client(cfg, settings.BaseURL), nil
+}
+
+func newS3Client(cfg aws.Config, endpoint string) *S3Client {
+	return &S3Client{
+		client: s3.NewFromConfig(cfg, func(o *s3.Options) {
+			if endpoint != "" {
+				// MinIO and friends don't support virtual hosted buckets by default.
+				o.EndpointResolver = s3.EndpointResolverFromURL(endpoint)
+				o.UsePathStyle = true
+			}
+		}),
+	}
+}
+
+var (
+	_ ReleaseFinder = &S3Client{}
+	_ AssetDeleter  = &S3Client{}
+)
+
+// S3Client uploads the release files to an S3 compatible bucket,
+// below s3_settings.prefix/tag/, and optionally copies them to s3_settings.latest_key_template.
+// There are no release notes, labels or drafts.
+type S3Client struct {
+	client *s3.Client
+}
+
+func (c *S3Client) Release(ctx context.Context, info ReleaseInfo) (int64, error) {
+	return s3ReleaseID, nil
+}
+
+func (c *S3Client) UploadAssetsFile(ctx context.Context, info ReleaseInfo, f *os.File, label string, releaseID int64) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(info.Settings.S3Settings.Bucket),
+		Key:           aws.String(s3Key(info, filepath.Base(f.Name()))),
+		Body:          uploadBody(ctx, f),
+		ContentLength: fi.Size(),
+	}
+	if info.Settings.S3Settings.PublicRead {
+		input.ACL = types.ObjectCannedACLPublicRead
+	}
+
+	if _, err = c.client.PutObject(ctx, input); err != nil {
+		return s3Error(err)
+	}
+
+	latestKey, err := s3LatestKey(info, filepath.Base(f.Name()))
+	if err != nil || latestKey == "" {
+		return err
+	}
+
+	// Copy the file on the server, so it's uploaded once.
+	copyInput := &s3.CopyObjectInput{
+		Bucket:     input.Bucket,
+		Key:        aws.String(latestKey),
+		CopySource: aws.String((&url.URL{Path: info.Settings.S3Settings.Bucket + "/" + aws.ToString(input.Key)}).EscapedPath()),
+		ACL:        input.ACL,
+	}
+	_, err = c.client.CopyObject(ctx, copyInput)
+
+	return s3Error(err)
+}
+
+// FindRelease returns s3ReleaseID if there are any files below the prefix for the tag, else 0.
+func (c *S3Client) FindRelease(ctx context.Context, info ReleaseInfo) (int64, error) {
+	out, err := c.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(info.Settings.S3Settings.Bucket),
+		Prefix:  aws.String(s3Key(info, "")),
+		MaxKeys: 1,
+	})
+	if err != nil {
+		return 0, s3Error(err)
+	}
+	if len(out.Contents) == 0 {
+		return 0, nil
+	}
+	return s3ReleaseID, nil
+}
+
+func (c *S3Client) ListAssets(ctx context.Context, info ReleaseInfo, releaseID int64) ([]Asset, error) {
+	var assets []Asset
+	prefix := s3Key(info, "")
+	paginator := s3.NewListObjectsV2Paginator(c.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(info.Settings.S3Settings.Bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, s3Error(err)
+		}
+		for _, obj := range page.Contents {
+			name := strings.TrimPrefix(aws.ToString(obj.Key), prefix)
+			if strings.Contains(name, "/") {
+				// Not uploaded by us.
+				continue
+			}
+			assets = append(assets, Asset{Name: name, Size: obj.Size})
+		}
+	}
+
+	return assets, nil
+}
+
+// DeleteAsset deletes the file with the asset's name, a no-op if it does not exist.
+func (c *S3Client) DeleteAsset(ctx context.Context, info ReleaseInfo, releaseID int64, asset Asset) error {
+	_, err := c.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(info.Settings.S3Settings.Bucket),
+		Key:    aws.String(s3Key(info, asset.Name)),
+	})
+	return s3Error(err)
+}
+
+// s3Key returns the object key for name, or the prefix with a trailing slash if name is empty.
+func s3Key(info ReleaseInfo, name string) string {
+	prefix := info.Settings.S3Settings.Prefix
+	if prefix == "" {
+		prefix = info.Project
+	}
+	key := path.Join(strings.Trim(prefix, "/"), info.Tag)
+	if name == "" {
+		return key + "/"
+	}
+	return key + "/" + name
+}
+
+// s3LatestKey returns the key to copy name to from s3_settings.latest_key_template,
+// or an empty string if it's not set or if this is a prerelease and prereleases are not included.
+func s3LatestKey(info ReleaseInfo, name string) (string, error) {
+	settings := info.Settings.S3Settings
+	if settings.LatestKeyTemplate == "" || (info.Settings.Prerelease && !settings.LatestIncludePrerelease) {
+		return "", nil
+	}
+	data := config.LatestKeyContext{
+		Project: info.Project,
+		Tag:     info.Tag,
+		Version: strings.TrimPrefix(info.Tag, "v"),
+		Name:    name,
+	}
+	key, err := templ.Sprintt(settings.LatestKeyTemplate, data)
+	if err != nil {
+		return "", fmt.Errorf("s3_settings: latest_key_template: %v", err)
+	}
+	prefix := settings.Prefix
+	if prefix == "" {
+		prefix = info.Project
+	}
+	return path.Join(strings.Trim(prefix, "/"), key), nil
+}
+
+// s3Error marks err as temporary unless it's a client error other than a rate limit.
+// Note that the AWS SDK already retries some errors.
+func s3Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	var rerr *smithyhttp.ResponseError
+	if errors.As(err, &rerr) {
+		status := rerr.HTTPStatusCode()
+		if status < 500 && status != http.StatusTooManyRequests {
+			return err
+		}
+	}
+	return TemporaryError{err}
+}
@@ -0,0 +1,214 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+)
+
+// fakeS3 is a minimal in-memory, path style S3 API for bucket "mybucket".
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]int64
+	acls    map[string]string
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	const bucketPrefix = "/mybucket"
+	if !strings.HasPrefix(r.URL.Path, bucketPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, bucketPrefix), "/")
+
+	switch {
+	case r.Method == http.MethodPut && key != "" && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		size, found := s.objects[strings.TrimPrefix(strings.TrimPrefix(source, "/"), "mybucket/")]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		s.objects[key] = size
+		s.acls[key] = r.Header.Get("X-Amz-Acl")
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, `<CopyObjectResult><ETag>"abc"</ETag></CopyObjectResult>`)
+	case r.Method == http.MethodPut && key != "":
+		size, err := io.Copy(io.Discard, r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.objects[key] = size
+		s.acls[key] = r.Header.Get("X-Amz-Acl")
+	case r.Method == http.MethodDelete && key != "":
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && key == "" && r.URL.Query().Get("list-type") == "2":
+		type object struct {
+			Key  string
+			Size int64
+		}
+		result := struct {
+			XMLName     xml.Name `xml:"ListBucketResult"`
+			Name        string
+			IsTruncated bool
+			Contents    []object
+		}{Name: "mybucket"}
+		prefix := r.URL.Query().Get("prefix")
+		for k, size := range s.objects {
+			if strings.HasPrefix(k, prefix) {
+				result.Contents = append(result.Contents, object{Key: k, Size: size})
+			}
+		}
+		sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(result)
+	default:
+		http.Error(w, "unexpected request", http.StatusNotImplemented)
+	}
+}
+
+func TestS3Client(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	fake := &fakeS3{objects: make(map[string]int64), acls: make(map[string]string)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := newS3Client(aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		HTTPClient:  server.Client(),
+	}, server.URL)
+
+	archive := filepath.Join(t.TempDir(), "hugo_1.2.0_linux-amd64.tar.gz")
+	c.Assert(os.WriteFile(archive, []byte("archive"), 0o644), qt.IsNil)
+
+	info := ReleaseInfo{
+		Project: "hugo",
+		Tag:     "v1.2.0",
+		Settings: config.ReleaseSettings{
+			S3Settings: config.S3Settings{Bucket: "mybucket", Prefix: "/downloads/", PublicRead: true},
+		},
+	}
+
+	releaseID, err := client.FindRelease(ctx, info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(releaseID, qt.Equals, int64(0))
+
+	releaseID, err = client.Release(ctx, info)
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(UploadAssetsFileWithRetries(ctx, client, info, "", releaseID, func() (*os.File, error) {
+		return os.Open(archive)
+	}), qt.IsNil)
+	c.Assert(fake.objects, qt.DeepEquals, map[string]int64{"downloads/v1.2.0/hugo_1.2.0_linux-amd64.tar.gz": 7})
+	c.Assert(fake.acls["downloads/v1.2.0/hugo_1.2.0_linux-amd64.tar.gz"], qt.Equals, "public-read")
+
+	// Not part of the release.
+	fake.objects["downloads/v1.2.0/sub/other.txt"] = 3
+
+	releaseID, err = client.FindRelease(ctx, info)
+	c.Assert(err, qt.IsNil)
+	c.Assert(releaseID, qt.Equals, int64(s3ReleaseID))
+	c.Assert(VerifyAssets(ctx, client, info, releaseID, false, archive), qt.IsNil)
+
+	c.Assert(client.DeleteAsset(ctx, info, releaseID, Asset{Name: "hugo_1.2.0_linux-amd64.tar.gz"}), qt.IsNil)
+	assets, err := client.ListAssets(ctx, info, releaseID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(assets, qt.HasLen, 0)
+
+	info.Settings.S3Settings.Bucket = "otherbucket"
+	_, err = client.ListAssets(ctx, info, releaseID)
+	c.Assert(err, qt.Not(qt.IsNil))
+	c.Assert(isTemporaryError(err), qt.IsFalse)
+}
+
+func TestS3ClientLatestKey(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	fake := &fakeS3{objects: make(map[string]int64), acls: make(map[string]string)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := newS3Client(aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		HTTPClient:  server.Client(),
+	}, server.URL)
+
+	archive := filepath.Join(t.TempDir(), "hugo_1.2.0_linux-amd64.tar.gz")
+	c.Assert(os.WriteFile(archive, []byte("archive"), 0o644), qt.IsNil)
+	openFile := func() (*os.File, error) {
+		return os.Open(archive)
+	}
+
+	info := ReleaseInfo{
+		Project: "hugo",
+		Tag:     "v1.2.0",
+		Settings: config.ReleaseSettings{
+			S3Settings: config.S3Settings{Bucket: "mybucket", PublicRead: true, LatestKeyTemplate: "latest/{{ replace .Name .Version `latest` }}"},
+		},
+	}
+
+	c.Assert(UploadAssetsFileWithRetries(ctx, client, info, "", s3ReleaseID, openFile), qt.IsNil)
+	c.Assert(fake.objects, qt.DeepEquals, map[string]int64{
+		"hugo/v1.2.0/hugo_1.2.0_linux-amd64.tar.gz":  7,
+		"hugo/latest/hugo_latest_linux-amd64.tar.gz": 7,
+	})
+	c.Assert(fake.acls["hugo/latest/hugo_latest_linux-amd64.tar.gz"], qt.Equals, "public-read")
+
+	// The latest files are not part of the release.
+	assets, err := client.ListAssets(ctx, info, s3ReleaseID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(assets, qt.HasLen, 1)
+
+	// Prereleases are skipped by default.
+	fake.objects = make(map[string]int64)
+	info.Settings.Prerelease = true
+	c.Assert(UploadAssetsFileWithRetries(ctx, client, info, "", s3ReleaseID, openFile), qt.IsNil)
+	c.Assert(fake.objects, qt.DeepEquals, map[string]int64{"hugo/v1.2.0/hugo_1.2.0_linux-amd64.tar.gz": 7})
+
+	info.Settings.S3Settings.LatestIncludePrerelease = true
+	c.Assert(UploadAssetsFileWithRetries(ctx, client, info, "", s3ReleaseID, openFile), qt.IsNil)
+	c.Assert(fake.objects, qt.HasLen, 2)
+}
@@ -0,0 +1,186 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+)
+
+// SignatureExtension is the extension added to the filename of the signed file.
+const SignatureExtension = ".asc"
+
+// The key in the settings may be shared between releases running in parallel.
+var decryptMu sync.Mutex
+
+// SignFile writes an armored detached signature of filename to dir, named
+// the base of filename plus SignatureExtension, and returns the signature filename.
+// The key is taken from settings.Key if set, else from the gpg keyring by settings.KeyID.
+func SignFile(ctx context.Context, settings config.SigningSettings, filename, dir string) (string, error) {
+	signatureFilename := filepath.Join(dir, filepath.Base(filename)+SignatureExtension)
+	passphrase := os.Getenv(settings.PassphraseEnv)
+
+	var err error
+	if settings.Key != nil {
+		err = signWithKey(settings.Key, passphrase, filename, signatureFilename)
+	} else {
+		err = signWithGPG(ctx, settings.KeyID, passphrase, filename, signatureFilename)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %q: %v", filename, err)
+	}
+
+	return signatureFilename, nil
+}
+
+// WritePublicKey writes the armored public key of the signing key to settings.PublicKeyFilename in dir
+// and returns the filename.
+// The key is taken from settings.Key if set, else it's exported from the gpg keyring by settings.KeyID.
+func WritePublicKey(ctx context.Context, settings config.SigningSettings, dir string) (string, error) {
+	filename := filepath.Join(dir, settings.PublicKeyFilename)
+
+	var (
+		b   []byte
+		err error
+	)
+	if settings.Key != nil {
+		b, err = armoredPublicKey(settings.Key)
+	} else {
+		b, err = exportPublicKeyWithGPG(ctx, settings.KeyID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to export the public key: %v", err)
+	}
+
+	return filename, os.WriteFile(filename, b, 0o644)
+}
+
+func armoredPublicKey(key *openpgp.Entity) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := key.Serialize(w); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+func exportPublicKeyWithGPG(ctx context.Context, keyID string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "gpg", "--batch", "--armor", "--export", keyID)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("gpg failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("no public key found for %q", keyID)
+	}
+	return b, nil
+}
+
+func signWithKey(key *openpgp.Entity, passphrase, filename, signatureFilename string) error {
+	if err := decryptKey(key, passphrase); err != nil {
+		return err
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	out, err := os.Create(signatureFilename)
+	if err != nil {
+		return err
+	}
+
+	if err := openpgp.ArmoredDetachSign(out, key, f, nil); err != nil {
+		out.Close()
+		return err
+	}
+	if _, err := out.WriteString("\n"); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+func decryptKey(key *openpgp.Entity, passphrase string) error {
+	decryptMu.Lock()
+	defer decryptMu.Unlock()
+
+	// The signature may be made with a signing subkey.
+	privateKeys := []*packet.PrivateKey{key.PrivateKey}
+	for _, subkey := range key.Subkeys {
+		if subkey.PrivateKey != nil {
+			privateKeys = append(privateKeys, subkey.PrivateKey)
+		}
+	}
+
+	for _, privateKey := range privateKeys {
+		if !privateKey.Encrypted {
+			continue
+		}
+		if passphrase == "" {
+			return fmt.Errorf("the key is encrypted, but no passphrase is set")
+		}
+		if err := privateKey.Decrypt([]byte(passphrase)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func signWithGPG(ctx context.Context, keyID, passphrase, filename, signatureFilename string) error {
+	args := []string{"--batch", "--yes", "--armor", "--detach-sign", "--local-user", keyID, "--output", signatureFilename}
+	var stdin *strings.Reader
+	if passphrase != "" {
+		args = append(args, "--pinentry-mode", "loopback", "--passphrase-fd", "0")
+		stdin = strings.NewReader(passphrase)
+	}
+	args = append(args, filename)
+
+	cmd := exec.CommandContext(ctx, "gpg", args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("gpg failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 The Hugoreleaser Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package releases
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	qt "github.com/frankban/quicktest"
+	"github.com/gohugoio/hugoreleaser/internal/config"
+)
+
+func TestSignFile(t *testing.T) {
+	c := qt.New(t)
+
+	key, err := openpgp.NewEntity("Hugoreleaser Test", "", "test@example.org", nil)
+	c.Assert(err, qt.IsNil)
+
+	tempDir := t.TempDir()
+	filename := filepath.Join(tempDir, "checksums.txt")
+	c.Assert(os.WriteFile(filename, []byte("abc  hugo.tar.gz\n"), 0o644), qt.IsNil)
+	releaseDir := filepath.Join(tempDir, "release")
+	c.Assert(os.Mkdir(releaseDir, 0o755), qt.IsNil)
+
+	signatureFilename, err := SignFile(context.Background(), config.SigningSettings{Key: key}, filename, releaseDir)
+	c.Assert(err, qt.IsNil)
+	c.Assert(signatureFilename, qt.Equals, filepath.Join(releaseDir, "checksums.txt.asc"))
+
+	signed, err := os.Open(filename)
+	c.Assert(err, qt.IsNil)
+	defer signed.Close()
+	signature, err := os.Open(signatureFilename)
+	c.Assert(err, qt.IsNil)
+	defer signature.Close()
+
+	signer, err := openpgp.CheckArmoredDetachedSignature(openpgp.EntityList{key}, signed, signature, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(signer.PrimaryKey.KeyId, qt.Equals, key.PrimaryKey.KeyId)
+}
+
+func TestSignFileEncryptedSubkey(t *testing.T) {
+	c := qt.New(t)
+
+	key, err := openpgp.NewEntity("Hugoreleaser Test", "", "test@example.org", nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(key.AddSigningSubkey(nil), qt.IsNil)
+	passphrase := []byte("secret")
+	c.Assert(key.PrivateKey.Encrypt(passphrase), qt.IsNil)
+	for _, subkey := range key.Subkeys {
+		c.Assert(subkey.PrivateKey.Encrypt(passphrase), qt.IsNil)
+	}
+
+	tempDir := t.TempDir()
+	filename := filepath.Join(tempDir, "checksums.txt")
+	c.Assert(os.WriteFile(filename, []byte("abc  hugo.tar.gz\n"), 0o644), qt.IsNil)
+
+	settings := config.SigningSettings{Key: key, PassphraseEnv: "HUGORELEASER_TEST_GPG_PASSPHRASE"}
+	_, err = SignFile(context.Background(), settings, filename, tempDir)
+	c.Assert(err, qt.ErrorMatches, ".*no passphrase is set")
+
+	t.Setenv(settings.PassphraseEnv, string(passphrase))
+	signatureFilename, err := SignFile(context.Background(), settings, filename, tempDir)
+	c.Assert(err, qt.IsNil)
+
+	signed, err := os.Open(filename)
+	c.Assert(err, qt.IsNil)
+	defer signed.Close()
+	signature, err := os.Open(signatureFilename)
+	c.Assert(err, qt.IsNil)
+	defer signature.Close()
+
+	_, err = openpgp.CheckArmoredDetachedSignature(openpgp.EntityList{key}, signed, signature, nil)
+	c.Assert(err, qt.IsNil)
+}
+
+func TestWritePublicKey(t *testing.T) {
+	c := qt.New(t)
+
+	key, err := openpgp.NewEntity("Hugoreleaser Test", "", "test@example.org", nil)
+	c.Assert(err, qt.IsNil)
+
+	tempDir := t.TempDir()
+	filename := filepath.Join(tempDir, "checksums.txt")
+	c.Assert(os.WriteFile(filename, []byte("abc  hugo.tar.gz\n"), 0o644), qt.IsNil)
+
+	settings := config.SigningSettings{Key: key, PublicKeyFilename: "hugo_public_key.asc"}
+	publicKeyFilename, err := WritePublicKey(context.Background(), settings, tempDir)
+	c.Assert(err, qt.IsNil)
+	c.Assert(publicKeyFilename, qt.Equals, filepath.Join(tempDir, "hugo_public_key.asc"))
+	signatureFilename, err := SignFile(context.Background(), settings, filename, tempDir)
+	c.Assert(err, qt.IsNil)
+
+	publicKey, err := os.Open(publicKeyFilename)
+	c.Assert(err, qt.IsNil)
+	defer publicKey.Close()
+	keyRing, err := openpgp.ReadArmoredKeyRing(publicKey)
+	c.Assert(err, qt.IsNil)
+	c.Assert(keyRing, qt.HasLen, 1)
+	c.Assert(keyRing[0].PrivateKey, qt.IsNil)
+
+	// The signature can be verified with the public key only.
+	signed, err := os.Open(filename)
+	c.Assert(err, qt.IsNil)
+	defer signed.Close()
+	signature, err := os.Open(signatureFilename)
+	c.Assert(err, qt.IsNil)
+	defer signature.Close()
+	_, err = openpgp.CheckArmoredDetachedSignature(keyRing, signed, signature, nil)
+	c.Assert(err, qt.IsNil)
+}
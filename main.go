@@ -31,9 +31,13 @@ import (
 	"github.com/gohugoio/hugoreleaser/cmd/allcmd"
 	"github.com/gohugoio/hugoreleaser/cmd/archivecmd"
 	"github.com/gohugoio/hugoreleaser/cmd/buildcmd"
+	"github.com/gohugoio/hugoreleaser/cmd/cleancmd"
 	"github.com/gohugoio/hugoreleaser/cmd/corecmd"
+	"github.com/gohugoio/hugoreleaser/cmd/plancmd"
 	"github.com/gohugoio/hugoreleaser/cmd/releasecmd"
+	"github.com/gohugoio/hugoreleaser/cmd/versioncmd"
 	"github.com/gohugoio/hugoreleaser/internal/common/logging"
+	"github.com/gohugoio/hugoreleaser/internal/releases"
 	"github.com/peterbourgon/ff/v3"
 	"github.com/peterbourgon/ff/v3/ffcli"
 	"golang.org/x/sync/errgroup"
@@ -62,7 +66,11 @@ func parseAndRun(args []string) (err error) {
 		buildCommand      = buildcmd.New(core)
 		archiveCommand    = archivecmd.New(core)
 		releaseCommand    = releasecmd.New(core)
+		notesCommand      = releasecmd.NewNotes(core)
 		allCommand        = allcmd.New(core)
+		cleanCommand      = cleancmd.New(core)
+		planCommand       = plancmd.New(core)
+		versionCommand    = versioncmd.New(core)
 	)
 
 	coreCommand.Subcommands = []*ffcli.Command{
@@ -70,8 +78,18 @@ func parseAndRun(args []string) (err error) {
 		archiveCommand,
 		releaseCommand,
 		allCommand,
+		cleanCommand,
+		planCommand,
+		versionCommand,
 	}
 
+	releaseCommand.Subcommands = []*ffcli.Command{
+		notesCommand,
+	}
+
+	// Set when running commands that don't need a fully initialized Core.
+	var skipInit bool
+
 	opts := []ff.Option{
 		ff.WithEnvVarPrefix(corecmd.EnvPrefix),
 	}
@@ -84,11 +102,15 @@ func parseAndRun(args []string) (err error) {
 	releaseCommand.Options = []ff.Option{
 		ff.WithEnvVarPrefix(corecmd.EnvPrefix),
 	}
+	notesCommand.Options = opts
 
 	defer func() {
 		if closeErr := core.Close(); closeErr != nil && err == nil {
 			err = fmt.Errorf("error closing app: %w", err)
 		}
+		if skipInit {
+			return
+		}
 		elapsed := time.Since(start)
 		s := logg.String(fmt.Sprintf("Total in %s …", logging.FormatBuildDuration(elapsed)))
 		if core.InfoLog != nil {
@@ -106,8 +128,17 @@ func parseAndRun(args []string) (err error) {
 		return fmt.Errorf("error parsing command line: %w", err)
 	}
 
-	if core.Try {
-		os.Setenv("GITHUB_TOKEN", "faketoken")
+	// The version command does not need a -tag and should not print any log lines.
+	// The release notes command only needs the config and prints the notes to stdout.
+	skipInit = versionCommand.FlagSet.Parsed() || notesCommand.FlagSet.Parsed()
+
+	// The plan command needs the fully initialized config, but should not lock the dist directory.
+	if planCommand.FlagSet.Parsed() {
+		core.Try = true
+	}
+
+	if core.Try || core.Snapshot {
+		releases.UseFakeClients()
 	}
 
 	// Pass any non-empty flag value into the HUGORELEASER_ prefix in OS environment if not already set.
@@ -129,8 +160,10 @@ func parseAndRun(args []string) (err error) {
 	g, ctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
-		if err := core.Init(); err != nil {
-			return fmt.Errorf("error initializing config: %w", err)
+		if !skipInit {
+			if err := core.Init(); err != nil {
+				return fmt.Errorf("error initializing config: %w", err)
+			}
 		}
 		if err := coreCommand.Run(ctx); err != nil {
 			return fmt.Errorf("error running command: %w", err)
@@ -34,7 +34,9 @@ import (
 
 	"github.com/bep/helpers/envhelpers"
 	"github.com/bep/helpers/filehelpers"
+	"github.com/klauspost/compress/zstd"
 	"github.com/rogpeppe/go-internal/testscript"
+	"github.com/ulikunitz/xz"
 )
 
 // Note: If tests are running slow for you, make sure you have GOMODCACHE set.
@@ -169,22 +171,37 @@ func TestMain(m *testing.M) {
 			"printarchive": func() int {
 				archiveFilename := os.Args[1]
 
-				if !strings.HasSuffix(archiveFilename, ".tar.gz") {
-					fatalf("only .tar.gz supported for now, got: %q", archiveFilename)
-				}
-
 				f, err := os.Open(archiveFilename)
 				if err != nil {
 					fatalf("%v", err)
 				}
 				defer f.Close()
 
-				gr, err := gzip.NewReader(f)
-				if err != nil {
-					fatalf("%v", err)
+				var r io.Reader
+				switch {
+				case strings.HasSuffix(archiveFilename, ".tar.gz"):
+					gr, err := gzip.NewReader(f)
+					if err != nil {
+						fatalf("%v", err)
+					}
+					defer gr.Close()
+					r = gr
+				case strings.HasSuffix(archiveFilename, ".tar.xz"):
+					r, err = xz.NewReader(f)
+					if err != nil {
+						fatalf("%v", err)
+					}
+				case strings.HasSuffix(archiveFilename, ".tar.zst"):
+					zr, err := zstd.NewReader(f)
+					if err != nil {
+						fatalf("%v", err)
+					}
+					defer zr.Close()
+					r = zr
+				default:
+					fatalf("only .tar.gz, .tar.xz and .tar.zst supported for now, got: %q", archiveFilename)
 				}
-				defer gr.Close()
-				tr := tar.NewReader(gr)
+				tr := tar.NewReader(r)
 
 				for {
 					hdr, err := tr.Next()
@@ -194,7 +211,11 @@ func TestMain(m *testing.M) {
 					if err != nil {
 						fatalf("%v", err)
 					}
-					mode := fs.FileMode(hdr.Mode)
+					mode := hdr.FileInfo().Mode()
+					if hdr.Typeflag == tar.TypeSymlink {
+						fmt.Printf("%s %04o %s -> %s\n", mode, mode.Perm(), hdr.Name, hdr.Linkname)
+						continue
+					}
 					fmt.Printf("%s %04o %s\n", mode, mode.Perm(), hdr.Name)
 				}
 
@@ -13,6 +13,10 @@ var (
 
 	// ReleaseNotesTemplate is the template for the release notes.
 	ReleaseNotesTemplate *template.Template
+
+	// InstallTemplate is the default template for the INSTALL.txt file in archives.
+	//go:embed templates/install.gotmpl
+	InstallTemplate string
 )
 
 func init() {